@@ -1,8 +1,10 @@
 package abysswrapper
 
 import (
+	"fmt"
 	"io"
 	"os/exec"
+	"strings"
 	"sync"
 	"time"
 
@@ -42,8 +44,9 @@ func (a *AbyssWrapper) Write(p []byte) (n int, err error) {
 	return a.output.Write(p)
 }
 
-// Launch launchs abyss wrapper
-func (a *AbyssWrapper) Launch(config *hsconfig.Config, output io.Writer) error {
+// Launch launchs abyss wrapper. loadOrder lists the project's content folder and auxiliary MPQs by priority,
+// it is passed to the engine where the engine's arguments in the preferences contain hsconfig.LoadOrderArgument.
+func (a *AbyssWrapper) Launch(config *hsconfig.Config, output io.Writer, loadOrder []string) error {
 	a.mutex.RLock()
 	if a.running {
 		a.mutex.RUnlock()
@@ -54,7 +57,7 @@ func (a *AbyssWrapper) Launch(config *hsconfig.Config, output io.Writer) error {
 	a.mutex.Lock()
 
 	a.output = output
-	a.cmd = exec.Command(config.AbyssEnginePath) // nolint:gosec // is ok
+	a.cmd = exec.Command(config.AbyssEnginePath, engineArguments(config.AbyssEngineArguments, loadOrder)...) // nolint:gosec // is ok
 	a.cmd.Stdout = a
 	a.cmd.Stderr = a
 	a.cmd.Stdin = a
@@ -68,6 +71,11 @@ func (a *AbyssWrapper) Launch(config *hsconfig.Config, output io.Writer) error {
 
 	a.mutex.Unlock()
 
+	if !strings.Contains(config.AbyssEngineArguments, hsconfig.LoadOrderArgument) {
+		_, _ = fmt.Fprintf(output, "The project's load order was not passed to Abyss Engine, add %s to its arguments in the preferences.\n",
+			hsconfig.LoadOrderArgument)
+	}
+
 	go func() {
 		_ = a.cmd.Wait()
 
@@ -79,6 +87,22 @@ func (a *AbyssWrapper) Launch(config *hsconfig.Config, output io.Writer) error {
 	return nil
 }
 
+// engineArguments splits the arguments of the preferences and replaces hsconfig.LoadOrderArgument by the load order
+func engineArguments(arguments string, loadOrder []string) []string {
+	result := make([]string, 0)
+
+	for _, argument := range strings.Fields(arguments) {
+		if argument == hsconfig.LoadOrderArgument {
+			result = append(result, loadOrder...)
+			continue
+		}
+
+		result = append(result, argument)
+	}
+
+	return result
+}
+
 // Kill stops abyss wrapper
 func (a *AbyssWrapper) Kill() error {
 	a.mutex.RLock()
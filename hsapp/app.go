@@ -26,12 +26,25 @@ import (
 	"github.com/OpenDiablo2/HellSpawner/hscommon"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsutil"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hswatcher"
 	"github.com/OpenDiablo2/HellSpawner/hsconfig"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hsaboutdialog"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hsexportdialog"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hsmpqexportdialog"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hsnewprojectdialog"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hspreferencesdialog"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hsprojectpropertiesdialog"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsdiff"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hslocalhistory"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsmpqexplorer"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsmpqinspector"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsoverridereport"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsproblems"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsprojectexplorer"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsrecyclebin"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsreferences"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hssearch"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsvirtualexplorer"
 )
 
 const (
@@ -44,6 +57,24 @@ const (
 	mpqExplorerDefaultY     = 30
 	consoleDefaultX         = 10
 	consoleDefaultY         = 500
+	virtualExplorerDefaultX = 60
+	virtualExplorerDefaultY = 60
+	overrideReportDefaultX  = 90
+	overrideReportDefaultY  = 90
+	searchDefaultX          = 120
+	searchDefaultY          = 120
+	referencesDefaultX      = 150
+	referencesDefaultY      = 150
+	problemsDefaultX        = 180
+	problemsDefaultY        = 180
+	localHistoryDefaultX    = 210
+	localHistoryDefaultY    = 210
+	mpqInspectorDefaultX    = 240
+	mpqInspectorDefaultY    = 240
+	diffDefaultX            = 270
+	diffDefaultY            = 270
+	recycleBinDefaultX      = 300
+	recycleBinDefaultY      = 300
 )
 
 const (
@@ -63,9 +94,21 @@ type App struct {
 	aboutDialog             *hsaboutdialog.AboutDialog
 	preferencesDialog       *hspreferencesdialog.PreferencesDialog
 	projectPropertiesDialog *hsprojectpropertiesdialog.ProjectPropertiesDialog
+	newProjectDialog        *hsnewprojectdialog.NewProjectDialog
+	exportDialog            *hsexportdialog.ExportDialog
+	mpqExportDialog         *hsmpqexportdialog.MPQExportDialog
 
 	projectExplorer *hsprojectexplorer.ProjectExplorer
 	mpqExplorer     *hsmpqexplorer.MPQExplorer
+	virtualExplorer *hsvirtualexplorer.VirtualExplorer
+	overrideReport  *hsoverridereport.OverrideReport
+	search          *hssearch.Search
+	references      *hsreferences.References
+	problems        *hsproblems.Problems
+	localHistory    *hslocalhistory.LocalHistory
+	mpqInspector    *hsmpqinspector.MPQInspector
+	diff            *hsdiff.Diff
+	recycleBin      *hsrecyclebin.RecycleBin
 	console         *hsconsole.Console
 
 	editors            []hscommon.EditorWindow
@@ -80,6 +123,12 @@ type App struct {
 	editorManagerMutex sync.RWMutex
 	focusedEditor      hscommon.EditorWindow
 
+	// watcher reports changes made to the project's files by other programs. The changes are collected by the
+	// watcher's goroutine and applied by the render loop, which is the only one walking the project's file tree.
+	watcher            *hswatcher.Watcher
+	fileChangesMutex   sync.Mutex
+	pendingFileChanges []hswatcher.Change
+
 	fontFixed         imgui.Font
 	fontFixedSmall    imgui.Font
 	diabloBoldFont    imgui.Font
@@ -140,6 +189,7 @@ func (a *App) Run() {
 
 func (a *App) render() {
 	a.TextureLoader.StopLoadingTextures()
+	a.applyProjectFileChanges()
 	a.renderMainMenuBar()
 
 	idx := 0
@@ -185,6 +235,51 @@ func (a *App) render() {
 		a.mpqExplorer.Render()
 	}
 
+	if a.virtualExplorer.IsVisible() {
+		a.virtualExplorer.Build()
+		a.virtualExplorer.Render()
+	}
+
+	if a.overrideReport.IsVisible() {
+		a.overrideReport.Build()
+		a.overrideReport.Render()
+	}
+
+	if a.search.IsVisible() {
+		a.search.Build()
+		a.search.Render()
+	}
+
+	if a.references.IsVisible() {
+		a.references.Build()
+		a.references.Render()
+	}
+
+	if a.problems.IsVisible() {
+		a.problems.Build()
+		a.problems.Render()
+	}
+
+	if a.localHistory.IsVisible() {
+		a.localHistory.Build()
+		a.localHistory.Render()
+	}
+
+	if a.mpqInspector.IsVisible() {
+		a.mpqInspector.Build()
+		a.mpqInspector.Render()
+	}
+
+	if a.diff.IsVisible() {
+		a.diff.Build()
+		a.diff.Render()
+	}
+
+	if a.recycleBin.IsVisible() {
+		a.recycleBin.Build()
+		a.recycleBin.Render()
+	}
+
 	if a.preferencesDialog.IsVisible() {
 		a.preferencesDialog.Build()
 		a.preferencesDialog.Render()
@@ -200,6 +295,21 @@ func (a *App) render() {
 		a.projectPropertiesDialog.Render()
 	}
 
+	if a.newProjectDialog.IsVisible() {
+		a.newProjectDialog.Build()
+		a.newProjectDialog.Render()
+	}
+
+	if a.exportDialog.IsVisible() {
+		a.exportDialog.Build()
+		a.exportDialog.Render()
+	}
+
+	if a.mpqExportDialog.IsVisible() {
+		a.mpqExportDialog.Build()
+		a.mpqExportDialog.Render()
+	}
+
 	if a.console.IsVisible() {
 		a.console.Build()
 		a.console.Render()
@@ -231,29 +341,30 @@ func (a *App) setupFonts() {
 	}
 }
 
-func (a *App) createEditor(path *hscommon.PathEntry, x, y float32) {
+// createEditor creates an editor for the file and shows it, it returns nil if the editor couldn't be created
+func (a *App) createEditor(path *hscommon.PathEntry, x, y float32) hscommon.EditorWindow {
 	data, err := path.GetFileBytes()
 	if err != nil {
 		dialog.Message("Could not load file!").Error()
-		return
+		return nil
 	}
 
 	fileType, err := hsfiletypes.GetFileTypeFromExtension(filepath.Ext(path.FullPath), &data)
 	if err != nil {
 		dialog.Message("No file type is defined for this extension!").Error()
-		return
+		return nil
 	}
 
 	if a.editorConstructors[fileType] == nil {
 		dialog.Message("No editor is defined for this file type!").Error()
-		return
+		return nil
 	}
 
 	editor, err := a.editorConstructors[fileType](a.TextureLoader, path, &data, x, y, a.project)
 
 	if err != nil {
 		dialog.Message("Error creating editor: %s", err).Error()
-		return
+		return nil
 	}
 
 	a.editorManagerMutex.Lock()
@@ -261,6 +372,8 @@ func (a *App) createEditor(path *hscommon.PathEntry, x, y float32) {
 	a.editorManagerMutex.Unlock()
 	editor.Show()
 	editor.BringToFront()
+
+	return editor
 }
 
 func (a *App) openEditor(path *hscommon.PathEntry) {
@@ -287,7 +400,7 @@ func (a *App) loadProjectFromFile(file string) {
 	var err error
 
 	if project, err = hsproject.LoadFromFile(file); err != nil {
-		dialog.Message("Could not load project.").Title("Load HellSpawner Project Error").Error()
+		dialog.Message("Could not load project:\n%s", err).Title("Load HellSpawner Project Error").Error()
 		return
 	}
 
@@ -297,11 +410,23 @@ func (a *App) loadProjectFromFile(file string) {
 	}
 
 	a.project = project
+	hscommon.SetArchiveReader(a.project.Archives())
 	a.config.AddToRecentProjects(file)
 	a.updateWindowTitle()
 	a.reloadAuxiliaryMPQs()
 	a.projectExplorer.SetProject(a.project)
 	a.mpqExplorer.SetProject(a.project)
+	a.virtualExplorer.SetProject(a.project)
+	a.overrideReport.SetProject(a.project)
+	a.search.SetProject(a.project)
+	a.references.SetProject(a.project)
+	a.problems.SetProject(a.project)
+	a.localHistory.SetProject(a.project)
+	a.mpqInspector.SetProject(a.project)
+	a.diff.SetProject(a.project)
+	a.recycleBin.SetProject(a.project)
+	a.project.History().SetRetentionPolicy(a.config.LocalHistoryRetention())
+	a.startProjectWatcher()
 
 	a.CloseAllOpenWindows()
 
@@ -327,7 +452,8 @@ func (a *App) toggleMPQExplorer() {
 }
 
 func (a *App) onProjectPropertiesChanged(project *hsproject.Project) {
-	a.project = project
+	// the tool windows keep a pointer to the open project, so the properties are copied instead of replacing it
+	a.project.ApplyProperties(project)
 	if err := a.project.Save(); err != nil {
 		log.Fatal(err)
 	}
@@ -343,13 +469,59 @@ func (a *App) onPreferencesChanged(config *hsconfig.Config) {
 	}
 
 	if a.project != nil {
+		a.project.History().SetRetentionPolicy(a.config.LocalHistoryRetention())
 		a.reloadAuxiliaryMPQs()
 	}
 }
 
 func (a *App) reloadAuxiliaryMPQs() {
-	a.project.ReloadAuxiliaryMPQs(a.config)
+	if err := a.project.ReloadAuxiliaryMPQs(a.config); err != nil {
+		dialog.Message("Could not load the auxiliary MPQs:\n%s", err).Title("Auxiliary MPQ Error").Error()
+	}
+
 	a.mpqExplorer.Reset()
+	a.virtualExplorer.Reset()
+	a.overrideReport.Reset()
+	a.search.Reset()
+	a.references.Reset()
+	a.problems.Reset()
+	a.diff.Reset()
+}
+
+func (a *App) toggleVirtualExplorer() {
+	a.virtualExplorer.ToggleVisibility()
+}
+
+func (a *App) toggleOverrideReport() {
+	a.overrideReport.ToggleVisibility()
+}
+
+func (a *App) toggleSearch() {
+	a.search.ToggleVisibility()
+}
+
+func (a *App) toggleReferences() {
+	a.references.ToggleVisibility()
+}
+
+func (a *App) toggleProblems() {
+	a.problems.ToggleVisibility()
+}
+
+func (a *App) toggleLocalHistory() {
+	a.localHistory.ToggleVisibility()
+}
+
+func (a *App) toggleMPQInspector() {
+	a.mpqInspector.ToggleVisibility()
+}
+
+func (a *App) toggleDiff() {
+	a.diff.ToggleVisibility()
+}
+
+func (a *App) toggleRecycleBin() {
+	a.recycleBin.ToggleVisibility()
 }
 
 func (a *App) toggleProjectExplorer() {
@@ -370,6 +542,9 @@ func (a *App) closePopups() {
 	a.projectPropertiesDialog.Cleanup()
 	a.aboutDialog.Cleanup()
 	a.preferencesDialog.Cleanup()
+	a.newProjectDialog.Cleanup()
+	a.exportDialog.Cleanup()
+	a.mpqExportDialog.Cleanup()
 }
 
 func (a *App) toggleConsole() {
@@ -381,6 +556,15 @@ func (a *App) CloseAllOpenWindows() {
 	a.closePopups()
 	a.projectExplorer.Cleanup()
 	a.mpqExplorer.Cleanup()
+	a.virtualExplorer.Cleanup()
+	a.overrideReport.Cleanup()
+	a.search.Cleanup()
+	a.references.Cleanup()
+	a.problems.Cleanup()
+	a.localHistory.Cleanup()
+	a.mpqInspector.Cleanup()
+	a.diff.Cleanup()
+	a.recycleBin.Cleanup()
 
 	for _, editor := range a.editors {
 		editor.Cleanup()
@@ -410,6 +594,7 @@ func (a *App) Quit() {
 		_ = a.abyssWrapper.Kill()
 	}
 
+	a.stopProjectWatcher()
 	a.Save()
 
 	a.CloseAllOpenWindows()
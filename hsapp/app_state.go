@@ -20,7 +20,10 @@ func (a *App) State() hsstate.AppState {
 		appState.EditorWindows = append(appState.EditorWindows, editor.State())
 	}
 
-	appState.ToolWindows = append(appState.ToolWindows, a.mpqExplorer.State(), a.projectExplorer.State())
+	appState.ToolWindows = append(appState.ToolWindows, a.mpqExplorer.State(), a.projectExplorer.State(),
+		a.virtualExplorer.State(), a.overrideReport.State(), a.search.State(),
+		a.references.State(), a.problems.State(), a.localHistory.State(), a.mpqInspector.State(),
+		a.diff.State(), a.recycleBin.State())
 
 	return appState
 }
@@ -37,6 +40,24 @@ func (a *App) RestoreAppState(state hsstate.AppState) {
 			tool = a.mpqExplorer
 		case hsstate.ToolWindowTypeProjectExplorer:
 			tool = a.projectExplorer
+		case hsstate.ToolWindowTypeVirtualExplorer:
+			tool = a.virtualExplorer
+		case hsstate.ToolWindowTypeOverrideReport:
+			tool = a.overrideReport
+		case hsstate.ToolWindowTypeSearch:
+			tool = a.search
+		case hsstate.ToolWindowTypeReferences:
+			tool = a.references
+		case hsstate.ToolWindowTypeProblems:
+			tool = a.problems
+		case hsstate.ToolWindowTypeLocalHistory:
+			tool = a.localHistory
+		case hsstate.ToolWindowTypeMPQInspector:
+			tool = a.mpqInspector
+		case hsstate.ToolWindowTypeDiff:
+			tool = a.diff
+		case hsstate.ToolWindowTypeRecycleBin:
+			tool = a.recycleBin
 		default:
 			continue
 		}
@@ -56,6 +77,10 @@ func (a *App) RestoreAppState(state hsstate.AppState) {
 			continue
 		}
 
-		go a.createEditor(&path, editorState.PosX, editorState.PosY)
+		go func() {
+			if editor := a.createEditor(&path, editorState.PosX, editorState.PosY); editor != nil {
+				editor.RestoreState(editorState)
+			}
+		}()
 	}
 }
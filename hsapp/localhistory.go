@@ -0,0 +1,38 @@
+package hsapp
+
+import (
+	"github.com/OpenDiablo2/dialog"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hshistory"
+)
+
+const (
+	localHistoryTimeFormat = "2006-01-02 15:04:05"
+)
+
+// onLocalHistoryRestore restores a revision of a project file and reloads the editor showing it
+func (a *App) onLocalHistoryRestore(path *hscommon.PathEntry, revision hshistory.Revision) {
+	editor := a.findProjectFileEditor(path.FullPath)
+
+	message := "Restore %s to the version recorded on %s?\nThe current version is kept in the local history."
+	if editor != nil {
+		message += "\nUnsaved changes in the open editor will be lost."
+	}
+
+	if !dialog.Message(message, path.Name, revision.Time.Local().Format(localHistoryTimeFormat)).
+		Title("Restore Local History").YesNo() {
+		return
+	}
+
+	if err := a.project.History().Restore(path.FullPath, revision.ID); err != nil {
+		dialog.Message("Could not restore %s:\n%s", path.Name, err).Title("Restore Local History Error").Error()
+		return
+	}
+
+	if editor != nil {
+		a.reloadEditor(editor)
+	}
+
+	a.localHistory.Refresh()
+}
@@ -1,7 +1,10 @@
 package hsapp
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/OpenDiablo2/dialog"
 
@@ -59,10 +62,19 @@ func (a *App) renderMainMenuBar() {
 			g.MenuItem("Properties...##MainMenuProjectProperties").
 				Enabled(projectOpened).
 				OnClick(a.onProjectPropertiesClicked),
+			g.MenuItem("Undo Delete\t\tCtrl+Z##MainMenuProjectUndoDelete").
+				Enabled(projectOpened).
+				OnClick(a.undoDelete),
 			g.Separator(),
+			g.MenuItem("Validate##MainMenuProjectValidate").
+				Enabled(projectOpened).
+				OnClick(a.problems.Validate),
 			g.MenuItem("Export MPQ...##MainMenuProjectExport").
 				Enabled(projectOpened).
-				OnClick(a.onProjectExportMPQClicked),
+				OnClick(a.mpqExportDialog.Show),
+			g.MenuItem("Export Distribution...##MainMenuProjectExportDistribution").
+				Enabled(projectOpened).
+				OnClick(a.exportDialog.Show),
 		}),
 		g.Menu("Help").Layout(g.Layout{
 			g.MenuItem("About HellSpawner...\tF1##MainMenuHelpAbout").OnClick(a.onHelpAboutClicked),
@@ -92,6 +104,51 @@ func (a *App) buildViewMenu() g.Layout {
 			Enabled(a.project != nil).
 			OnClick(a.toggleMPQExplorer),
 
+		g.MenuItem("Virtual File System\tCtrl+Shift+V").
+			Selected(a.virtualExplorer.Visible).
+			Enabled(a.project != nil).
+			OnClick(a.toggleVirtualExplorer),
+
+		g.MenuItem("Override Report").
+			Selected(a.overrideReport.Visible).
+			Enabled(a.project != nil).
+			OnClick(a.toggleOverrideReport),
+
+		g.MenuItem("Search\t\t\t\t\tCtrl+Shift+F").
+			Selected(a.search.Visible).
+			Enabled(a.project != nil).
+			OnClick(a.toggleSearch),
+
+		g.MenuItem("References").
+			Selected(a.references.Visible).
+			Enabled(a.project != nil).
+			OnClick(a.toggleReferences),
+
+		g.MenuItem("Problems").
+			Selected(a.problems.Visible).
+			Enabled(a.project != nil).
+			OnClick(a.toggleProblems),
+
+		g.MenuItem("Local History").
+			Selected(a.localHistory.Visible).
+			Enabled(a.project != nil).
+			OnClick(a.toggleLocalHistory),
+
+		g.MenuItem("MPQ Inspector").
+			Selected(a.mpqInspector.Visible).
+			Enabled(a.project != nil).
+			OnClick(a.toggleMPQInspector),
+
+		g.MenuItem("Archive Diff").
+			Selected(a.diff.Visible).
+			Enabled(a.project != nil).
+			OnClick(a.toggleDiff),
+
+		g.MenuItem("Recycle Bin").
+			Selected(a.recycleBin.Visible).
+			Enabled(a.project != nil).
+			OnClick(a.toggleRecycleBin),
+
 		g.MenuItem("Console\t\t\t\t\tCtrl+Shift+C").
 			Selected(a.console.Visible).
 			OnClick(a.toggleConsole),
@@ -112,17 +169,26 @@ func (a *App) buildViewMenu() g.Layout {
 }
 
 func (a *App) onNewProjectClicked() {
+	a.newProjectDialog.Show(a.config)
+}
+
+func (a *App) onNewProjectTemplateSelected(template *hsproject.Template) {
 	file, err := dialog.File().Filter("HellSpawner Project", "hsp").Save()
 	if err != nil || file == "" {
 		return
 	}
 
-	var project *hsproject.Project
-
-	if project, err = hsproject.CreateNew(file); err != nil {
+	project, warnings, err := hsproject.CreateFromTemplate(file, template, a.config)
+	if err != nil {
+		dialog.Message("Could not create project:\n%s", err).Title("New HellSpawner Project Error").Error()
 		return
 	}
 
+	if len(warnings) > 0 {
+		dialog.Message("The project was created, but some of the template's content is missing:\n%s",
+			strings.Join(warnings, "\n")).Title("New HellSpawner Project").Info()
+	}
+
 	a.loadProjectFromFile(project.GetProjectFilePath())
 }
 
@@ -158,11 +224,70 @@ func (a *App) onProjectRunClicked() {
 
 	a.console.Show()
 
-	if err := a.abyssWrapper.Launch(a.config, a.console); err != nil {
+	if err := a.abyssWrapper.Launch(a.config, a.console, a.project.LoadOrderPaths(a.config)); err != nil {
 		dialog.Message(err.Error()).Error()
 	}
 }
 
-func (a *App) onProjectExportMPQClicked() {
+func (a *App) onProjectExportMPQ(compression hsproject.CompressionSelector) {
+	file, err := dialog.File().Filter("MPQ Archive", "mpq").Title("Export MPQ").Save()
+	if err != nil || file == "" {
+		return
+	}
+
+	if !strings.EqualFold(filepath.Ext(file), ".mpq") {
+		file += ".mpq"
+	}
+
+	project := a.project
+	progress := a.mpqExportDialog
+
+	progress.Start()
+
+	go func() {
+		err := project.ExportMPQ(file, compression, progress.Progress)
+
+		progress.Finish()
+
+		switch {
+		case errors.Is(err, hsproject.ErrExportCanceled):
+			return
+		case err != nil:
+			dialog.Message("Could not export MPQ:\n%s", err).Title("Export MPQ Error").Error()
+			return
+		}
+
+		dialog.Message("Project exported to:\n%s", file).Title("Export MPQ").Info()
+	}()
+}
+
+func (a *App) onProjectExportDistribution(options hsproject.DistributionOptions) {
+	var target string
+
+	var err error
+
+	if options.Format == hsproject.DistributionZip {
+		target, err = dialog.File().Filter("Zip Archive", "zip").Title("Export Distribution").Save()
+		if err == nil && target != "" && !strings.EqualFold(filepath.Ext(target), ".zip") {
+			target += ".zip"
+		}
+	} else {
+		target, err = dialog.Directory().Title("Export Distribution").Browse()
+	}
+
+	if err != nil || target == "" {
+		return
+	}
+
+	project := a.project
+
+	go func() {
+		manifest, exportErr := project.ExportDistribution(target, options)
+		if exportErr != nil {
+			dialog.Message("Could not export distribution:\n%s", exportErr).Title("Export Distribution Error").Error()
+			return
+		}
 
+		dialog.Message("%d files exported to:\n%s", len(manifest.Files), target).Title("Export Distribution").Info()
+	}()
 }
@@ -16,6 +16,9 @@ import (
 
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hsaboutdialog"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hsexportdialog"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hsmpqexportdialog"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hsnewprojectdialog"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hspreferencesdialog"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog/hsprojectpropertiesdialog"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hseditor/hscofeditor"
@@ -25,8 +28,17 @@ import (
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hseditor/hspaletteeditor"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hseditor/hssoundeditor"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hseditor/hstexteditor"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsdiff"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hslocalhistory"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsmpqexplorer"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsmpqinspector"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsoverridereport"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsproblems"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsprojectexplorer"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsrecyclebin"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsreferences"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hssearch"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow/hsvirtualexplorer"
 )
 
 func (a *App) setup() error {
@@ -47,13 +59,52 @@ func (a *App) setup() error {
 	a.editorConstructors[hsfiletypes.FileTypeDS1] = hsds1editor.Create
 
 	// Register the tool windows
-	if a.mpqExplorer, err = hsmpqexplorer.Create(a.openEditor, a.config, mpqExplorerDefaultX, mpqExplorerDefaultY); err != nil {
+	if a.references, err = hsreferences.Create(a.openEditor, a.config, referencesDefaultX, referencesDefaultY); err != nil {
+		return err
+	}
+
+	if a.mpqInspector, err = hsmpqinspector.Create(a.config, mpqInspectorDefaultX, mpqInspectorDefaultY); err != nil {
+		return err
+	}
+
+	if a.mpqExplorer, err = hsmpqexplorer.Create(a.openEditor, a.references.ShowUsages, a.references.GoToReferencedFile,
+		a.mpqInspector.Inspect, a.config, mpqExplorerDefaultX, mpqExplorerDefaultY); err != nil {
+		return err
+	}
+
+	if a.virtualExplorer, err = hsvirtualexplorer.Create(a.openEditor, a.config,
+		virtualExplorerDefaultX, virtualExplorerDefaultY); err != nil {
+		return err
+	}
+
+	if a.overrideReport, err = hsoverridereport.Create(a.openEditor, a.config,
+		overrideReportDefaultX, overrideReportDefaultY); err != nil {
+		return err
+	}
+
+	if a.search, err = hssearch.Create(a.openEditor, a.config, searchDefaultX, searchDefaultY); err != nil {
+		return err
+	}
+
+	if a.problems, err = hsproblems.Create(a.openEditor, a.config, problemsDefaultX, problemsDefaultY); err != nil {
+		return err
+	}
+
+	if a.diff, err = hsdiff.Create(a.openEditor, a.config, diffDefaultX, diffDefaultY); err != nil {
+		return err
+	}
+
+	if a.localHistory, err = hslocalhistory.Create(a.onLocalHistoryRestore, localHistoryDefaultX, localHistoryDefaultY); err != nil {
+		return err
+	}
+
+	if a.recycleBin, err = hsrecyclebin.Create(a.onProjectFilesRestored, recycleBinDefaultX, recycleBinDefaultY); err != nil {
 		return err
 	}
 
 	if a.projectExplorer, err = hsprojectexplorer.Create(a.TextureLoader,
-		a.openEditor, projectExplorerDefaultX,
-		projectExplorerDefaultY); err != nil {
+		a.openEditor, a.references.ShowUsages, a.references.GoToReferencedFile, a.localHistory.ShowFile,
+		a.onProjectFilesMoved, a.recycleBin.Refresh, a.config, projectExplorerDefaultX, projectExplorerDefaultY); err != nil {
 		return err
 	}
 
@@ -66,6 +117,9 @@ func (a *App) setup() error {
 
 	a.projectPropertiesDialog = hsprojectpropertiesdialog.Create(a.TextureLoader, a.onProjectPropertiesChanged)
 	a.preferencesDialog = hspreferencesdialog.Create(a.onPreferencesChanged)
+	a.newProjectDialog = hsnewprojectdialog.Create(a.onNewProjectTemplateSelected)
+	a.exportDialog = hsexportdialog.Create(a.onProjectExportDistribution)
+	a.mpqExportDialog = hsmpqexportdialog.Create(a.onProjectExportMPQ)
 
 	// Set up keyboard shortcuts
 	a.registerGlobalKeyboardShortcuts()
@@ -82,9 +136,12 @@ func (a *App) registerGlobalKeyboardShortcuts() {
 	a.InputManager.RegisterShortcut(a.onHelpAboutClicked, g.KeyF1, g.ModNone, true)
 
 	a.InputManager.RegisterShortcut(a.closeActiveEditor, g.KeyW, g.ModControl, true)
+	a.InputManager.RegisterShortcut(a.undoDelete, g.KeyZ, g.ModControl, true)
 	a.InputManager.RegisterShortcut(func() { a.closePopups(); a.closeActiveEditor() }, g.KeyEscape, g.ModNone, true)
 
 	a.InputManager.RegisterShortcut(a.toggleMPQExplorer, g.KeyM, g.ModControl+g.ModShift, true)
 	a.InputManager.RegisterShortcut(a.toggleProjectExplorer, g.KeyP, g.ModControl+g.ModShift, true)
 	a.InputManager.RegisterShortcut(a.toggleConsole, g.KeyC, g.ModControl+g.ModShift, true)
+	a.InputManager.RegisterShortcut(a.toggleVirtualExplorer, g.KeyV, g.ModControl+g.ModShift, true)
+	a.InputManager.RegisterShortcut(a.toggleSearch, g.KeyF, g.ModControl+g.ModShift, true)
 }
@@ -0,0 +1,180 @@
+package hsapp
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/OpenDiablo2/dialog"
+	"github.com/ianling/imgui-go"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hswatcher"
+)
+
+// startProjectWatcher starts watching the content directory of the current project,
+// replacing the watcher of the previously loaded project
+func (a *App) startProjectWatcher() {
+	a.stopProjectWatcher()
+
+	a.watcher = hswatcher.New(a.project.GetProjectFileContentPath(), a.onProjectFilesChanged)
+	a.watcher.Start()
+}
+
+func (a *App) stopProjectWatcher() {
+	if a.watcher == nil {
+		return
+	}
+
+	a.watcher.Stop()
+	a.watcher = nil
+
+	// the changes belong to the project which is closed
+	a.fileChangesMutex.Lock()
+	a.pendingFileChanges = nil
+	a.fileChangesMutex.Unlock()
+}
+
+// onProjectFilesChanged is called by the watcher's goroutine, the changes are applied by the next frame
+func (a *App) onProjectFilesChanged(changes []hswatcher.Change) {
+	a.fileChangesMutex.Lock()
+	defer a.fileChangesMutex.Unlock()
+
+	a.pendingFileChanges = append(a.pendingFileChanges, changes...)
+}
+
+// applyProjectFileChanges updates the project's file tree with the changes reported by the watcher,
+// it is called by the render loop
+func (a *App) applyProjectFileChanges() {
+	a.fileChangesMutex.Lock()
+	changes := a.pendingFileChanges
+	a.pendingFileChanges = nil
+	a.fileChangesMutex.Unlock()
+
+	if len(changes) == 0 || a.project == nil {
+		return
+	}
+
+	paths := make([]string, len(changes))
+	changed := make(map[string]hscommon.EditorWindow)
+
+	for idx := range changes {
+		paths[idx] = changes[idx].Path
+
+		if changes[idx].Type != hswatcher.ChangeModified {
+			continue
+		}
+
+		// the editors are checked here, they are only used by the render loop
+		if editor := a.findProjectFileEditor(changes[idx].Path); editor != nil && editor.CheckExternalChanges() {
+			changed[changes[idx].Path] = editor
+		}
+	}
+
+	a.project.InvalidatePaths(paths)
+	a.virtualExplorer.Reset()
+	a.references.Reset()
+
+	if len(changed) == 0 {
+		return
+	}
+
+	// the questions block until they are answered, the frames are drawn in the meantime
+	go func() {
+		for path, editor := range changed {
+			a.offerEditorReload(path, editor)
+		}
+	}()
+}
+
+// onProjectFilesMoved points the editors of moved files at their new paths, keeping their unsaved changes
+func (a *App) onProjectFilesMoved(moves map[string]string) {
+	a.editorManagerMutex.RLock()
+	defer a.editorManagerMutex.RUnlock()
+
+	for oldPath, newPath := range moves {
+		uniqueID := (&hscommon.PathEntry{FullPath: oldPath, Source: hscommon.PathEntrySourceProject}).GetUniqueID()
+
+		for idx := range a.editors {
+			if a.editors[idx].GetID() == uniqueID {
+				a.editors[idx].Move(newPath)
+			}
+		}
+	}
+
+	a.virtualExplorer.Reset()
+	a.references.Reset()
+}
+
+// onProjectFilesRestored refreshes the views of the project after files were restored from the recycle bin
+func (a *App) onProjectFilesRestored() {
+	a.project.InvalidateFileStructure()
+	a.recycleBin.Refresh()
+	a.virtualExplorer.Reset()
+	a.references.Reset()
+}
+
+// undoDelete restores the file or folder deleted last, if it is still in the recycle bin
+func (a *App) undoDelete() {
+	// Ctrl+Z in a text field undoes the typing, not a delete
+	if a.project == nil || imgui.CurrentIO().WantTextInput() {
+		return
+	}
+
+	item, err := a.project.Trash().UndoDelete()
+	if err != nil {
+		dialog.Message("Could not undo the delete:\n%s", err).Error()
+		return
+	}
+
+	if item == nil {
+		return
+	}
+
+	a.onProjectFilesRestored()
+}
+
+// offerEditorReload asks to reload an editor whose file was changed by another program
+func (a *App) offerEditorReload(filePath string, editor hscommon.EditorWindow) {
+	if !dialog.Message("%s was changed by another program.\nReload it? Any unsaved changes will be lost.", filePath).
+		Title("File Changed").YesNo() {
+		return
+	}
+
+	a.reloadEditor(editor)
+}
+
+// findProjectFileEditor returns the visible editor which has the given project file open, or nil
+func (a *App) findProjectFileEditor(filePath string) hscommon.EditorWindow {
+	uniqueID := (&hscommon.PathEntry{FullPath: filePath, Source: hscommon.PathEntrySourceProject}).GetUniqueID()
+
+	a.editorManagerMutex.RLock()
+	defer a.editorManagerMutex.RUnlock()
+
+	for idx := range a.editors {
+		if a.editors[idx].GetID() == uniqueID && a.editors[idx].IsVisible() {
+			return a.editors[idx]
+		}
+	}
+
+	return nil
+}
+
+// reloadEditor replaces the editor with a new one at the same position, which reads the file again
+func (a *App) reloadEditor(editor hscommon.EditorWindow) {
+	state := editor.State()
+
+	var path hscommon.PathEntry
+
+	if err := json.Unmarshal(state.Path, &path); err != nil {
+		log.Print("failed to reload editor: ", err)
+		return
+	}
+
+	// the render loop removes the old editor, it must not ask to save the changes we are throwing away
+	editor.DiscardChanges()
+	editor.SetVisible(false)
+
+	if newEditor := a.createEditor(&path, state.PosX, state.PosY); newEditor != nil {
+		newEditor.RestoreState(state)
+	}
+}
@@ -0,0 +1,210 @@
+package hscli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+// Exit codes
+const (
+	// ExitOK is returned when the command succeeded
+	ExitOK = 0
+	// ExitFailure is returned when the command ran, but found problems (e.g. validation errors)
+	ExitFailure = 1
+	// ExitUsage is returned when the command could not run (bad arguments, project could not be loaded...)
+	ExitUsage = 2
+)
+
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(args []string) int
+}
+
+func commands() []command {
+	return []command{
+		{
+			name:        "validate",
+			usage:       "validate [options] <project.hsp>",
+			description: "decodes every file of the project and checks its references",
+			run:         runValidate,
+		},
+		{
+			name:        "export-mpq",
+			usage:       "export-mpq [options] <project.hsp> <output.mpq>",
+			description: "builds an MPQ from the project's content",
+			run:         runExportMPQ,
+		},
+		{
+			name:        "export",
+			usage:       "export [options] <project.hsp> <output>",
+			description: "exports the project's content as loose files, or as a zip archive if the output ends in .zip",
+			run:         runExport,
+		},
+		{
+			name:        "verify",
+			usage:       "verify [options] <directory or .zip>",
+			description: "checks an exported distribution against its manifest",
+			run:         runVerify,
+		},
+		{
+			name:        "ls-mpq",
+			usage:       "ls-mpq [options] <archive.mpq>",
+			description: "lists the files inside of an MPQ",
+			run:         runListMPQ,
+		},
+		{
+			name:        "extract",
+			usage:       "extract [options] <archive.mpq> [pattern...]",
+			description: "extracts the files matching the glob patterns from an MPQ",
+			run:         runExtract,
+		},
+		{
+			name:        "convert",
+			usage:       "convert [options] <input> <output>",
+			description: "converts a game file to another format, based on the output's extension",
+			run:         runConvert,
+		},
+		{
+			name:        "export-dc6",
+			usage:       "export-dc6 [options] <input.dc6> <output directory>",
+			description: "writes the frames of a DC6 as PNG frames, a sprite sheet with a JSON atlas or GIFs",
+			run:         runExportDC6,
+		},
+		{
+			name:        "diff",
+			usage:       "diff [options] <old.mpq[,...]> <new.mpq[,...]>",
+			description: "lists the files added, removed and changed between two archives or archive sets",
+			run:         runDiff,
+		},
+	}
+}
+
+// Run runs the command named by the first argument. handled is false when the arguments
+// don't name a command, in which case the GUI should be started instead.
+func Run(args []string) (handled bool, exitCode int) {
+	if len(args) == 0 {
+		return false, ExitOK
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return true, ExitOK
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name == args[0] {
+			return true, cmd.run(args[1:])
+		}
+	}
+
+	return false, ExitOK
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: hellspawner [command]")
+	fmt.Fprintln(w, "without a command, the editor is started")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-48s %s\n", cmd.usage, cmd.description)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run 'hellspawner <command> -h' for the options of a command")
+	fmt.Fprintln(w, "exit codes: 0 on success, 1 when the command found problems, 2 when it could not run")
+}
+
+// commonFlags are the options shared by several commands
+type commonFlags struct {
+	mpqPath  string
+	listFile string
+	json     bool
+}
+
+// registerProject registers the options of commands loading a project
+func (f *commonFlags) registerProject(flags *flag.FlagSet) {
+	flags.StringVar(&f.mpqPath, "mpq-path", "", "directory containing the auxiliary MPQs (defaults to the path in the preferences)")
+	f.registerListFile(flags)
+}
+
+// registerListFile registers the options of commands listing the files of an MPQ
+func (f *commonFlags) registerListFile(flags *flag.FlagSet) {
+	flags.StringVar(&f.listFile, "listfile", "", "external listfile for MPQs without one (defaults to the file in the preferences)")
+}
+
+// registerJSON registers the option to print the result as JSON
+func (f *commonFlags) registerJSON(flags *flag.FlagSet) {
+	flags.BoolVar(&f.json, "json", false, "print the result as JSON")
+}
+
+// loadConfig loads the user's preferences, with the paths overridden by the command line options.
+// The preferences are never saved by the command line interface.
+func (f *commonFlags) loadConfig() (*hsconfig.Config, error) {
+	config, err := hsconfig.LoadReadOnly()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.mpqPath != "" {
+		config.AuxiliaryMpqPath = f.mpqPath
+	}
+
+	if f.listFile != "" {
+		config.ExternalListFile = f.listFile
+	}
+
+	return config, nil
+}
+
+// loadProject loads a project and its auxiliary MPQs
+func loadProject(fileName string, config *hsconfig.Config) (*hsproject.Project, error) {
+	project, err := hsproject.LoadFromFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("could not load project: %w", err)
+	}
+
+	if !project.ValidateAuxiliaryMPQs(config) {
+		return nil, fmt.Errorf("could not locate one or more auxiliary MPQs in %s", config.AuxiliaryMpqPath)
+	}
+
+	if err := project.ReloadAuxiliaryMPQs(config); err != nil {
+		return nil, err
+	}
+
+	hscommon.SetArchiveReader(project.Archives())
+
+	return project, nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+
+	return flags
+}
+
+func printJSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Println(string(data))
+
+	return err
+}
+
+func fail(format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	return ExitUsage
+}
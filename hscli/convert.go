@@ -0,0 +1,330 @@
+package hscli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2cof"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2pl2"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2tbl"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsarchive"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes"
+)
+
+const (
+	// stdout is the output file name which writes the converted file to the standard output
+	stdout = "-"
+)
+
+// converter converts the data of a game file into another format
+type converter func(data []byte) ([]byte, error)
+
+// conversion is a conversion supported by the convert command
+type conversion struct {
+	name      string
+	from      hsfiletypes.FileType
+	extension string
+	convert   converter
+}
+
+func conversions() []conversion {
+	return []conversion{
+		{name: "string table (.tbl)", from: hsfiletypes.FileTypeTBLStringTable, extension: ".json", convert: stringTableToJSON},
+		{name: "string table (.tbl)", from: hsfiletypes.FileTypeTBLStringTable, extension: ".txt", convert: stringTableToText},
+		{name: "text table (.txt)", from: hsfiletypes.FileTypeText, extension: ".json", convert: textTableToJSON},
+		{name: "palette (.dat)", from: hsfiletypes.FileTypePalette, extension: ".json", convert: paletteToJSON},
+		{name: "palette (.dat)", from: hsfiletypes.FileTypePalette, extension: ".act", convert: paletteToACT},
+		{name: "palette map (.pl2)", from: hsfiletypes.FileTypePL2, extension: ".json", convert: pl2ToJSON},
+		{name: "COF (.cof)", from: hsfiletypes.FileTypeCOF, extension: ".json", convert: cofToJSON},
+	}
+}
+
+type convertResult struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+	Size   int    `json:"size"`
+}
+
+func runConvert(args []string) int {
+	var options commonFlags
+
+	var mpqPath, to string
+
+	flags := newFlagSet("convert")
+	flags.StringVar(&mpqPath, "mpq", "", "read the input file from this MPQ instead of the disk")
+	flags.StringVar(&to, "to", "", "output format (an extension like .json), defaults to the output's extension")
+	options.registerJSON(flags)
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: hellspawner convert [options] <input> <output>")
+		fmt.Fprintln(os.Stderr, "the output is written to the standard output when it is -, the format is then given by -to")
+		flags.PrintDefaults()
+		fmt.Fprintln(os.Stderr, "supported conversions:")
+
+		for _, c := range conversions() {
+			fmt.Fprintf(os.Stderr, "  %-20s -> %s\n", c.name, c.extension)
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
+		// the usage has already been printed by the flag set
+		return ExitUsage
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	input, output := flags.Arg(0), flags.Arg(1)
+
+	data, err := readInput(input, mpqPath)
+	if err != nil {
+		return fail("could not read %s: %s", input, err)
+	}
+
+	if to == "" {
+		to = filepath.Ext(output)
+	}
+
+	convert, err := findConverter(input, data, to)
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	converted, err := convert(data)
+	if err != nil {
+		return fail("could not convert %s: %s", input, err)
+	}
+
+	if output == stdout {
+		if _, err := os.Stdout.Write(converted); err != nil {
+			return fail("%s", err)
+		}
+
+		return ExitOK
+	}
+
+	if err := ioutil.WriteFile(output, converted, newFileMode); err != nil {
+		return fail("could not write %s: %s", output, err)
+	}
+
+	if options.json {
+		if err := printJSON(convertResult{Input: input, Output: output, Size: len(converted)}); err != nil {
+			return fail("%s", err)
+		}
+	}
+
+	return ExitOK
+}
+
+func readInput(input, mpqPath string) ([]byte, error) {
+	if mpqPath == "" {
+		return ioutil.ReadFile(filepath.Clean(input))
+	}
+
+	archives := hsarchive.NewRegistry(hsarchive.DefaultCacheSize)
+
+	return archives.ReadFile(mpqPath, input)
+}
+
+func findConverter(input string, data []byte, to string) (converter, error) {
+	to = strings.ToLower(to)
+	if !strings.HasPrefix(to, ".") {
+		to = "." + to
+	}
+
+	fileType, err := hsfiletypes.GetFileTypeFromExtension(filepath.Ext(input), &data)
+	if err != nil {
+		return nil, fmt.Errorf("unknown file type of %s", input)
+	}
+
+	for _, c := range conversions() {
+		if c.from == fileType && c.extension == to {
+			return c.convert, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s files can not be converted to %s", filepath.Ext(input), to)
+}
+
+func stringTableToJSON(data []byte) ([]byte, error) {
+	table, err := d2tbl.LoadTextDictionary(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(table, "", "   ")
+}
+
+// stringTableToText writes one tab separated key and string per line, line breaks inside of strings are escaped
+func stringTableToText(data []byte) ([]byte, error) {
+	table, err := d2tbl.LoadTextDictionary(data)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	escape := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\t", "\\t")
+
+	var result strings.Builder
+
+	for _, key := range keys {
+		result.WriteString(escape.Replace(key) + "\t" + escape.Replace(table[key]) + "\n")
+	}
+
+	return []byte(result.String()), nil
+}
+
+// textTableToJSON converts a tab separated table (like the ones in data\global\excel) into an array of objects,
+// keyed by the column names in the first line
+func textTableToJSON(data []byte) ([]byte, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		return nil, fmt.Errorf("the table has no header")
+	}
+
+	columns := strings.Split(lines[0], "\t")
+	rows := make([]map[string]string, 0, len(lines)-1)
+
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+
+		values := strings.Split(line, "\t")
+		row := make(map[string]string, len(columns))
+
+		for idx, column := range columns {
+			if idx < len(values) {
+				row[column] = values[idx]
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return json.MarshalIndent(rows, "", "   ")
+}
+
+// paletteToJSON converts a palette into an array of #rrggbb colors
+func paletteToJSON(data []byte) ([]byte, error) {
+	palette, err := d2dat.Load(data)
+	if err != nil {
+		return nil, err
+	}
+
+	colors := palette.GetColors()
+	result := make([]string, 0, palette.NumColors())
+
+	for idx := 0; idx < palette.NumColors(); idx++ {
+		result = append(result, fmt.Sprintf("#%02x%02x%02x", colors[idx].R(), colors[idx].G(), colors[idx].B()))
+	}
+
+	return json.MarshalIndent(result, "", "   ")
+}
+
+// paletteToACT converts a palette into an Adobe Color Table, which can be loaded by most image editors
+func paletteToACT(data []byte) ([]byte, error) {
+	palette, err := d2dat.Load(data)
+	if err != nil {
+		return nil, err
+	}
+
+	colors := palette.GetColors()
+	result := make([]byte, 0, len(colors)*3) // nolint:gomnd // 3 bytes per color
+
+	for idx := range colors {
+		result = append(result, colors[idx].R(), colors[idx].G(), colors[idx].B())
+	}
+
+	return result, nil
+}
+
+func pl2ToJSON(data []byte) ([]byte, error) {
+	pl2, err := d2pl2.Load(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(pl2, "", "   ")
+}
+
+type cofLayerJSON struct {
+	Type        string `json:"type"`
+	Shadow      byte   `json:"shadow"`
+	Selectable  bool   `json:"selectable"`
+	Transparent bool   `json:"transparent"`
+	DrawEffect  int    `json:"draw_effect"`
+	WeaponClass string `json:"weapon_class"`
+}
+
+type cofJSON struct {
+	Directions         int            `json:"directions"`
+	FramesPerDirection int            `json:"frames_per_direction"`
+	Speed              int            `json:"speed"`
+	Layers             []cofLayerJSON `json:"layers"`
+	AnimationFrames    []int          `json:"animation_frames"`
+	Priority           [][][]string   `json:"priority"`
+}
+
+// cofToJSON converts a COF file into JSON, layers are named by their composite type (HD, TR, ...)
+func cofToJSON(data []byte) ([]byte, error) {
+	cof, err := d2cof.Load(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := cofJSON{
+		Directions:         cof.NumberOfDirections,
+		FramesPerDirection: cof.FramesPerDirection,
+		Speed:              cof.Speed,
+		Layers:             make([]cofLayerJSON, len(cof.CofLayers)),
+		AnimationFrames:    make([]int, len(cof.AnimationFrames)),
+		Priority:           make([][][]string, len(cof.Priority)),
+	}
+
+	for idx, layer := range cof.CofLayers {
+		result.Layers[idx] = cofLayerJSON{
+			Type:        layer.Type.String(),
+			Shadow:      layer.Shadow,
+			Selectable:  layer.Selectable,
+			Transparent: layer.Transparent,
+			DrawEffect:  int(layer.DrawEffect),
+			WeaponClass: layer.WeaponClass.String(),
+		}
+	}
+
+	for idx, frame := range cof.AnimationFrames {
+		result.AnimationFrames[idx] = int(frame)
+	}
+
+	for direction := range cof.Priority {
+		result.Priority[direction] = make([][]string, len(cof.Priority[direction]))
+
+		for frame := range cof.Priority[direction] {
+			layers := make([]string, len(cof.Priority[direction][frame]))
+
+			for idx, layer := range cof.Priority[direction][frame] {
+				layers[idx] = layer.String()
+			}
+
+			result.Priority[direction][frame] = layers
+		}
+	}
+
+	return json.MarshalIndent(result, "", "   ")
+}
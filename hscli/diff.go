@@ -0,0 +1,102 @@
+package hscli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsarchive"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+func runDiff(args []string) int {
+	var options commonFlags
+
+	var projectFile string
+
+	flags := newFlagSet("diff")
+	flags.StringVar(&projectFile, "project", "", "compare the project with its auxiliary MPQs instead of two archives")
+	options.registerProject(flags)
+	options.registerJSON(flags)
+
+	usage := "usage: hellspawner diff [options] <old.mpq[,...]> <new.mpq[,...]> | hellspawner diff [options] -project <project.hsp>"
+
+	if err := flags.Parse(args); err != nil {
+		return fail("%s", usage)
+	}
+
+	config, err := options.loadConfig()
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	var a, b *hsproject.DiffSource
+
+	switch {
+	case projectFile != "" && flags.NArg() == 0:
+		project, err := loadProject(projectFile, config)
+		if err != nil {
+			return fail("%s", err)
+		}
+
+		if a, err = project.AuxiliaryDiffSource(config); err != nil {
+			return fail("%s", err)
+		}
+
+		b = project.ProjectDiffSource(config)
+	case projectFile == "" && flags.NArg() == 2:
+		// a side may consist of several archives, the first one has the highest priority
+		archives := hsarchive.NewRegistry(hsarchive.DefaultCacheSize)
+
+		var err error
+
+		if a, err = newMPQDiffSource(archives, flags.Arg(0), config); err != nil {
+			return fail("%s", err)
+		}
+
+		if b, err = newMPQDiffSource(archives, flags.Arg(1), config); err != nil {
+			return fail("%s", err)
+		}
+	default:
+		return fail("%s", usage)
+	}
+
+	report := hsproject.Diff(a, b, nil)
+
+	if options.json {
+		if err := printJSON(report); err != nil {
+			return fail("%s", err)
+		}
+	} else {
+		for _, entry := range report.Entries {
+			if entry.Summary == "" {
+				fmt.Printf("%-8s %s\n", entry.Status, entry.Path)
+				continue
+			}
+
+			fmt.Printf("%-8s %s: %s\n", entry.Status, entry.Path, entry.Summary)
+		}
+
+		fmt.Printf("%d changed, %d added, %d removed, %d identical\n", report.Count(hsproject.DiffStatusChanged),
+			report.Count(hsproject.DiffStatusAdded), report.Count(hsproject.DiffStatusRemoved), report.Identical)
+	}
+
+	if len(report.Entries) > 0 {
+		return ExitFailure
+	}
+
+	return ExitOK
+}
+
+// newMPQDiffSource creates a diff source from a comma separated list of MPQs
+func newMPQDiffSource(archives *hsarchive.Registry, arg string, config *hsconfig.Config) (*hsproject.DiffSource, error) {
+	mpqPaths := strings.Split(arg, ",")
+
+	names := make([]string, len(mpqPaths))
+	for idx := range mpqPaths {
+		names[idx] = filepath.Base(mpqPaths[idx])
+	}
+
+	return hsproject.NewMPQDiffSource(strings.Join(names, ", "), archives, mpqPaths, config)
+}
@@ -0,0 +1,3 @@
+// Package hscli contains HellSpawner's command line interface, which runs
+// project operations without opening any windows
+package hscli
@@ -0,0 +1,179 @@
+package hscli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+)
+
+type exportResult struct {
+	Project string `json:"project"`
+	Output  string `json:"output"`
+	Files   int    `json:"files"`
+	Size    int64  `json:"size"`
+}
+
+// mpqCompressionModes maps the values of the -compression option to the compression selectors
+func mpqCompressionModes() map[string]hsproject.CompressionSelector {
+	return map[string]hsproject.CompressionSelector{
+		"default": hsproject.DefaultMPQCompression,
+		"zlib":    hsproject.ZLibMPQCompression,
+		"none":    hsproject.StoredMPQCompression,
+	}
+}
+
+func runExportMPQ(args []string) int {
+	var options commonFlags
+
+	var compressionMode string
+
+	flags := newFlagSet("export-mpq")
+	flags.StringVar(&compressionMode, "compression", "default",
+		"default (zlib, audio stored), zlib (every file) or none (every file stored)")
+	options.registerJSON(flags)
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return fail("usage: hellspawner export-mpq [options] <project.hsp> <output.mpq>")
+	}
+
+	compression, found := mpqCompressionModes()[strings.ToLower(compressionMode)]
+	if !found {
+		return fail("unknown compression %s", compressionMode)
+	}
+
+	// the auxiliary MPQs are not needed, only the project's own files are exported
+	project, err := hsproject.LoadFromFile(flags.Arg(0))
+	if err != nil {
+		return fail("could not load project: %s", err)
+	}
+
+	files, err := project.GetContentFiles()
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	output := flags.Arg(1)
+
+	if err := project.ExportMPQ(output, compression, nil); err != nil {
+		return fail("could not export project: %s", err)
+	}
+
+	info, err := os.Stat(output)
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	result := exportResult{
+		Project: project.ProjectName,
+		Output:  output,
+		Files:   len(files),
+		Size:    info.Size(),
+	}
+
+	if options.json {
+		if err := printJSON(result); err != nil {
+			return fail("%s", err)
+		}
+
+		return ExitOK
+	}
+
+	fmt.Printf("exported %d files to %s (%d bytes)\n", result.Files, result.Output, result.Size)
+
+	return ExitOK
+}
+
+func runExport(args []string) int {
+	var options commonFlags
+
+	var distribution hsproject.DistributionOptions
+
+	noDataPrefix := false
+
+	flags := newFlagSet("export")
+	flags.BoolVar(&distribution.Lowercase, "lowercase", false, "convert every path to lower case")
+	flags.BoolVar(&noDataPrefix, "no-data-prefix", false, "don't place the files under data/")
+	flags.BoolVar(&distribution.IncludeEditorFiles, "include-editor-files", false, "export files only used by HellSpawner (.hsf)")
+	options.registerJSON(flags)
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return fail("usage: hellspawner export [options] <project.hsp> <output directory or .zip>")
+	}
+
+	distribution.DataPrefix = !noDataPrefix
+
+	output := flags.Arg(1)
+	if strings.EqualFold(filepath.Ext(output), ".zip") {
+		distribution.Format = hsproject.DistributionZip
+	}
+
+	// the auxiliary MPQs are not needed, only the project's own files are exported
+	project, err := hsproject.LoadFromFile(flags.Arg(0))
+	if err != nil {
+		return fail("could not load project: %s", err)
+	}
+
+	manifest, err := project.ExportDistribution(output, distribution)
+	if err != nil {
+		return fail("could not export project: %s", err)
+	}
+
+	result := exportResult{
+		Project: project.ProjectName,
+		Output:  output,
+		Files:   len(manifest.Files),
+	}
+
+	for idx := range manifest.Files {
+		result.Size += manifest.Files[idx].Size
+	}
+
+	if options.json {
+		if err := printJSON(result); err != nil {
+			return fail("%s", err)
+		}
+
+		return ExitOK
+	}
+
+	fmt.Printf("exported %d files to %s (%d bytes)\n", result.Files, result.Output, result.Size)
+
+	return ExitOK
+}
+
+func runVerify(args []string) int {
+	var options commonFlags
+
+	flags := newFlagSet("verify")
+	options.registerJSON(flags)
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return fail("usage: hellspawner verify [options] <directory or .zip>")
+	}
+
+	report, err := hsproject.VerifyDistribution(flags.Arg(0))
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	if options.json {
+		if err := printJSON(report); err != nil {
+			return fail("%s", err)
+		}
+	} else {
+		for _, problem := range report.Problems {
+			fmt.Printf("%s: %s\n", problem.Path, problem.Message)
+		}
+
+		fmt.Printf("%d files checked, %d problems found\n", report.Checked, len(report.Problems))
+	}
+
+	if len(report.Problems) > 0 {
+		return ExitFailure
+	}
+
+	return ExitOK
+}
@@ -0,0 +1,223 @@
+package hscli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsarchive"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsutil"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+const (
+	newFileMode = 0644
+	newDirMode  = 0755
+)
+
+type listResult struct {
+	Archive string   `json:"archive"`
+	Files   []string `json:"files"`
+}
+
+type extractedFile struct {
+	Path   string `json:"path"`
+	Output string `json:"output"`
+	Size   int    `json:"size"`
+}
+
+type failedFile struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+type extractResult struct {
+	Archive string          `json:"archive"`
+	Output  string          `json:"output"`
+	Files   []extractedFile `json:"files"`
+	Failed  []failedFile    `json:"failed"`
+}
+
+func runListMPQ(args []string) int {
+	var options commonFlags
+
+	flags := newFlagSet("ls-mpq")
+	options.registerListFile(flags)
+	options.registerJSON(flags)
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return fail("usage: hellspawner ls-mpq [options] <archive.mpq>")
+	}
+
+	config, err := options.loadConfig()
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	archives := hsarchive.NewRegistry(hsarchive.DefaultCacheSize)
+
+	mpq, files, err := listMPQ(archives, flags.Arg(0), config)
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	if options.json {
+		if err := printJSON(listResult{Archive: mpq.Path(), Files: files}); err != nil {
+			return fail("%s", err)
+		}
+
+		return ExitOK
+	}
+
+	for _, file := range files {
+		fmt.Println(file)
+	}
+
+	return ExitOK
+}
+
+func runExtract(args []string) int {
+	var options commonFlags
+
+	var output string
+
+	var content bool
+
+	flags := newFlagSet("extract")
+	flags.StringVar(&output, "o", ".", "directory the files are extracted to")
+	flags.BoolVar(&content, "content", false, "strip the leading data directory, so the files can be copied into a project content directory")
+	options.registerListFile(flags)
+	options.registerJSON(flags)
+
+	if err := flags.Parse(args); err != nil || flags.NArg() < 1 {
+		return fail("usage: hellspawner extract [options] <archive.mpq> [pattern...]")
+	}
+
+	matchers := make([]hsproject.PathMatcher, 0, flags.NArg()-1)
+
+	for _, pattern := range flags.Args()[1:] {
+		matcher, err := hsproject.NewPathMatcher(pattern, hsproject.SearchModeGlob)
+		if err != nil {
+			return fail("%s", err)
+		}
+
+		matchers = append(matchers, matcher)
+	}
+
+	config, err := options.loadConfig()
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	archives := hsarchive.NewRegistry(hsarchive.DefaultCacheSize)
+
+	mpq, files, err := listMPQ(archives, flags.Arg(0), config)
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	result := extractResult{
+		Archive: mpq.Path(),
+		Output:  output,
+		Files:   make([]extractedFile, 0),
+		Failed:  make([]failedFile, 0),
+	}
+
+	for _, file := range files {
+		if !matchesAny(file, matchers) {
+			continue
+		}
+
+		relPath := strings.ReplaceAll(strings.Trim(file, `\`), `\`, "/")
+		if content {
+			relPath = hsproject.ContentPathFromArchivePath(file)
+		}
+
+		outputPath := filepath.Join(output, filepath.FromSlash(relPath))
+
+		if !hsutil.IsInsideDir(outputPath, output) {
+			result.Failed = append(result.Failed, failedFile{Path: file, Error: "path leaves the output directory"})
+			continue
+		}
+
+		size, err := extractFile(archives, mpq.Path(), file, outputPath)
+		if err != nil {
+			result.Failed = append(result.Failed, failedFile{Path: file, Error: err.Error()})
+			continue
+		}
+
+		result.Files = append(result.Files, extractedFile{Path: file, Output: outputPath, Size: size})
+	}
+
+	if options.json {
+		if err := printJSON(result); err != nil {
+			return fail("%s", err)
+		}
+	} else {
+		for _, file := range result.Failed {
+			fmt.Fprintf(os.Stderr, "error: %s: %s\n", file.Path, file.Error)
+		}
+
+		fmt.Printf("extracted %d files to %s, %d failed\n", len(result.Files), result.Output, len(result.Failed))
+	}
+
+	if len(result.Failed) > 0 {
+		return ExitFailure
+	}
+
+	return ExitOK
+}
+
+// listMPQ opens an MPQ and returns the sorted list of its files
+func listMPQ(archives *hsarchive.Registry, fileName string, config *hsconfig.Config) (d2interface.Archive, []string, error) {
+	mpq, err := archives.Open(fileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open %s: %w", fileName, err)
+	}
+
+	files, err := hsproject.MPQFileList(archives, mpq, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not list the files of %s: %w", fileName, err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return strings.ToLower(files[i]) < strings.ToLower(files[j]) })
+
+	return mpq, files, nil
+}
+
+func matchesAny(path string, matchers []hsproject.PathMatcher) bool {
+	if len(matchers) == 0 {
+		return true
+	}
+
+	for _, match := range matchers {
+		if match(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func extractFile(archives *hsarchive.Registry, mpqPath, file, outputPath string) (int, error) {
+	data, err := archives.ReadFile(mpqPath, file)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), newDirMode); err != nil {
+		return 0, err
+	}
+
+	if err := ioutil.WriteFile(outputPath, data, newFileMode); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
@@ -0,0 +1,101 @@
+package hscli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsimage"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+)
+
+type exportDC6Result struct {
+	Input string   `json:"input"`
+	Files []string `json:"files"`
+}
+
+// spriteExportFormats maps the values of the -format option to the export formats
+func spriteExportFormats() map[string]hsimage.ExportFormat {
+	return map[string]hsimage.ExportFormat{
+		"png":   hsimage.ExportPNGFrames,
+		"sheet": hsimage.ExportSpriteSheet,
+		"gif":   hsimage.ExportGIF,
+	}
+}
+
+func runExportDC6(args []string) int {
+	var options commonFlags
+
+	var mpqPath, palettePath, paletteMPQPath, format string
+
+	flags := newFlagSet("export-dc6")
+	flags.StringVar(&mpqPath, "mpq", "", "read the DC6 and the palette from this MPQ instead of the disk")
+	flags.StringVar(&palettePath, "palette", hsproject.DefaultPalettePath, "palette (.dat) the frames are drawn with")
+	flags.StringVar(&paletteMPQPath, "palette-mpq", "", "read the palette from this MPQ, by default the one given with -mpq")
+	flags.StringVar(&format, "format", "png", "png (a PNG per frame), sheet (sprite sheet and JSON atlas) or gif (a GIF per direction)")
+	options.registerJSON(flags)
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return fail("usage: hellspawner export-dc6 [options] <input.dc6> <output directory>")
+	}
+
+	exportFormat, found := spriteExportFormats()[strings.ToLower(format)]
+	if !found {
+		return fail("unknown format %s", format)
+	}
+
+	input, output := flags.Arg(0), flags.Arg(1)
+
+	data, err := readInput(input, mpqPath)
+	if err != nil {
+		return fail("could not read %s: %s", input, err)
+	}
+
+	dc6, err := d2dc6.Load(data)
+	if err != nil {
+		return fail("could not decode %s: %s", input, err)
+	}
+
+	if paletteMPQPath == "" {
+		paletteMPQPath = mpqPath
+	}
+
+	// the default palette is only found in the game's MPQs
+	if paletteMPQPath == "" && palettePath == hsproject.DefaultPalettePath {
+		return fail("a DC6 read from the disk needs -palette <pal.dat> or -palette-mpq <archive.mpq>")
+	}
+
+	paletteData, err := readInput(palettePath, paletteMPQPath)
+	if err != nil {
+		return fail("could not read %s: %s", palettePath, err)
+	}
+
+	palette, err := d2dat.Load(paletteData)
+	if err != nil {
+		return fail("could not decode %s: %s", palettePath, err)
+	}
+
+	// MPQ paths are separated by backslashes
+	name := filepath.Base(strings.ReplaceAll(input, `\`, "/"))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	files, err := hsimage.ExportDC6(dc6, palette, exportFormat, output, name)
+	if err != nil {
+		return fail("could not export %s: %s", input, err)
+	}
+
+	if options.json {
+		if err := printJSON(exportDC6Result{Input: input, Files: files}); err != nil {
+			return fail("%s", err)
+		}
+
+		return ExitOK
+	}
+
+	fmt.Printf("exported %s to %d files in %s\n", input, len(files), output)
+
+	return ExitOK
+}
@@ -0,0 +1,50 @@
+package hscli
+
+import (
+	"fmt"
+)
+
+func runValidate(args []string) int {
+	var options commonFlags
+
+	flags := newFlagSet("validate")
+	options.registerProject(flags)
+	options.registerJSON(flags)
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return fail("usage: hellspawner validate [options] <project.hsp>")
+	}
+
+	config, err := options.loadConfig()
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	project, err := loadProject(flags.Arg(0), config)
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	report := project.Validate(config)
+
+	if options.json {
+		data, jsonErr := report.JSON()
+		if jsonErr != nil {
+			return fail("%s", jsonErr)
+		}
+
+		fmt.Println(string(data))
+	} else {
+		for _, problem := range report.Problems {
+			fmt.Printf("%s: %s: %s\n", problem.Severity, problem.Path, problem.Message)
+		}
+
+		fmt.Printf("%d files checked, %d problems found\n", report.FileCount, len(report.Problems))
+	}
+
+	if report.HasErrors() {
+		return ExitFailure
+	}
+
+	return ExitOK
+}
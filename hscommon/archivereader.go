@@ -0,0 +1,30 @@
+package hscommon
+
+import "sync"
+
+// ArchiveReader reads files from MPQ archives, it is used by PathEntry for files that come from MPQs
+type ArchiveReader interface {
+	// ReadFile returns the contents of filePath inside of the MPQ at mpqPath
+	ReadFile(mpqPath, filePath string) ([]byte, error)
+}
+
+// nolint:gochecknoglobals // path entries are restored from JSON, so they can't carry a reader themselves
+var (
+	archiveReader      ArchiveReader
+	archiveReaderMutex sync.RWMutex
+)
+
+// SetArchiveReader sets the reader used by all path entries to read files from MPQs.
+// When no reader is set, the MPQ is opened every time a file is read.
+func SetArchiveReader(reader ArchiveReader) {
+	archiveReaderMutex.Lock()
+	archiveReader = reader
+	archiveReaderMutex.Unlock()
+}
+
+func getArchiveReader() ArchiveReader {
+	archiveReaderMutex.RLock()
+	defer archiveReaderMutex.RUnlock()
+
+	return archiveReader
+}
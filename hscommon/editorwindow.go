@@ -21,6 +21,15 @@ type EditorWindow interface {
 	BringToFront()
 	// State returns the current state of this editor, in a JSON-serializable struct
 	State() hsstate.EditorState
+	// RestoreState applies the parts of a saved state which aren't restored by creating the editor (e.g. the palette)
+	RestoreState(state hsstate.EditorState)
 	// Save writes any changes made in the editor to the file that is open in the editor.
 	Save()
+	// CheckExternalChanges returns true (once per change) if the file was changed by another program
+	// since the editor loaded or saved it.
+	CheckExternalChanges() bool
+	// DiscardChanges makes the editor forget its unsaved changes, so closing it won't ask to save them.
+	DiscardChanges()
+	// Move points the editor at the new path of its file, after the file was moved or renamed.
+	Move(filePath string)
 }
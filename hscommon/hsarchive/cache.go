@@ -0,0 +1,79 @@
+package hsarchive
+
+import (
+	"container/list"
+	"sync"
+)
+
+type cacheItem struct {
+	key  string
+	data []byte
+}
+
+// fileCache is a least-recently-used cache of file contents, limited by the total size of the cached data
+type fileCache struct {
+	mutex    sync.Mutex
+	maxBytes int
+	curBytes int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+func newFileCache(maxBytes int) *fileCache {
+	return &fileCache{
+		maxBytes: maxBytes,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *fileCache) get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, found := c.items[key]
+	if !found {
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+
+	return element.Value.(*cacheItem).data, true
+}
+
+func (c *fileCache) put(key string, data []byte) {
+	if len(data) > c.maxBytes {
+		// would evict everything else and still not fit
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, found := c.items[key]; found {
+		c.curBytes -= len(element.Value.(*cacheItem).data)
+		c.order.Remove(element)
+		delete(c.items, key)
+	}
+
+	c.items[key] = c.order.PushFront(&cacheItem{key: key, data: data})
+	c.curBytes += len(data)
+
+	for c.curBytes > c.maxBytes {
+		oldest := c.order.Back()
+		item := oldest.Value.(*cacheItem)
+
+		c.order.Remove(oldest)
+		delete(c.items, item.key)
+		c.curBytes -= len(item.data)
+	}
+}
+
+func (c *fileCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.curBytes = 0
+}
@@ -0,0 +1,2 @@
+// Package hsarchive provides shared, concurrency-safe access to MPQ archives
+package hsarchive
@@ -0,0 +1,237 @@
+package hsarchive
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2mpq"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+const (
+	// DefaultCacheSize is the default amount of decompressed file data kept in memory (64 MiB)
+	DefaultCacheSize = 64 << 20
+)
+
+// errArchiveClosed is returned by the reads which were still using an archive when the registry was invalidated
+var errArchiveClosed = errors.New("the archive was closed")
+
+// archiveHandle is a single open archive. Reading from an archive moves the underlying
+// file's read position, so all reads are serialized through the handle's mutex.
+type archiveHandle struct {
+	mutex    sync.Mutex
+	archive  d2interface.Archive
+	closed   bool
+	listfile []string
+	listErr  error
+	listRead bool
+
+	// names are the file names found without the listfile, see Names
+	namesOnce sync.Once
+	names     []string
+}
+
+// Registry keeps every MPQ archive open once, and caches the contents of recently read files.
+// It is safe to use from multiple goroutines.
+type Registry struct {
+	mutex    sync.Mutex
+	archives map[string]*archiveHandle
+	cache    *fileCache
+	// generation is increased by Invalidate, the data read before it is not cached
+	generation int
+}
+
+// NewRegistry creates a new archive registry, caching up to cacheSize bytes of file data
+func NewRegistry(cacheSize int) *Registry {
+	return &Registry{
+		archives: make(map[string]*archiveHandle),
+		cache:    newFileCache(cacheSize),
+	}
+}
+
+func (r *Registry) getHandle(mpqPath string) (*archiveHandle, error) {
+	key := filepath.Clean(mpqPath)
+
+	for {
+		r.mutex.Lock()
+		handle, found := r.archives[key]
+		generation := r.generation
+		r.mutex.Unlock()
+
+		if found {
+			return handle, nil
+		}
+
+		// opening large archives takes a while, so don't block the other archives while doing it
+		archive, err := d2mpq.FromFile(key)
+		if err != nil {
+			return nil, err
+		}
+
+		r.mutex.Lock()
+
+		if handle, found = r.archives[key]; found || generation != r.generation {
+			// someone else opened it in the meantime, or the registry was invalidated and the file may have changed
+			r.mutex.Unlock()
+
+			_ = archive.Close()
+
+			if found {
+				return handle, nil
+			}
+
+			continue
+		}
+
+		handle = &archiveHandle{archive: archive}
+		r.archives[key] = handle
+
+		r.mutex.Unlock()
+
+		return handle, nil
+	}
+}
+
+// Open returns the archive at the given path, opening it if it hasn't been opened yet.
+// Reads should go through the registry's ReadFile, which serializes access to the archive.
+func (r *Registry) Open(mpqPath string) (d2interface.Archive, error) {
+	handle, err := r.getHandle(mpqPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return handle.archive, nil
+}
+
+// ReadFile returns the contents of a file inside of an archive.
+// The returned slice is a copy, so callers are free to modify it.
+func (r *Registry) ReadFile(mpqPath, filePath string) ([]byte, error) {
+	cacheKey := filepath.Clean(mpqPath) + "|" + strings.ToLower(strings.ReplaceAll(filePath, "/", `\`))
+
+	if data, found := r.cache.get(cacheKey); found {
+		return copyBytes(data), nil
+	}
+
+	r.mutex.Lock()
+	generation := r.generation
+	r.mutex.Unlock()
+
+	handle, err := r.getHandle(mpqPath)
+	if err != nil {
+		return nil, err
+	}
+
+	handle.mutex.Lock()
+
+	if handle.closed {
+		handle.mutex.Unlock()
+
+		return nil, errArchiveClosed
+	}
+
+	if !handle.archive.Contains(filePath) {
+		handle.mutex.Unlock()
+
+		return nil, errors.New("could not locate file in mpq")
+	}
+
+	data, err := handle.archive.ReadFile(filePath)
+
+	handle.mutex.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// data read from an archive which was invalidated in the meantime may be stale
+	r.mutex.Lock()
+
+	if generation == r.generation {
+		r.cache.put(cacheKey, data)
+	}
+
+	r.mutex.Unlock()
+
+	return copyBytes(data), nil
+}
+
+// Contains returns true if the archive contains the given file
+func (r *Registry) Contains(mpqPath, filePath string) bool {
+	handle, err := r.getHandle(mpqPath)
+	if err != nil {
+		return false
+	}
+
+	handle.mutex.Lock()
+	defer handle.mutex.Unlock()
+
+	return !handle.closed && handle.archive.Contains(filePath)
+}
+
+// Listfile returns the archive's listfile. The result is read once and then kept for the lifetime of the registry.
+func (r *Registry) Listfile(mpqPath string) ([]string, error) {
+	handle, err := r.getHandle(mpqPath)
+	if err != nil {
+		return nil, err
+	}
+
+	handle.mutex.Lock()
+	defer handle.mutex.Unlock()
+
+	if handle.closed && !handle.listRead {
+		return nil, errArchiveClosed
+	}
+
+	if !handle.listRead {
+		handle.listfile, handle.listErr = handle.archive.Listfile()
+		handle.listRead = true
+	}
+
+	return handle.listfile, handle.listErr
+}
+
+// Names returns the names of the files inside of an archive without a listfile. They are looked up
+// by the find function once and then kept for the lifetime of the registry.
+// find may read from the archive, so it is not called while holding the archive's mutex.
+func (r *Registry) Names(mpqPath string, find func() []string) ([]string, error) {
+	handle, err := r.getHandle(mpqPath)
+	if err != nil {
+		return nil, err
+	}
+
+	handle.namesOnce.Do(func() {
+		handle.names = find()
+	})
+
+	return handle.names, nil
+}
+
+// Invalidate closes every open archive and clears the file cache.
+// It should be called whenever the archives on disk (or the path they are loaded from) may have changed.
+// The archives returned by Open before can't be read anymore.
+func (r *Registry) Invalidate() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, handle := range r.archives {
+		// waits for the read in progress, the reads after it fail
+		handle.mutex.Lock()
+		handle.closed = true
+		_ = handle.archive.Close()
+		handle.mutex.Unlock()
+	}
+
+	r.archives = make(map[string]*archiveHandle)
+	r.generation++
+
+	r.cache.clear()
+}
+
+func copyBytes(data []byte) []byte {
+	result := make([]byte, len(data))
+	copy(result, data)
+
+	return result
+}
@@ -0,0 +1,88 @@
+package hsharvest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1" // nolint:gosec // only used to name the cache files
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	cacheExtension  = ".txt"
+	cacheHashLength = 12
+
+	newFileMode = 0644
+	newDirMode  = 0755
+)
+
+// cacheFileName returns the name of an archive's cache file. The name contains a hash of the archive's
+// absolute path, size and modification time, so a modified archive is harvested again.
+func cacheFileName(cacheDir, mpqPath string) (string, error) {
+	absPath, err := filepath.Abs(mpqPath)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("%s|%d|%d", strings.ToLower(absPath), info.Size(), info.ModTime().UnixNano())
+	hash := sha1.Sum([]byte(key)) // nolint:gosec // only used to name the cache files
+	base := strings.TrimSuffix(filepath.Base(absPath), filepath.Ext(absPath))
+
+	return filepath.Join(cacheDir, base+"-"+hex.EncodeToString(hash[:])[:cacheHashLength]+cacheExtension), nil
+}
+
+// LoadCache returns the names harvested from an archive before. The second return value is false
+// if the archive hasn't been harvested yet (or it changed since).
+func LoadCache(cacheDir, mpqPath string) ([]string, bool) {
+	if cacheDir == "" {
+		return nil, false
+	}
+
+	fileName, err := cacheFileName(cacheDir, mpqPath)
+	if err != nil {
+		return nil, false
+	}
+
+	data, err := ioutil.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		return nil, false
+	}
+
+	result := make([]string, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			result = append(result, name)
+		}
+	}
+
+	return result, scanner.Err() == nil
+}
+
+// SaveCache stores the names harvested from an archive, in the format of a listfile
+func SaveCache(cacheDir, mpqPath string, names []string) error {
+	if cacheDir == "" {
+		return nil
+	}
+
+	fileName, err := cacheFileName(cacheDir, mpqPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(cacheDir, newDirMode); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(fileName, []byte(strings.Join(names, "\r\n")+"\r\n"), newFileMode)
+}
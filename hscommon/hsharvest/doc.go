@@ -0,0 +1,7 @@
+// Package hsharvest recovers the file names of MPQ archives which have no listfile.
+//
+// Starting with well known file names, every file found in the archive is read and the names it
+// mentions are tried as well: the tile files of DS1 maps, the DCC layers of COF animations, the
+// monster and object tokens and the file columns of the excel tables. Combined with the naming
+// conventions of the game's directories, this finds most of the files of a typical mod archive.
+package hsharvest
@@ -0,0 +1,307 @@
+package hsharvest
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2cof"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2ds1"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsreference"
+)
+
+const (
+	// a COF file is named <token><mode><weapon class>.cof, for example ZMNUHTH.cof
+	cofModeLength        = 2
+	cofWeaponClassLength = 3
+	// armor types and item codes are three letters long
+	codeLength = 3
+)
+
+var errDecoder = errors.New("the decoder failed")
+
+// Archive is the part of an MPQ archive used by the harvester
+type Archive interface {
+	Contains(filePath string) bool
+	ReadFile(filePath string) ([]byte, error)
+}
+
+// tokenKind is the kind of animated entity a token (e.g. ZM for zombies) belongs to
+type tokenKind int
+
+const (
+	tokenCharacter tokenKind = iota
+	tokenMonster
+	tokenObject
+)
+
+// harvester holds the state of a single Harvest call
+type harvester struct {
+	archive Archive
+
+	// tried contains the lower case name of every parsed candidate, found contains every file inside of the archive
+	tried map[string]bool
+	found map[string]string
+	// queue contains the found files which have not been parsed yet
+	queue []string
+
+	tokens     map[tokenKind]map[string]bool
+	itemCodes  map[string]bool
+	armorTypes map[string]bool
+}
+
+// Harvest returns the names of the archive's files which could be derived from the well known file names,
+// the given seeds (e.g. names from an external listfile) and the content of the files found.
+func Harvest(archive Archive, seeds []string) []string {
+	h := &harvester{
+		archive: archive,
+		tried:   make(map[string]bool),
+		found:   make(map[string]string),
+		tokens: map[tokenKind]map[string]bool{
+			tokenCharacter: make(map[string]bool),
+			tokenMonster:   make(map[string]bool),
+			tokenObject:    make(map[string]bool),
+		},
+		itemCodes:  make(map[string]bool),
+		armorTypes: make(map[string]bool),
+	}
+
+	for _, armorType := range defaultArmorTypes() {
+		h.armorTypes[armorType] = true
+	}
+
+	for _, name := range append(knownFiles(), seeds...) {
+		h.try(name)
+	}
+
+	for _, token := range characterTokens() {
+		h.addToken(tokenCharacter, token)
+	}
+
+	for len(h.queue) > 0 {
+		name := h.queue[0]
+		h.queue = h.queue[1:]
+
+		h.parse(name)
+	}
+
+	result := make([]string, 0, len(h.found))
+	for _, name := range h.found {
+		result = append(result, name)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return strings.ToLower(result[i]) < strings.ToLower(result[j]) })
+
+	return result
+}
+
+// try checks if the archive contains the file, found files are queued for parsing
+func (h *harvester) try(name string) bool {
+	name = normalizePath(name)
+	if name == "" {
+		return false
+	}
+
+	key := strings.ToLower(name)
+	if _, found := h.found[key]; found {
+		return true
+	}
+
+	// only the candidates which would be parsed are remembered, there are millions of DCC candidates
+	parsed := isParsed(name)
+	if parsed {
+		if h.tried[key] {
+			return false
+		}
+
+		h.tried[key] = true
+	}
+
+	if !h.archive.Contains(name) {
+		return false
+	}
+
+	h.found[key] = name
+
+	if parsed {
+		h.queue = append(h.queue, name)
+	}
+
+	return true
+}
+
+// tryWithPrefixes tries a (relative) path in each of the given directories
+func (h *harvester) tryWithPrefixes(name string, prefixes []string) {
+	for _, prefix := range prefixes {
+		if h.try(prefix + name) {
+			return
+		}
+	}
+}
+
+// isParsed returns true if the harvester looks for more file names inside of the file
+func isParsed(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".txt", ".ds1", ".cof":
+		return true
+	}
+
+	return false
+}
+
+func (h *harvester) parse(name string) {
+	data, err := h.archive.ReadFile(name)
+	if err != nil || len(data) == 0 {
+		return
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".txt":
+		h.parseTable(name, data)
+	case ".ds1":
+		h.parseDS1(data)
+	case ".cof":
+		h.parseCOF(name, data)
+	}
+}
+
+// parseDS1 tries the tile files of a map
+func (h *harvester) parseDS1(data []byte) {
+	ds1, err := loadDS1(data)
+	if err != nil {
+		return
+	}
+
+	for _, file := range ds1.Files {
+		if file != "" {
+			h.try(hsreference.DS1TilePath(file))
+		}
+	}
+}
+
+// parseCOF tries the DCC file of each layer with every known armor type
+func (h *harvester) parseCOF(name string, data []byte) {
+	cof, err := loadCOF(data)
+	if err != nil {
+		return
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(strings.ReplaceAll(name, `\`, "/")), filepath.Ext(name))
+	if len(baseName) <= cofModeLength+cofWeaponClassLength {
+		return
+	}
+
+	token := baseName[:len(baseName)-cofModeLength-cofWeaponClassLength]
+	mode := baseName[len(token) : len(token)+cofModeLength]
+	tokenDir := dirName(dirName(name))
+
+	for idx := range cof.CofLayers {
+		layer := cof.CofLayers[idx]
+		layerName := layer.Type.String()
+		prefix := tokenDir + `\` + layerName + `\` + token + layerName
+		suffix := mode + strings.ToUpper(layer.WeaponClass.String()) + ".dcc"
+
+		for _, armorType := range h.layerArmorTypes(layerName) {
+			h.try(prefix + armorType + suffix)
+		}
+	}
+}
+
+// layerArmorTypes returns the armor types to try for a layer. Weapons, shields and helmets
+// use item codes, the other layers use the body armor types.
+func (h *harvester) layerArmorTypes(layerName string) []string {
+	codes := h.armorTypes
+
+	switch layerName {
+	case "HD", "RH", "LH", "SH":
+		codes = mergeSets(h.itemCodes, h.armorTypes)
+	}
+
+	result := make([]string, 0, len(codes))
+	for code := range codes {
+		result = append(result, code)
+	}
+
+	return result
+}
+
+// addToken tries every COF file of a new monster, object or character token
+func (h *harvester) addToken(kind tokenKind, token string) {
+	token = strings.ToUpper(strings.TrimSpace(token))
+	if token == "" || strings.ContainsAny(token, `\/. `) || h.tokens[kind][token] {
+		return
+	}
+
+	h.tokens[kind][token] = true
+
+	dir := tokenDirectory(kind) + token + `\COF\`
+
+	for _, mode := range modes(kind) {
+		for _, weaponClass := range weaponClasses() {
+			h.try(dir + token + mode + weaponClass + ".cof")
+		}
+	}
+}
+
+func tokenDirectory(kind tokenKind) string {
+	switch kind {
+	case tokenCharacter:
+		return `data\global\chars\`
+	case tokenMonster:
+		return `data\global\monsters\`
+	default:
+		return `data\global\objects\`
+	}
+}
+
+func normalizePath(name string) string {
+	name = strings.TrimSpace(strings.ReplaceAll(name, "/", `\`))
+
+	return strings.TrimLeft(name, `\`)
+}
+
+func dirName(path string) string {
+	idx := strings.LastIndex(path, `\`)
+	if idx < 0 {
+		return ""
+	}
+
+	return path[:idx]
+}
+
+func mergeSets(a, b map[string]bool) map[string]bool {
+	result := make(map[string]bool, len(a)+len(b))
+
+	for key := range a {
+		result[key] = true
+	}
+
+	for key := range b {
+		result[key] = true
+	}
+
+	return result
+}
+
+// loadCOF and loadDS1 recover from the panics of the decoders, files found by guessing may be anything
+func loadCOF(data []byte) (cof *d2cof.COF, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			cof, err = nil, errDecoder
+		}
+	}()
+
+	return d2cof.Load(data)
+}
+
+func loadDS1(data []byte) (ds1 *d2ds1.DS1, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ds1, err = nil, errDecoder
+		}
+	}()
+
+	return d2ds1.LoadDS1(data)
+}
@@ -0,0 +1,210 @@
+package hsharvest
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// knownFiles returns the names of files which exist in (almost) every version of the game
+func knownFiles() []string {
+	result := make([]string, 0)
+
+	for _, table := range excelTables() {
+		result = append(result, `data\global\excel\`+table+".txt", `data\global\excel\`+table+".bin")
+	}
+
+	for _, palette := range []string{"ACT1", "ACT2", "ACT3", "ACT4", "ACT5", "Units", "Static", "Loading",
+		"Sky", "Trademark", "EndGame", "fechar", "Menu0", "Menu1", "Menu2", "Menu3", "Menu4"} {
+		result = append(result, `data\global\palette\`+palette+`\pal.dat`, `data\global\palette\`+palette+`\Pal.PL2`)
+	}
+
+	for _, font := range []string{"font6", "font8", "font16", "font24", "font30", "font42", "fontformal10",
+		"fontformal11", "fontformal12", "fontexocet8", "fontexocet10", "fontridiculous", "fontinguild"} {
+		for _, dir := range []string{`data\local\font\latin\`, `data\local\FONT\LATIN\`} {
+			result = append(result, dir+font+".dc6", dir+font+".tbl")
+		}
+	}
+
+	for _, language := range []string{"eng", "deu", "fra", "ita", "esp", "pol", "kor", "chi", "jpn", "por", "rus"} {
+		for _, table := range []string{"string", "expansionstring", "patchstring"} {
+			result = append(result, `data\local\lng\`+language+`\`+table+".tbl")
+		}
+	}
+
+	return result
+}
+
+func excelTables() []string {
+	return []string{
+		"Armor", "Arena", "ArmType", "AutoMagic", "AutoMap", "BodyLocs", "Books", "CharStats", "CharTemplate",
+		"CompCode", "Composit", "CubeMain", "CubeMod", "DifficultyLevels", "ElemTypes", "Events", "Experience",
+		"Gamble", "Gems", "Hireling", "HireDesc", "HitClass", "Inventory", "ItemRatio", "ItemStatCost", "ItemTypes",
+		"Levels", "LowQualityItems", "LvlMaze", "LvlPrest", "LvlSub", "LvlTypes", "LvlWarp", "MagicPrefix",
+		"MagicSuffix", "Misc", "MissCalc", "Missiles", "MonAi", "MonEquip", "MonItemPercent", "MonLvl", "MonMode",
+		"MonPlace", "MonPreset", "MonProp", "MonSeq", "MonSounds", "MonStats", "MonStats2", "MonType", "MonUMod",
+		"NPC", "ObjGroup", "ObjMode", "Objects", "ObjType", "Overlay", "PetType", "PlayerClass", "PlrMode",
+		"PlrType", "Properties", "QualityItems", "RarePrefix", "RareSuffix", "Runes", "SetItems", "Sets", "Shrines",
+		"SkillCalc", "SkillDesc", "Skills", "Sounds", "States", "StorePage", "SuperUniques", "TreasureClass",
+		"TreasureClassEx", "UniqueAppellation", "UniqueItems", "UniquePrefix", "UniqueSuffix", "UniqueTitle",
+		"WeaponClass", "Weapons",
+	}
+}
+
+func characterTokens() []string {
+	return []string{"AM", "SO", "NE", "PA", "BA", "DZ", "AI"}
+}
+
+// modes returns the animation modes of PlrMode.txt, MonMode.txt and ObjMode.txt
+func modes(kind tokenKind) []string {
+	switch kind {
+	case tokenCharacter:
+		return []string{"DT", "NU", "WL", "RN", "GH", "TN", "TW", "A1", "A2", "BL", "SC", "TH", "KK",
+			"S1", "S2", "S3", "S4", "DD", "QU"}
+	case tokenMonster:
+		return []string{"DT", "NU", "WL", "GH", "A1", "A2", "BL", "SC", "S1", "S2", "S3", "S4", "DD", "KB", "SQ", "RN"}
+	default:
+		return []string{"NU", "OP", "ON", "S1", "S2", "S3", "S4", "S5"}
+	}
+}
+
+// weaponClasses returns the weapon classes of WeaponClass.txt
+func weaponClasses() []string {
+	return []string{"HTH", "BOW", "1HS", "1HT", "STF", "2HS", "2HT", "XBW", "1JS", "1JT", "1SS", "1ST", "HT1", "HT2"}
+}
+
+// defaultArmorTypes returns the armor types used by the body layers
+func defaultArmorTypes() []string {
+	return []string{"LIT", "MED", "HVY"}
+}
+
+// fileColumns maps the columns of the excel tables which contain file names without directory
+// (or extension) to the directory and extension of the files. A column is looked up as
+// "<table>.<column>" first, then as "<column>".
+func fileColumns() map[string][2]string {
+	return map[string][2]string{
+		"invfile":          {`data\global\items\`, ".dc6"},
+		"flippyfile":       {`data\global\items\`, ".dc6"},
+		"uniqueinvfile":    {`data\global\items\`, ".dc6"},
+		"setinvfile":       {`data\global\items\`, ".dc6"},
+		"celfile":          {`data\global\missiles\`, ".dcc"},
+		"overlay.filename": {`data\global\overlays\`, ".dcc"},
+		"sounds.filename":  {`data\global\sfx\`, ""},
+		"lvlprest.file1":   {`data\global\tiles\`, ""},
+		"lvlprest.file2":   {`data\global\tiles\`, ""},
+		"lvlprest.file3":   {`data\global\tiles\`, ""},
+		"lvlprest.file4":   {`data\global\tiles\`, ""},
+		"lvlprest.file5":   {`data\global\tiles\`, ""},
+		"lvlprest.file6":   {`data\global\tiles\`, ""},
+		"lvltypes.file 1":  {`data\global\tiles\`, ""},
+		"lvltypes.file 2":  {`data\global\tiles\`, ""},
+		"lvltypes.file 3":  {`data\global\tiles\`, ""},
+		"lvltypes.file 4":  {`data\global\tiles\`, ""},
+		"lvltypes.file 5":  {`data\global\tiles\`, ""},
+		"lvltypes.file 6":  {`data\global\tiles\`, ""},
+		"lvltypes.file 7":  {`data\global\tiles\`, ""},
+		"lvltypes.file 8":  {`data\global\tiles\`, ""},
+	}
+}
+
+// pathPrefixes returns the directories relative file names of the excel tables are tried in
+func pathPrefixes() []string {
+	return []string{
+		"",
+		`data\`,
+		`data\global\`,
+		`data\global\tiles\`,
+		`data\global\sfx\`,
+		`data\global\music\`,
+		`data\global\overlays\`,
+		`data\global\missiles\`,
+		`data\global\items\`,
+	}
+}
+
+// isFileName returns true if the value has the extension of a game file
+func isFileName(value string) bool {
+	switch strings.ToLower(filepath.Ext(value)) {
+	case ".dc6", ".dcc", ".cof", ".ds1", ".dt1", ".wav", ".txt", ".tbl", ".dat", ".pl2", ".bin", ".smk":
+		return true
+	}
+
+	return false
+}
+
+// parseTable collects the tokens, item codes and file names of an excel table
+func (h *harvester) parseTable(name string, data []byte) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) < 2 {
+		return
+	}
+
+	header := strings.Split(lines[0], "\t")
+	for idx := range header {
+		header[idx] = strings.ToLower(strings.TrimSpace(header[idx]))
+	}
+
+	table := strings.ToLower(strings.TrimSuffix(filepath.Base(strings.ReplaceAll(name, `\`, "/")), filepath.Ext(name)))
+	columns := fileColumns()
+	prefixes := pathPrefixes()
+
+	for _, line := range lines[1:] {
+		cells := strings.Split(line, "\t")
+
+		for idx, cell := range cells {
+			cell = strings.TrimSpace(cell)
+			if cell == "" || idx >= len(header) {
+				continue
+			}
+
+			h.parseCell(table, header[idx], cell, columns, prefixes)
+		}
+	}
+}
+
+func (h *harvester) parseCell(table, column, cell string, columns map[string][2]string, prefixes []string) {
+	switch {
+	case table == "monstats" && column == "code":
+		h.addToken(tokenMonster, cell)
+		return
+	case table == "objects" && column == "token":
+		h.addToken(tokenObject, cell)
+		return
+	case (table == "armor" || table == "weapons" || table == "misc") &&
+		(column == "code" || column == "alternategfx" || column == "normcode" ||
+			column == "ubercode" || column == "ultracode"):
+		if len(cell) == codeLength {
+			h.itemCodes[strings.ToUpper(cell)] = true
+		}
+
+		return
+	case table == "monstats2" && strings.HasSuffix(column, "v") && len(column) == codeLength:
+		// the armor types of the monster layers (HDv, TRv, ...), e.g. "lit, med"
+		for _, value := range strings.FieldsFunc(cell, func(r rune) bool { return r == ',' || r == ' ' || r == '"' }) {
+			if len(value) == codeLength {
+				h.armorTypes[strings.ToUpper(value)] = true
+			}
+		}
+
+		return
+	}
+
+	location, found := columns[table+"."+column]
+	if !found {
+		location, found = columns[column]
+	}
+
+	if found {
+		file := cell
+		if location[1] != "" && filepath.Ext(file) == "" {
+			file += location[1]
+		}
+
+		h.tryWithPrefixes(file, append([]string{location[0]}, prefixes...))
+
+		return
+	}
+
+	if isFileName(cell) {
+		h.tryWithPrefixes(cell, prefixes)
+	}
+}
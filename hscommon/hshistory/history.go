@@ -0,0 +1,275 @@
+// Package hshistory keeps local revisions of project files, so that overwritten versions can be restored
+package hshistory
+
+import (
+	"crypto/sha1" // nolint:gosec // only used to detect identical revisions
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DirName is the name of the directory inside of the project's content directory which contains the history.
+	// It starts with a dot, so it is hidden from the project explorer and never exported.
+	DirName = ".hshistory"
+
+	// DefaultMaxRevisions is the default number of revisions kept per file
+	DefaultMaxRevisions = 50
+	// DefaultMaxAge is the default time after which revisions are removed
+	DefaultMaxAge = 30 * 24 * time.Hour
+
+	indexFileName     = "revisions.json"
+	revisionExtension = ".rev"
+	revisionIDFormat  = "20060102T150405.000000000"
+
+	newFileMode = 0644
+	newDirMode  = 0755
+)
+
+// Revision describes a stored version of a file
+type Revision struct {
+	// ID identifies the revision, it is derived from the time the revision was recorded
+	ID string
+	// Time is the time the revision was recorded, i.e. when this content was replaced
+	Time time.Time
+	// ModTime is the modification time the file had when the revision was recorded
+	ModTime time.Time
+	Size    int
+	// Hash is the SHA1 of the content
+	Hash string
+	// Reason describes why the revision was recorded (e.g. "save" or "restore")
+	Reason string
+}
+
+// RetentionPolicy defines which revisions are kept
+type RetentionPolicy struct {
+	// MaxRevisions is the maximum number of revisions kept per file, 0 means no limit
+	MaxRevisions int
+	// MaxAge is the time after which revisions are removed, 0 means no limit
+	MaxAge time.Duration
+}
+
+// DefaultRetentionPolicy returns the retention policy used when none is set
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{MaxRevisions: DefaultMaxRevisions, MaxAge: DefaultMaxAge}
+}
+
+// Store keeps the revisions of the files inside of a directory. The revisions of content/global/ui/cursor.dc6
+// are stored in content/.hshistory/global/ui/cursor.dc6/, next to an index describing them.
+type Store struct {
+	root    string
+	history string
+
+	mutex     sync.Mutex
+	retention RetentionPolicy
+}
+
+// New creates a store for the files inside of root, the history is kept in root/.hshistory
+func New(root string) *Store {
+	return &Store{
+		root:      root,
+		history:   filepath.Join(root, DirName),
+		retention: DefaultRetentionPolicy(),
+	}
+}
+
+// SetRetentionPolicy sets the retention policy, it is applied the next time a revision is recorded
+func (s *Store) SetRetentionPolicy(policy RetentionPolicy) {
+	s.mutex.Lock()
+	s.retention = policy
+	s.mutex.Unlock()
+}
+
+// Record stores the current content of the file as a new revision, before it is overwritten.
+// Nothing is stored when the file doesn't exist or the content is identical to the latest revision.
+func (s *Store) Record(filePath, reason string) error {
+	data, err := ioutil.ReadFile(filepath.Clean(filePath))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+
+	dir, err := s.fileDir(filePath)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	revisions, err := s.readIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	hash := hashData(data)
+	if len(revisions) > 0 && revisions[0].Hash == hash {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	revision := Revision{
+		ID:      now.Format(revisionIDFormat),
+		Time:    now,
+		ModTime: info.ModTime(),
+		Size:    len(data),
+		Hash:    hash,
+		Reason:  reason,
+	}
+
+	if err := os.MkdirAll(dir, newDirMode); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, revision.ID+revisionExtension), data, newFileMode); err != nil {
+		return err
+	}
+
+	revisions = append([]Revision{revision}, revisions...)
+
+	return s.writeIndex(dir, s.prune(dir, revisions))
+}
+
+// Revisions returns the revisions of a file, newest first
+func (s *Store) Revisions(filePath string) ([]Revision, error) {
+	dir, err := s.fileDir(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.readIndex(dir)
+}
+
+// Read returns the content of a revision
+func (s *Store) Read(filePath, id string) ([]byte, error) {
+	dir, err := s.fileDir(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid revision %s", id)
+	}
+
+	return ioutil.ReadFile(filepath.Join(dir, id+revisionExtension))
+}
+
+// Restore overwrites the file with the content of a revision. The current content is recorded first,
+// so restoring can be undone.
+func (s *Store) Restore(filePath, id string) error {
+	data, err := s.Read(filePath, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Record(filePath, "restore"); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(newFileMode)
+	if info, statErr := os.Stat(filePath); statErr == nil {
+		mode = info.Mode()
+	}
+
+	return ioutil.WriteFile(filePath, data, mode)
+}
+
+// HashFile returns the hash of a file's current content in the same form as Revision.Hash
+func HashFile(filePath string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(filePath))
+	if err != nil {
+		return "", err
+	}
+
+	return hashData(data), nil
+}
+
+// fileDir returns the directory containing the revisions of a file
+func (s *Store) fileDir(filePath string) (string, error) {
+	relPath, err := filepath.Rel(s.root, filePath)
+	if err != nil {
+		return "", err
+	}
+
+	if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not inside of %s", filePath, s.root)
+	}
+
+	return filepath.Join(s.history, relPath), nil
+}
+
+func (s *Store) readIndex(dir string) ([]Revision, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, indexFileName))
+	if os.IsNotExist(err) {
+		return []Revision{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var revisions []Revision
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		return nil, fmt.Errorf("invalid history index in %s: %w", dir, err)
+	}
+
+	sort.SliceStable(revisions, func(i, j int) bool { return revisions[i].Time.After(revisions[j].Time) })
+
+	return revisions, nil
+}
+
+func (s *Store) writeIndex(dir string, revisions []Revision) error {
+	data, err := json.MarshalIndent(revisions, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, indexFileName), data, newFileMode)
+}
+
+// prune removes the revisions (newest first) which are not kept by the retention policy and returns the others.
+// The newest revision is always kept.
+func (s *Store) prune(dir string, revisions []Revision) []Revision {
+	keep := len(revisions)
+
+	if s.retention.MaxRevisions > 0 && keep > s.retention.MaxRevisions {
+		keep = s.retention.MaxRevisions
+	}
+
+	if s.retention.MaxAge > 0 {
+		oldest := time.Now().Add(-s.retention.MaxAge)
+
+		for keep > 1 && revisions[keep-1].Time.Before(oldest) {
+			keep--
+		}
+	}
+
+	for _, revision := range revisions[keep:] {
+		if err := os.Remove(filepath.Join(dir, revision.ID+revisionExtension)); err != nil && !os.IsNotExist(err) {
+			log.Printf("failed to remove revision %s of %s: %s", revision.ID, dir, err)
+		}
+	}
+
+	return revisions[:keep]
+}
+
+func hashData(data []byte) string {
+	hash := sha1.Sum(data) // nolint:gosec // only used to detect identical revisions
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,57 @@
+package hsimage
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+const (
+	opaque = 0xff
+)
+
+// Color returns the color of a palette index, index 0 is transparent. Without a palette,
+// the indices are drawn as shades of gray.
+func Color(palette d2interface.Palette, idx byte) color.RGBA {
+	if idx == 0 {
+		return color.RGBA{}
+	}
+
+	if palette == nil {
+		return color.RGBA{R: idx, G: idx, B: idx, A: opaque}
+	}
+
+	c, err := palette.GetColor(int(idx))
+	if err != nil {
+		return color.RGBA{R: idx, G: idx, B: idx, A: opaque}
+	}
+
+	return color.RGBA{R: c.R(), G: c.G(), B: c.B(), A: opaque}
+}
+
+// RGBA draws the frame with the colors of a palette
+func (f *Frame) RGBA(palette d2interface.Palette) *image.RGBA {
+	result := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			result.SetRGBA(x, y, Color(palette, f.Pixels[x+y*f.Width]))
+		}
+	}
+
+	return result
+}
+
+// DC6Images draws every frame of a DC6 with the colors of a palette
+func DC6Images(dc6 *d2dc6.DC6, palette d2interface.Palette) []*image.RGBA {
+	frames := DC6Frames(dc6)
+	result := make([]*image.RGBA, len(frames))
+
+	for idx, frame := range frames {
+		result[idx] = frame.RGBA(palette)
+	}
+
+	return result
+}
@@ -0,0 +1,227 @@
+package hsimage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+)
+
+const (
+	dc6Version  = 6
+	dc6Flags    = 1
+	dc6Encoding = 0
+
+	dc6TerminationSize = 4
+	dc6TerminatorSize  = 3
+	dc6TerminatorByte  = 0xee
+
+	// the header has 6 fields, each frame header has 8 fields, every field has 4 bytes
+	dc6FieldSize       = 4
+	dc6HeaderSize      = 6 * dc6FieldSize
+	dc6FrameHeaderSize = 8 * dc6FieldSize
+
+	dc6EndOfLine   = 0x80
+	dc6Transparent = 0x80
+	dc6MaxRun      = 0x7f
+)
+
+// Frame is an image whose pixels are palette indices, index 0 is transparent
+type Frame struct {
+	Width, Height int
+	// OffsetX and OffsetY place the frame relative to the origin of the sprite
+	OffsetX, OffsetY int32
+	// Pixels has Width*Height palette indices, row by row from the top
+	Pixels []byte
+}
+
+// NewDC6 builds a DC6 from frames, which are ordered by direction: every frame of the first direction comes first
+func NewDC6(frames []*Frame, directions int) (*d2dc6.DC6, error) {
+	if err := checkFrames(frames, directions); err != nil {
+		return nil, err
+	}
+
+	result := &d2dc6.DC6{
+		Version:            dc6Version,
+		Flags:              dc6Flags,
+		Encoding:           dc6Encoding,
+		Termination:        terminator(dc6TerminationSize),
+		Directions:         uint32(directions),
+		FramesPerDirection: uint32(len(frames) / directions),
+		Frames:             make([]*d2dc6.DC6Frame, len(frames)),
+	}
+
+	for idx, frame := range frames {
+		data := encodeDC6FrameData(frame)
+
+		result.Frames[idx] = &d2dc6.DC6Frame{
+			Width:      uint32(frame.Width),
+			Height:     uint32(frame.Height),
+			OffsetX:    frame.OffsetX,
+			OffsetY:    frame.OffsetY,
+			Length:     uint32(len(data)),
+			FrameData:  data,
+			Terminator: terminator(dc6TerminatorSize),
+		}
+	}
+
+	updateDC6Pointers(result)
+
+	return result, nil
+}
+
+// checkFrames verifies that the frames can be divided into the directions and that they have all of their pixels
+func checkFrames(frames []*Frame, directions int) error {
+	if len(frames) == 0 {
+		return errors.New("a sprite needs at least one frame")
+	}
+
+	if directions < 1 || len(frames)%directions != 0 {
+		return fmt.Errorf("%d frames can't be divided into %d directions", len(frames), directions)
+	}
+
+	for idx, frame := range frames {
+		if frame.Width < 1 || frame.Height < 1 {
+			return fmt.Errorf("frame %d is empty", idx)
+		}
+
+		if len(frame.Pixels) != frame.Width*frame.Height {
+			return fmt.Errorf("frame %d has %d pixels instead of %d", idx, len(frame.Pixels), frame.Width*frame.Height)
+		}
+	}
+
+	return nil
+}
+
+// DC6Frames decodes the frames of a DC6, in the order of the file
+func DC6Frames(dc6 *d2dc6.DC6) []*Frame {
+	result := make([]*Frame, len(dc6.Frames))
+
+	for idx, frame := range dc6.Frames {
+		result[idx] = &Frame{
+			Width:   int(frame.Width),
+			Height:  int(frame.Height),
+			OffsetX: frame.OffsetX,
+			OffsetY: frame.OffsetY,
+			Pixels:  dc6.DecodeFrame(idx),
+		}
+	}
+
+	return result
+}
+
+// MarshalDC6 writes a DC6 in the format of the game. The frame pointers are computed from the frames.
+func MarshalDC6(dc6 *d2dc6.DC6) []byte {
+	updateDC6Pointers(dc6)
+
+	sw := d2datautils.CreateStreamWriter()
+
+	sw.PushInt32(dc6.Version)
+	sw.PushUint32(dc6.Flags)
+	sw.PushUint32(dc6.Encoding)
+	pushBytes(sw, dc6.Termination, dc6TerminationSize)
+	sw.PushUint32(dc6.Directions)
+	sw.PushUint32(dc6.FramesPerDirection)
+
+	for _, pointer := range dc6.FramePointers {
+		sw.PushUint32(pointer)
+	}
+
+	for _, frame := range dc6.Frames {
+		sw.PushUint32(frame.Flipped)
+		sw.PushUint32(frame.Width)
+		sw.PushUint32(frame.Height)
+		sw.PushInt32(frame.OffsetX)
+		sw.PushInt32(frame.OffsetY)
+		sw.PushUint32(frame.Unknown)
+		sw.PushUint32(frame.NextBlock)
+		sw.PushUint32(frame.Length)
+		pushBytes(sw, frame.FrameData, len(frame.FrameData))
+		pushBytes(sw, frame.Terminator, dc6TerminatorSize)
+	}
+
+	return sw.GetBytes()
+}
+
+// updateDC6Pointers sets the frame pointers and the lengths of the frames, the frames are stored one after another
+func updateDC6Pointers(dc6 *d2dc6.DC6) {
+	dc6.FramePointers = make([]uint32, len(dc6.Frames))
+	offset := uint32(dc6HeaderSize + len(dc6.Frames)*dc6FieldSize)
+
+	for idx, frame := range dc6.Frames {
+		frame.Length = uint32(len(frame.FrameData))
+		dc6.FramePointers[idx] = offset
+		offset += dc6FrameHeaderSize + frame.Length + dc6TerminatorSize
+		frame.NextBlock = offset
+	}
+}
+
+// encodeDC6FrameData run length encodes the rows of a frame, starting with the bottom row.
+// Transparent pixels at the end of a row are left out, every row ends with an end of line marker.
+func encodeDC6FrameData(frame *Frame) []byte {
+	result := make([]byte, 0, len(frame.Pixels))
+
+	for y := frame.Height - 1; y >= 0; y-- {
+		row := frame.Pixels[y*frame.Width : (y+1)*frame.Width]
+		x := 0
+
+		for x < len(row) {
+			transparent := 0
+			for x+transparent < len(row) && row[x+transparent] == 0 {
+				transparent++
+			}
+
+			if x+transparent == len(row) {
+				break
+			}
+
+			x += transparent
+
+			for ; transparent > 0; transparent -= dc6MaxRun {
+				result = append(result, dc6Transparent|byte(minInt(transparent, dc6MaxRun)))
+			}
+
+			opaque := 0
+			for x+opaque < len(row) && row[x+opaque] != 0 && opaque < dc6MaxRun {
+				opaque++
+			}
+
+			result = append(result, byte(opaque))
+			result = append(result, row[x:x+opaque]...)
+			x += opaque
+		}
+
+		result = append(result, dc6EndOfLine)
+	}
+
+	return result
+}
+
+func terminator(size int) []byte {
+	result := make([]byte, size)
+	for idx := range result {
+		result[idx] = dc6TerminatorByte
+	}
+
+	return result
+}
+
+// pushBytes writes exactly size bytes, missing bytes are written as terminator bytes
+func pushBytes(sw *d2datautils.StreamWriter, data []byte, size int) {
+	for idx := 0; idx < size; idx++ {
+		if idx < len(data) {
+			sw.PushByte(data[idx])
+		} else {
+			sw.PushByte(dc6TerminatorByte)
+		}
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
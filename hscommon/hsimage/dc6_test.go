@@ -0,0 +1,242 @@
+package hsimage
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+// testColors are the colors of the test palette, from index 1. The other indices are black.
+func testColors() []color.NRGBA {
+	return []color.NRGBA{
+		{R: 0, G: 0, B: 0, A: 0xff},
+		{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+		{R: 0xff, G: 0, B: 0, A: 0xff},
+		{R: 0, G: 0xff, B: 0, A: 0xff},
+		{R: 0, G: 0, B: 0xff, A: 0xff},
+	}
+}
+
+func testPalette(t *testing.T) d2interface.Palette {
+	t.Helper()
+
+	data := make([]byte, numColors*3)
+
+	// .dat palettes are stored as blue, green, red
+	for idx, c := range testColors() {
+		data[(idx+1)*3], data[(idx+1)*3+1], data[(idx+1)*3+2] = c.B, c.G, c.R
+	}
+
+	palette, err := d2dat.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return palette
+}
+
+// randomFrame returns a frame whose pixels are runs of transparent and opaque pixels of random lengths,
+// up to maxRun pixels long
+func randomFrame(random *rand.Rand, width, height, maxRun int) *Frame {
+	const maxOffset = 200
+
+	frame := &Frame{
+		Width:   width,
+		Height:  height,
+		OffsetX: int32(random.Intn(2*maxOffset) - maxOffset),
+		OffsetY: int32(random.Intn(2*maxOffset) - maxOffset),
+		Pixels:  make([]byte, width*height),
+	}
+
+	for idx := 0; idx < len(frame.Pixels); {
+		run := 1 + random.Intn(maxRun)
+		transparent := random.Intn(2) == 0
+
+		for ; run > 0 && idx < len(frame.Pixels); run-- {
+			if !transparent {
+				frame.Pixels[idx] = byte(1 + random.Intn(numColors-1))
+			}
+
+			idx++
+		}
+	}
+
+	return frame
+}
+
+func TestDC6RoundTrip(t *testing.T) {
+	tests := []struct {
+		name               string
+		directions         int
+		framesPerDirection int
+		maxSize            int
+		maxRun             int
+	}{
+		{name: "single pixel", directions: 1, framesPerDirection: 1, maxSize: 1, maxRun: 1},
+		{name: "short runs", directions: 1, framesPerDirection: 4, maxSize: 20, maxRun: 4},
+		{name: "several directions", directions: 8, framesPerDirection: 3, maxSize: 40, maxRun: 10},
+		{name: "runs longer than 127 pixels", directions: 2, framesPerDirection: 2, maxSize: 400, maxRun: 600},
+	}
+
+	random := rand.New(rand.NewSource(1))
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			frames := make([]*Frame, test.directions*test.framesPerDirection)
+			for idx := range frames {
+				frames[idx] = randomFrame(random, 1+random.Intn(test.maxSize), 1+random.Intn(test.maxSize), test.maxRun)
+			}
+
+			dc6, err := NewDC6(frames, test.directions)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := d2dc6.Load(MarshalDC6(dc6))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if int(decoded.Directions) != test.directions || int(decoded.FramesPerDirection) != test.framesPerDirection {
+				t.Fatalf("decoded %d directions of %d frames, expected %d of %d",
+					decoded.Directions, decoded.FramesPerDirection, test.directions, test.framesPerDirection)
+			}
+
+			for idx, frame := range DC6Frames(decoded) {
+				expected := frames[idx]
+
+				if frame.Width != expected.Width || frame.Height != expected.Height {
+					t.Errorf("frame %d is %dx%d, expected %dx%d", idx, frame.Width, frame.Height, expected.Width, expected.Height)
+				}
+
+				if frame.OffsetX != expected.OffsetX || frame.OffsetY != expected.OffsetY {
+					t.Errorf("frame %d has the offset %d,%d, expected %d,%d",
+						idx, frame.OffsetX, frame.OffsetY, expected.OffsetX, expected.OffsetY)
+				}
+
+				if !bytes.Equal(frame.Pixels, expected.Pixels) {
+					t.Errorf("the pixels of frame %d differ", idx)
+				}
+			}
+		})
+	}
+}
+
+func TestNewDC6RejectsInvalidFrames(t *testing.T) {
+	frame := &Frame{Width: 2, Height: 2, Pixels: make([]byte, 4)}
+
+	tests := []struct {
+		name       string
+		frames     []*Frame
+		directions int
+	}{
+		{name: "no frames", directions: 1},
+		{name: "frames not divisible by the directions", frames: []*Frame{frame, frame, frame}, directions: 2},
+		{name: "empty frame", frames: []*Frame{{}}, directions: 1},
+		{name: "missing pixels", frames: []*Frame{{Width: 2, Height: 2, Pixels: make([]byte, 3)}}, directions: 1},
+	}
+
+	for _, test := range tests {
+		if _, err := NewDC6(test.frames, test.directions); err == nil {
+			t.Errorf("%s: the frames were accepted", test.name)
+		}
+	}
+}
+
+// testImage returns an image of the test palette's colors, index 0 is transparent
+func testImage(width, height int, indices func(x, y int) int) *image.NRGBA {
+	colors := testColors()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if idx := indices(x, y); idx > 0 {
+				img.SetNRGBA(x, y, colors[idx-1])
+			}
+		}
+	}
+
+	return img
+}
+
+func TestQuantizeFrames(t *testing.T) {
+	const width, height = 7, 5
+
+	palette := testPalette(t)
+	indices := func(x, y int) int { return (x + y*width) % (len(testColors()) + 1) }
+
+	images := []image.Image{testImage(width, height, indices), testImage(width, height, indices)}
+	options := ImportOptions{
+		Directions: 1,
+		OffsetX:    -3,
+		OffsetY:    -9,
+		Offsets:    []image.Point{{X: 4, Y: -20}},
+	}
+
+	for _, dither := range []bool{false, true} {
+		options.Dither = dither
+
+		frames := QuantizeFrames(images, palette, options)
+
+		if frames[0].OffsetX != 4 || frames[0].OffsetY != -20 || frames[1].OffsetX != -3 || frames[1].OffsetY != -9 {
+			t.Errorf("dither %v: wrong offsets %d,%d and %d,%d", dither,
+				frames[0].OffsetX, frames[0].OffsetY, frames[1].OffsetX, frames[1].OffsetY)
+		}
+
+		// the colors of the palette are kept exactly, there is no error to spread
+		for _, frame := range frames {
+			for idx, pixel := range frame.Pixels {
+				if expected := indices(idx%width, idx/width); int(pixel) != expected {
+					t.Fatalf("dither %v: pixel %d is %d, expected %d", dither, idx, pixel, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestQuantizeDither(t *testing.T) {
+	const size = 16
+
+	palette := testPalette(t)
+	gray := color.NRGBA{R: 0x70, G: 0x70, B: 0x70, A: 0xff}
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.SetNRGBA(x, y, gray)
+		}
+	}
+
+	// gray is nearest to black (index 1), dithering mixes black and white (index 2) pixels
+	plain := QuantizeFrames([]image.Image{img}, palette, ImportOptions{Directions: 1})[0]
+	dithered := QuantizeFrames([]image.Image{img}, palette, ImportOptions{Directions: 1, Dither: true})[0]
+
+	white := 0
+
+	for idx := range plain.Pixels {
+		if plain.Pixels[idx] != 1 {
+			t.Fatalf("pixel %d is %d without dithering, expected 1", idx, plain.Pixels[idx])
+		}
+
+		switch dithered.Pixels[idx] {
+		case 1:
+		case 2:
+			white++
+		default:
+			t.Fatalf("pixel %d is %d with dithering, expected 1 or 2", idx, dithered.Pixels[idx])
+		}
+	}
+
+	// 0x70 is a bit less than half of the way to white
+	if white < size*size/3 || white > size*size*2/3 {
+		t.Errorf("%d of %d pixels are white", white, size*size)
+	}
+}
@@ -0,0 +1,153 @@
+package hsimage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+)
+
+// The frames of a DC6 form a grid, with a row per direction and a column per frame of the animation.
+// Every direction has the same number of frames, so frames are inserted, removed and moved in every direction
+// at once. The functions return a new DC6 and leave the given one unchanged, except for the frames they share.
+
+// BlankDC6Frame returns a frame with one transparent pixel
+func BlankDC6Frame() *d2dc6.DC6Frame {
+	data := encodeDC6FrameData(&Frame{Width: 1, Height: 1, Pixels: []byte{0}})
+
+	return &d2dc6.DC6Frame{
+		Width:      1,
+		Height:     1,
+		Length:     uint32(len(data)),
+		FrameData:  data,
+		Terminator: terminator(dc6TerminatorSize),
+	}
+}
+
+// InsertDC6Frame inserts a blank frame at the column frame of every direction
+func InsertDC6Frame(dc6 *d2dc6.DC6, frame int) (*d2dc6.DC6, error) {
+	if frame < 0 || frame > int(dc6.FramesPerDirection) {
+		return nil, fmt.Errorf("frame %d is out of range", frame)
+	}
+
+	return rebuildDC6(dc6, int(dc6.FramesPerDirection)+1, func(direction, column int) *d2dc6.DC6Frame {
+		switch {
+		case column < frame:
+			return dc6.Frames[direction*int(dc6.FramesPerDirection)+column]
+		case column == frame:
+			return BlankDC6Frame()
+		default:
+			return dc6.Frames[direction*int(dc6.FramesPerDirection)+column-1]
+		}
+	}), nil
+}
+
+// DuplicateDC6Frame inserts a copy of the column frame after it, in every direction
+func DuplicateDC6Frame(dc6 *d2dc6.DC6, frame int) (*d2dc6.DC6, error) {
+	if frame < 0 || frame >= int(dc6.FramesPerDirection) {
+		return nil, fmt.Errorf("frame %d is out of range", frame)
+	}
+
+	return rebuildDC6(dc6, int(dc6.FramesPerDirection)+1, func(direction, column int) *d2dc6.DC6Frame {
+		switch {
+		case column <= frame:
+			return dc6.Frames[direction*int(dc6.FramesPerDirection)+column]
+		case column == frame+1:
+			return cloneDC6Frame(dc6.Frames[direction*int(dc6.FramesPerDirection)+frame])
+		default:
+			return dc6.Frames[direction*int(dc6.FramesPerDirection)+column-1]
+		}
+	}), nil
+}
+
+// DeleteDC6Frame removes the column frame from every direction
+func DeleteDC6Frame(dc6 *d2dc6.DC6, frame int) (*d2dc6.DC6, error) {
+	if frame < 0 || frame >= int(dc6.FramesPerDirection) {
+		return nil, fmt.Errorf("frame %d is out of range", frame)
+	}
+
+	if dc6.FramesPerDirection < 2 { // nolint:gomnd // the last frame can't be removed
+		return nil, errors.New("every direction needs at least one frame")
+	}
+
+	return rebuildDC6(dc6, int(dc6.FramesPerDirection)-1, func(direction, column int) *d2dc6.DC6Frame {
+		if column >= frame {
+			column++
+		}
+
+		return dc6.Frames[direction*int(dc6.FramesPerDirection)+column]
+	}), nil
+}
+
+// MoveDC6Frame moves the column from to the column to, in every direction
+func MoveDC6Frame(dc6 *d2dc6.DC6, from, to int) (*d2dc6.DC6, error) {
+	count := int(dc6.FramesPerDirection)
+	if from < 0 || from >= count || to < 0 || to >= count {
+		return nil, fmt.Errorf("can't move frame %d to %d", from, to)
+	}
+
+	// the order of the columns after the move
+	order := make([]int, 0, count)
+
+	for column := 0; column < count; column++ {
+		if column != from {
+			order = append(order, column)
+		}
+	}
+
+	order = append(order[:to], append([]int{from}, order[to:]...)...)
+
+	return rebuildDC6(dc6, count, func(direction, column int) *d2dc6.DC6Frame {
+		return dc6.Frames[direction*count+order[column]]
+	}), nil
+}
+
+// ResizeDC6 divides the frames into the given number of directions and frames per direction. The frames keep
+// their order, frames beyond the new size are removed and missing frames are added as blank frames.
+func ResizeDC6(dc6 *d2dc6.DC6, directions, framesPerDirection int) (*d2dc6.DC6, error) {
+	if directions < 1 || framesPerDirection < 1 {
+		return nil, errors.New("a DC6 needs at least one direction and one frame per direction")
+	}
+
+	result := *dc6
+	result.Directions = uint32(directions)
+	result.FramesPerDirection = uint32(framesPerDirection)
+	result.Frames = make([]*d2dc6.DC6Frame, directions*framesPerDirection)
+
+	for idx := range result.Frames {
+		if idx < len(dc6.Frames) {
+			result.Frames[idx] = dc6.Frames[idx]
+		} else {
+			result.Frames[idx] = BlankDC6Frame()
+		}
+	}
+
+	updateDC6Pointers(&result)
+
+	return &result, nil
+}
+
+// rebuildDC6 creates a DC6 with the same header, whose frames are chosen by frameAt
+func rebuildDC6(dc6 *d2dc6.DC6, framesPerDirection int, frameAt func(direction, column int) *d2dc6.DC6Frame) *d2dc6.DC6 {
+	result := *dc6
+	result.FramesPerDirection = uint32(framesPerDirection)
+	result.Frames = make([]*d2dc6.DC6Frame, 0, int(dc6.Directions)*framesPerDirection)
+
+	for direction := 0; direction < int(dc6.Directions); direction++ {
+		for column := 0; column < framesPerDirection; column++ {
+			result.Frames = append(result.Frames, frameAt(direction, column))
+		}
+	}
+
+	updateDC6Pointers(&result)
+
+	return &result
+}
+
+func cloneDC6Frame(frame *d2dc6.DC6Frame) *d2dc6.DC6Frame {
+	clone := *frame
+	clone.FrameData = append([]byte(nil), frame.FrameData...)
+	clone.Terminator = append([]byte(nil), frame.Terminator...)
+
+	return &clone
+}
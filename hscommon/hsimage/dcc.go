@@ -0,0 +1,543 @@
+package hsimage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"math/bits"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+// A DCC direction is a bitstream. A grid of cells of 4x4 pixels is laid over the box around every frame of the
+// direction and the frames are split along the lines of that grid, so the first and the last cell of a row or a
+// column of a frame may be smaller or larger. A cell has at most 4 colors, or repeats the cell of the previous
+// frame at the same place of the grid. The colors of a cell are written as distances between the palette
+// entries (the colors used by the direction), then every pixel is written as an index into the colors of its cell.
+
+const (
+	dccSignature  = 0x74
+	dccVersion    = 6
+	dccHeaderSize = 15
+	dccOffsetSize = 4
+
+	dccMaxDirections = 0xff
+	dccCellSize      = 4
+	dccCellColors    = 4
+
+	dccEqualCellsFlag       = 0x2
+	dccCompressionFlagsBits = 2
+	dccFieldSizeBits        = 4
+	dccStreamSizeBits       = 20
+	dccPixelMaskBits        = 4
+	dccPixelMaskAll         = 0x0f
+	dccDisplacementBits     = 4
+	dccMaxDisplacement      = 0x0f
+	dccOutSizeBits          = 32
+
+	byteBits = 8
+)
+
+// dccFieldSizes returns the bit sizes the fields of the frame headers can have, a direction stores the index
+// of the size of each field
+func dccFieldSizes() []int {
+	return []int{0, 1, 2, 4, 6, 8, 10, 12, 14, 16, 20, 24, 26, 28, 30, 32}
+}
+
+// EncodeDCC writes frames as a DCC, the frames are ordered by direction like for NewDC6. Cells with more than
+// 4 colors keep the transparent color and their most frequent colors, the other pixels get the nearest of them.
+// The colors are compared with the palette, without a palette the nearest palette index is taken.
+func EncodeDCC(frames []*Frame, directions int, palette d2interface.Palette) ([]byte, error) {
+	if err := checkFrames(frames, directions); err != nil {
+		return nil, err
+	}
+
+	if directions > dccMaxDirections {
+		return nil, fmt.Errorf("a DCC can't have more than %d directions", dccMaxDirections)
+	}
+
+	framesPerDirection := len(frames) / directions
+	distance := colorDistance(palette)
+	encoded := make([][]byte, directions)
+	total := 0
+
+	for direction := range encoded {
+		data, err := encodeDCCDirection(frames[direction*framesPerDirection:(direction+1)*framesPerDirection], distance)
+		if err != nil {
+			return nil, fmt.Errorf("direction %d: %w", direction, err)
+		}
+
+		encoded[direction] = data
+		total += len(data)
+	}
+
+	sw := d2datautils.CreateStreamWriter()
+
+	sw.PushByte(dccSignature)
+	sw.PushByte(dccVersion)
+	sw.PushByte(byte(directions))
+	sw.PushUint32(uint32(framesPerDirection))
+	sw.PushUint32(1)
+	sw.PushUint32(uint32(total))
+
+	offset := dccHeaderSize + directions*dccOffsetSize
+
+	for _, data := range encoded {
+		sw.PushUint32(uint32(offset))
+		offset += len(data)
+	}
+
+	for _, data := range encoded {
+		for _, b := range data {
+			sw.PushByte(b)
+		}
+	}
+
+	return sw.GetBytes(), nil
+}
+
+// ImportDCC builds a DCC from images, which are quantized to the palette
+func ImportDCC(images []image.Image, palette d2interface.Palette, options ImportOptions) ([]byte, error) {
+	if err := checkImport(images, palette, options); err != nil {
+		return nil, err
+	}
+
+	return EncodeDCC(QuantizeFrames(images, palette, options), options.Directions, palette)
+}
+
+// dccCell is a cell of a frame, placed relative to the box of the direction
+type dccCell struct {
+	rect   image.Rectangle
+	pixels []byte
+}
+
+// dccBufferCell is a cell of the grid of a direction, it remembers where it was drawn the last time
+type dccBufferCell struct {
+	visited bool
+	last    image.Rectangle
+}
+
+// dccDirectionEncoder follows the state of the decoder, to find out which cells can be repeated
+type dccDirectionEncoder struct {
+	width   int
+	columns int
+	canvas  []byte
+	buffer  []dccBufferCell
+	entries [numColors]byte
+
+	equalCells, pixelMasks, displacements, pixelCodes bitWriter
+}
+
+func encodeDCCDirection(frames []*Frame, distance func(a, b byte) int) ([]byte, error) {
+	box := image.Rectangle{}
+	for _, frame := range frames {
+		box = box.Union(frameBounds(frame))
+	}
+
+	cells := make([][]dccCell, len(frames))
+	used := [numColors]bool{true}
+
+	for idx, frame := range frames {
+		cells[idx] = splitDCCFrame(frame, frameBounds(frame).Sub(box.Min), distance)
+
+		for _, cell := range cells[idx] {
+			for _, pixel := range cell.pixels {
+				used[pixel] = true
+			}
+		}
+	}
+
+	e := &dccDirectionEncoder{
+		width:   box.Dx(),
+		columns: 1 + (box.Dx()-1)/dccCellSize,
+		canvas:  make([]byte, box.Dx()*box.Dy()),
+	}
+
+	e.buffer = make([]dccBufferCell, e.columns*(1+(box.Dy()-1)/dccCellSize))
+
+	for entry, color := 0, 0; color < numColors; color++ {
+		if used[color] {
+			e.entries[color] = byte(entry)
+			entry++
+		}
+	}
+
+	for _, frameCells := range cells {
+		for _, cell := range frameCells {
+			e.encodeCell(cell)
+		}
+	}
+
+	return e.marshal(frames, used)
+}
+
+// splitDCCFrame splits a frame, placed at rect in its direction, into cells and reduces their colors
+func splitDCCFrame(frame *Frame, rect image.Rectangle, distance func(a, b byte) int) []dccCell {
+	widths := dccCellSizes(rect.Min.X, rect.Dx())
+	heights := dccCellSizes(rect.Min.Y, rect.Dy())
+	result := make([]dccCell, 0, len(widths)*len(heights))
+
+	for y, cellY := 0, 0; y < len(heights); cellY, y = cellY+heights[y], y+1 {
+		for x, cellX := 0, 0; x < len(widths); cellX, x = cellX+widths[x], x+1 {
+			cell := dccCell{
+				rect:   image.Rect(cellX, cellY, cellX+widths[x], cellY+heights[y]).Add(rect.Min),
+				pixels: make([]byte, 0, widths[x]*heights[y]),
+			}
+
+			for row := cellY; row < cellY+heights[y]; row++ {
+				cell.pixels = append(cell.pixels, frame.Pixels[row*frame.Width+cellX:row*frame.Width+cellX+widths[x]]...)
+			}
+
+			reduceDCCCell(cell.pixels, distance)
+			result = append(result, cell)
+		}
+	}
+
+	return result
+}
+
+// dccCellSizes returns the sizes of the cells along one side of a frame, which starts at offset in its direction.
+// The first cell ends on the grid of the direction, a last cell of a single pixel is added to the cell before.
+func dccCellSizes(offset, length int) []int {
+	first := dccCellSize - offset%dccCellSize
+	if length-first <= 1 {
+		return []int{length}
+	}
+
+	rest := length - first - 1
+	count := 2 + rest/dccCellSize
+
+	if rest%dccCellSize == 0 {
+		count--
+	}
+
+	result := make([]int, count)
+	result[0] = first
+
+	for idx := 1; idx < count-1; idx++ {
+		result[idx] = dccCellSize
+	}
+
+	result[count-1] = length - first - dccCellSize*(count-2)
+
+	return result
+}
+
+// reduceDCCCell leaves at most 4 colors in a cell: the transparent color and the most frequent opaque colors
+// are kept, the other pixels get the nearest kept color
+func reduceDCCCell(pixels []byte, distance func(a, b byte) int) {
+	var counts [numColors]int
+
+	for _, pixel := range pixels {
+		counts[pixel]++
+	}
+
+	colors := make([]byte, 0, len(pixels))
+
+	for color := 1; color < numColors; color++ {
+		if counts[color] > 0 {
+			colors = append(colors, byte(color))
+		}
+	}
+
+	keep := dccCellColors
+	if counts[0] > 0 {
+		keep--
+	}
+
+	if len(colors) <= keep {
+		return
+	}
+
+	sort.SliceStable(colors, func(i, j int) bool { return counts[colors[i]] > counts[colors[j]] })
+	colors = colors[:keep]
+
+	for idx, pixel := range pixels {
+		if pixel == 0 || containsByte(colors, pixel) {
+			continue
+		}
+
+		nearest := colors[0]
+		for _, color := range colors[1:] {
+			if distance(pixel, color) < distance(pixel, nearest) {
+				nearest = color
+			}
+		}
+
+		pixels[idx] = nearest
+	}
+}
+
+// encodeCell writes a cell, or marks it as equal to the cell of the previous frame at the same place
+func (e *dccDirectionEncoder) encodeCell(cell dccCell) {
+	buffer := &e.buffer[cell.rect.Min.X/dccCellSize+cell.rect.Min.Y/dccCellSize*e.columns]
+
+	if buffer.visited {
+		if e.isEqualCell(buffer.last, cell) {
+			e.equalCells.push(1, 1)
+
+			// a repeated cell is already on the canvas, a cell whose size changed is cleared by the decoder
+			e.fillCanvas(cell.rect, cell.pixels)
+
+			buffer.last = cell.rect
+
+			return
+		}
+
+		e.equalCells.push(0, 1)
+		e.pixelMasks.push(dccPixelMaskAll, dccPixelMaskBits)
+	}
+
+	e.encodePixels(cell)
+
+	buffer.visited = true
+	buffer.last = cell.rect
+}
+
+// isEqualCell returns whether the decoder gets the pixels of a cell without reading them. A cell of the same
+// size at the same place is taken from the last frame, a cell of another size is transparent.
+func (e *dccDirectionEncoder) isEqualCell(last image.Rectangle, cell dccCell) bool {
+	if last.Size() != cell.rect.Size() {
+		for _, pixel := range cell.pixels {
+			if pixel != 0 {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if last != cell.rect {
+		return false
+	}
+
+	for y := 0; y < cell.rect.Dy(); y++ {
+		for x := 0; x < cell.rect.Dx(); x++ {
+			if e.canvas[(cell.rect.Min.Y+y)*e.width+cell.rect.Min.X+x] != cell.pixels[y*cell.rect.Dx()+x] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// encodePixels writes the colors of a cell in ascending order, then the index of every pixel's color. The colors
+// are read into the cell in the opposite order, the transparent color follows the opaque colors.
+func (e *dccDirectionEncoder) encodePixels(cell dccCell) {
+	var present [numColors]bool
+
+	for _, pixel := range cell.pixels {
+		present[e.entries[pixel]] = true
+	}
+
+	values := make([]byte, 0, dccCellColors)
+
+	for entry := numColors - 1; entry > 0; entry-- {
+		if present[entry] {
+			values = append(values, byte(entry))
+		}
+	}
+
+	last := 0
+	for idx := len(values) - 1; idx >= 0; idx-- {
+		e.pushDisplacement(int(values[idx]) - last)
+		last = int(values[idx])
+	}
+
+	if len(values) < dccCellColors {
+		// a displacement of 0 ends the colors of the cell
+		e.pushDisplacement(0)
+	}
+
+	e.fillCanvas(cell.rect, cell.pixels)
+
+	if len(values) == 0 {
+		// the cell is filled with the transparent color
+		return
+	}
+
+	codeBits := 2
+	if len(values) == 1 {
+		codeBits = 1
+	}
+
+	for _, pixel := range cell.pixels {
+		code := len(values)
+
+		for idx, value := range values {
+			if value == e.entries[pixel] {
+				code = idx
+				break
+			}
+		}
+
+		e.pixelCodes.push(uint32(code), codeBits)
+	}
+}
+
+func (e *dccDirectionEncoder) pushDisplacement(displacement int) {
+	for ; displacement >= dccMaxDisplacement; displacement -= dccMaxDisplacement {
+		e.displacements.push(dccMaxDisplacement, dccDisplacementBits)
+	}
+
+	e.displacements.push(uint32(displacement), dccDisplacementBits)
+}
+
+// fillCanvas draws the pixels of a cell on the canvas
+func (e *dccDirectionEncoder) fillCanvas(rect image.Rectangle, pixels []byte) {
+	for y := 0; y < rect.Dy(); y++ {
+		copy(e.canvas[(rect.Min.Y+y)*e.width+rect.Min.X:], pixels[y*rect.Dx():(y+1)*rect.Dx()])
+	}
+}
+
+// marshal writes the header of the direction, followed by the bitstreams
+func (e *dccDirectionEncoder) marshal(frames []*Frame, used [numColors]bool) ([]byte, error) {
+	if e.equalCells.bits >= 1<<dccStreamSizeBits || e.pixelMasks.bits >= 1<<dccStreamSizeBits {
+		return nil, errors.New("the frames are too large for a DCC direction")
+	}
+
+	var maxSize, minOffset, maxOffset int
+
+	for _, frame := range frames {
+		rect := frameBounds(frame)
+		// the frame headers place the frames by their bottom row
+		maxSize = maxInt(maxSize, maxInt(rect.Dx(), rect.Dy()))
+		minOffset = minInt(minOffset, minInt(rect.Min.X, rect.Max.Y-1))
+		maxOffset = maxInt(maxOffset, maxInt(rect.Min.X, rect.Max.Y-1))
+	}
+
+	sizeCode, sizeBits := dccFieldSize(bits.Len(uint(maxSize)))
+	offsetCode, offsetBits := dccFieldSize(maxInt(signedBits(minOffset), signedBits(maxOffset)))
+
+	flags := 0
+	if e.equalCells.bits > 0 {
+		flags |= dccEqualCellsFlag
+	}
+
+	w := &bitWriter{}
+	w.push(0, dccOutSizeBits)
+	w.push(uint32(flags), dccCompressionFlagsBits)
+
+	// variable0, width, height, x offset, y offset, optional data and coded bytes
+	for _, code := range []int{0, sizeCode, sizeCode, offsetCode, offsetCode, 0, 0} {
+		w.push(uint32(code), dccFieldSizeBits)
+	}
+
+	for _, frame := range frames {
+		rect := frameBounds(frame)
+
+		w.push(uint32(rect.Dx()), sizeBits)
+		w.push(uint32(rect.Dy()), sizeBits)
+		w.push(uint32(rect.Min.X), offsetBits)
+		w.push(uint32(rect.Max.Y-1), offsetBits)
+		w.push(0, 1) // the frame is stored top down
+	}
+
+	if flags&dccEqualCellsFlag != 0 {
+		w.push(uint32(e.equalCells.bits), dccStreamSizeBits)
+	}
+
+	w.push(uint32(e.pixelMasks.bits), dccStreamSizeBits)
+
+	for _, isUsed := range used {
+		if isUsed {
+			w.push(1, 1)
+		} else {
+			w.push(0, 1)
+		}
+	}
+
+	w.pushWriter(&e.equalCells)
+	w.pushWriter(&e.pixelMasks)
+	w.pushWriter(&e.displacements)
+	w.pushWriter(&e.pixelCodes)
+
+	binary.LittleEndian.PutUint32(w.data, uint32(len(w.data)))
+
+	return w.data, nil
+}
+
+// dccFieldSize returns the index and the size of the smallest field size with at least the given number of bits
+func dccFieldSize(minBits int) (code, size int) {
+	for code, size = range dccFieldSizes() {
+		if size >= minBits {
+			break
+		}
+	}
+
+	return code, size
+}
+
+// signedBits returns the number of bits of a value in two's complement
+func signedBits(value int) int {
+	switch {
+	case value > 0:
+		return bits.Len(uint(value)) + 1
+	case value < 0:
+		return bits.Len(uint(^value)) + 1
+	default:
+		return 0
+	}
+}
+
+// colorDistance returns a function comparing palette indices by their colors
+func colorDistance(palette d2interface.Palette) func(a, b byte) int {
+	if palette == nil {
+		return func(a, b byte) int {
+			d := int(a) - int(b)
+			return d * d
+		}
+	}
+
+	colors := NewQuantizer(palette).colors
+
+	return func(a, b byte) int {
+		dr, dg, db := colors[a][0]-colors[b][0], colors[a][1]-colors[b][1], colors[a][2]-colors[b][2]
+		return dr*dr + dg*dg + db*db
+	}
+}
+
+func containsByte(values []byte, value byte) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// bitWriter writes values bit by bit, starting with the lowest bit, the way d2datautils.BitMuncher reads them
+type bitWriter struct {
+	data []byte
+	bits int
+}
+
+func (w *bitWriter) push(value uint32, count int) {
+	for idx := 0; idx < count; idx++ {
+		if w.bits%byteBits == 0 {
+			w.data = append(w.data, 0)
+		}
+
+		w.data[w.bits/byteBits] |= byte(value>>uint(idx)&1) << uint(w.bits%byteBits)
+		w.bits++
+	}
+}
+
+func (w *bitWriter) pushWriter(other *bitWriter) {
+	for idx := 0; idx < other.bits; idx++ {
+		w.push(uint32(other.data[idx/byteBits]>>uint(idx%byteBits)), 1)
+	}
+}
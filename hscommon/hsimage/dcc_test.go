@@ -0,0 +1,216 @@
+package hsimage
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dcc"
+)
+
+// randomDCCFrames returns the frames of the directions, every third frame repeats the frame before it
+// with a few changed pixels, so that the encoder can reuse its cells. A quarter of the pixels is transparent
+// and the opaque pixels get one of the colors.
+func randomDCCFrames(random *rand.Rand, directions, framesPerDirection int, colors []byte) []*Frame {
+	const maxSize, maxOffsetX, minOffsetY, maxOffsetY, changes = 40, 30, -80, 10, 3
+
+	result := make([]*Frame, 0, directions*framesPerDirection)
+
+	for idx := 0; idx < directions*framesPerDirection; idx++ {
+		if idx%3 == 2 {
+			previous := result[idx-1]
+			frame := &Frame{
+				Width:   previous.Width,
+				Height:  previous.Height,
+				OffsetX: previous.OffsetX,
+				OffsetY: previous.OffsetY,
+				Pixels:  append([]byte{}, previous.Pixels...),
+			}
+
+			for change := 0; change < changes; change++ {
+				frame.Pixels[random.Intn(len(frame.Pixels))] = colors[random.Intn(len(colors))]
+			}
+
+			result = append(result, frame)
+
+			continue
+		}
+
+		frame := &Frame{
+			Width:   1 + random.Intn(maxSize),
+			Height:  1 + random.Intn(maxSize),
+			OffsetX: int32(random.Intn(2*maxOffsetX) - maxOffsetX),
+			OffsetY: int32(minOffsetY + random.Intn(maxOffsetY-minOffsetY)),
+		}
+
+		frame.Pixels = make([]byte, frame.Width*frame.Height)
+
+		// some frames are entirely transparent
+		if idx%7 == 6 {
+			result = append(result, frame)
+
+			continue
+		}
+
+		for pixel := range frame.Pixels {
+			if random.Intn(4) > 0 {
+				frame.Pixels[pixel] = colors[random.Intn(len(colors))]
+			}
+		}
+
+		result = append(result, frame)
+	}
+
+	return result
+}
+
+// decodeDCCFrames encodes the frames and decodes them with the DCC loader of the engine, the decoded frames
+// have the sizes and offsets of the original frames
+func decodeDCCFrames(t *testing.T, frames []*Frame, directions int) []*Frame {
+	t.Helper()
+
+	data, err := EncodeDCC(frames, directions, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dcc, err := d2dcc.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	framesPerDirection := len(frames) / directions
+
+	if dcc.NumberOfDirections != directions || dcc.FramesPerDirection != framesPerDirection {
+		t.Fatalf("decoded %d directions of %d frames, expected %d of %d",
+			dcc.NumberOfDirections, dcc.FramesPerDirection, directions, framesPerDirection)
+	}
+
+	result := make([]*Frame, len(frames))
+
+	for idx, expected := range frames {
+		direction := dcc.Directions[idx/framesPerDirection]
+		decoded := direction.Frames[idx%framesPerDirection]
+
+		if decoded.Box.Left != int(expected.OffsetX) || decoded.Box.Top != int(expected.OffsetY) ||
+			decoded.Box.Width != expected.Width || decoded.Box.Height != expected.Height {
+			t.Fatalf("frame %d was decoded at %+v, expected %dx%d at %d,%d", idx, decoded.Box,
+				expected.Width, expected.Height, expected.OffsetX, expected.OffsetY)
+		}
+
+		// the pixels of a decoded frame are placed in the box of its direction
+		frame := &Frame{Width: expected.Width, Height: expected.Height, Pixels: make([]byte, len(expected.Pixels))}
+
+		for y := 0; y < frame.Height; y++ {
+			for x := 0; x < frame.Width; x++ {
+				position := (y+decoded.Box.Top-direction.Box.Top)*direction.Box.Width + x + decoded.Box.Left - direction.Box.Left
+				frame.Pixels[y*frame.Width+x] = decoded.PixelData[position]
+			}
+		}
+
+		result[idx] = frame
+	}
+
+	return result
+}
+
+func TestDCCRoundTrip(t *testing.T) {
+	tests := []struct {
+		name               string
+		directions         int
+		framesPerDirection int
+		colors             []byte
+	}{
+		{name: "single frame", directions: 1, framesPerDirection: 1, colors: []byte{5, 9, 200}},
+		{name: "repeated frames", directions: 1, framesPerDirection: 12, colors: []byte{17, 18, 250}},
+		{name: "several directions", directions: 8, framesPerDirection: 6, colors: []byte{5, 9, 200}},
+		{name: "single color", directions: 4, framesPerDirection: 3, colors: []byte{255}},
+	}
+
+	random := rand.New(rand.NewSource(1))
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			// with 3 opaque colors, no cell has more than 4 colors and every pixel is kept
+			frames := randomDCCFrames(random, test.directions, test.framesPerDirection, test.colors)
+
+			for idx, decoded := range decodeDCCFrames(t, frames, test.directions) {
+				for pixel := range decoded.Pixels {
+					if decoded.Pixels[pixel] != frames[idx].Pixels[pixel] {
+						t.Fatalf("pixel %d,%d of frame %d is %d, expected %d", pixel%decoded.Width, pixel/decoded.Width,
+							idx, decoded.Pixels[pixel], frames[idx].Pixels[pixel])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDCCReducesCellColors(t *testing.T) {
+	const directions, framesPerDirection = 2, 9
+
+	colors := make([]byte, numColors-1)
+	for idx := range colors {
+		colors[idx] = byte(idx + 1)
+	}
+
+	frames := randomDCCFrames(rand.New(rand.NewSource(2)), directions, framesPerDirection, colors)
+	decodedFrames := decodeDCCFrames(t, frames, directions)
+
+	for idx, decoded := range decodedFrames {
+		frame := frames[idx]
+
+		// the cells are placed on the grid of the direction, which starts at the top left of its frames
+		direction := idx / framesPerDirection
+		left, top := int(frame.OffsetX), int(frame.OffsetY)
+
+		for _, other := range frames[direction*framesPerDirection : (direction+1)*framesPerDirection] {
+			left, top = minInt(left, int(other.OffsetX)), minInt(top, int(other.OffsetY))
+		}
+
+		widths := dccCellSizes(int(frame.OffsetX)-left, frame.Width)
+		heights := dccCellSizes(int(frame.OffsetY)-top, frame.Height)
+
+		for cellY, y := 0, 0; y < len(heights); cellY, y = cellY+heights[y], y+1 {
+			for cellX, x := 0, 0; x < len(widths); cellX, x = cellX+widths[x], x+1 {
+				checkReducedCell(t, frame, decoded, cellX, cellY, widths[x], heights[y])
+			}
+		}
+	}
+}
+
+// checkReducedCell verifies that a decoded cell has at most 4 colors, which were colors of the original cell,
+// that the transparent pixels are kept and that the pixels of the kept colors are unchanged
+func checkReducedCell(t *testing.T, frame, decoded *Frame, cellX, cellY, width, height int) {
+	t.Helper()
+
+	original := make(map[byte]bool)
+	kept := make(map[byte]bool)
+
+	for y := cellY; y < cellY+height; y++ {
+		for x := cellX; x < cellX+width; x++ {
+			original[frame.Pixels[y*frame.Width+x]] = true
+			kept[decoded.Pixels[y*frame.Width+x]] = true
+		}
+	}
+
+	if len(kept) > dccCellColors {
+		t.Fatalf("the cell at %d,%d has %d colors", cellX, cellY, len(kept))
+	}
+
+	for y := cellY; y < cellY+height; y++ {
+		for x := cellX; x < cellX+width; x++ {
+			from, to := frame.Pixels[y*frame.Width+x], decoded.Pixels[y*frame.Width+x]
+
+			switch {
+			case (from == 0) != (to == 0):
+				t.Fatalf("the transparency of pixel %d,%d changed from %d to %d", x, y, from, to)
+			case !original[to]:
+				t.Fatalf("pixel %d,%d got the color %d, which isn't in its cell", x, y, to)
+			case kept[from] && from != to:
+				t.Fatalf("pixel %d,%d changed from the kept color %d to %d", x, y, from, to)
+			}
+		}
+	}
+}
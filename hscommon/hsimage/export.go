@@ -0,0 +1,289 @@
+package hsimage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+// ExportFormat is a way of writing the frames of a sprite to common image files
+type ExportFormat int
+
+// Export formats
+const (
+	// ExportPNGFrames writes every frame as a PNG
+	ExportPNGFrames ExportFormat = iota
+	// ExportSpriteSheet packs the frames into one PNG and describes them in a JSON atlas
+	ExportSpriteSheet
+	// ExportGIF writes an animated GIF per direction
+	ExportGIF
+)
+
+const (
+	// DefaultGIFDelay is the delay between the frames of the exported GIFs in 100ths of a second, the game runs
+	// its animations at 25 frames per second
+	DefaultGIFDelay = 4
+
+	exportFileMode = 0644
+	exportDirMode  = 0755
+
+	// the frames of a sprite sheet are separated by transparent pixels, so they don't bleed into each other
+	sheetPadding = 1
+)
+
+// ExportFormats returns the names of the export formats, in the order of their values
+func ExportFormats() []string {
+	return []string{"PNG frames", "Sprite sheet and JSON atlas", "Animated GIF per direction"}
+}
+
+// Atlas describes the frames of a sprite sheet
+type Atlas struct {
+	Image              string       `json:"image"`
+	Width              int          `json:"width"`
+	Height             int          `json:"height"`
+	Directions         int          `json:"directions"`
+	FramesPerDirection int          `json:"framesPerDirection"`
+	Frames             []AtlasFrame `json:"frames"`
+}
+
+// AtlasFrame is the place of a frame in a sprite sheet
+type AtlasFrame struct {
+	Direction int   `json:"direction"`
+	Frame     int   `json:"frame"`
+	X         int   `json:"x"`
+	Y         int   `json:"y"`
+	Width     int   `json:"width"`
+	Height    int   `json:"height"`
+	OffsetX   int32 `json:"offsetX"`
+	OffsetY   int32 `json:"offsetY"`
+}
+
+// ExportDC6 writes the frames of a DC6 with the colors of a palette into the directory, in files named after name.
+// It returns the paths of the written files.
+func ExportDC6(dc6 *d2dc6.DC6, palette d2interface.Palette, format ExportFormat, dir, name string) ([]string, error) {
+	if err := os.MkdirAll(dir, exportDirMode); err != nil {
+		return nil, err
+	}
+
+	frames := DC6Frames(dc6)
+	directions := int(dc6.Directions)
+
+	if len(frames) == 0 || directions < 1 {
+		return nil, errors.New("the DC6 has no frames")
+	}
+
+	switch format {
+	case ExportPNGFrames:
+		return exportPNGFrames(frames, directions, palette, dir, name)
+	case ExportSpriteSheet:
+		return exportSpriteSheet(frames, directions, palette, dir, name)
+	case ExportGIF:
+		return exportGIFs(frames, directions, palette, dir, name)
+	default:
+		return nil, fmt.Errorf("unknown export format %d", format)
+	}
+}
+
+// exportPNGFrames writes <name>_<direction>_<frame>.png files
+func exportPNGFrames(frames []*Frame, directions int, palette d2interface.Palette, dir, name string) ([]string, error) {
+	framesPerDirection := len(frames) / directions
+	result := make([]string, 0, len(frames))
+
+	for idx, frame := range frames {
+		path := filepath.Join(dir, fmt.Sprintf("%s_%d_%d.png", name, idx/framesPerDirection, idx%framesPerDirection))
+
+		if err := writePNG(path, frame.RGBA(palette)); err != nil {
+			return result, err
+		}
+
+		result = append(result, path)
+	}
+
+	return result, nil
+}
+
+// exportSpriteSheet writes <name>.png and <name>.json
+func exportSpriteSheet(frames []*Frame, directions int, palette d2interface.Palette, dir, name string) ([]string, error) {
+	framesPerDirection := len(frames) / directions
+	rects := packFrames(frames)
+
+	bounds := image.Rectangle{}
+	for _, rect := range rects {
+		bounds = bounds.Union(rect)
+	}
+
+	sheet := image.NewRGBA(bounds)
+	atlas := Atlas{
+		Image:              name + ".png",
+		Width:              bounds.Dx(),
+		Height:             bounds.Dy(),
+		Directions:         directions,
+		FramesPerDirection: framesPerDirection,
+		Frames:             make([]AtlasFrame, len(frames)),
+	}
+
+	for idx, frame := range frames {
+		draw.Draw(sheet, rects[idx], frame.RGBA(palette), image.Point{}, draw.Src)
+
+		atlas.Frames[idx] = AtlasFrame{
+			Direction: idx / framesPerDirection,
+			Frame:     idx % framesPerDirection,
+			X:         rects[idx].Min.X,
+			Y:         rects[idx].Min.Y,
+			Width:     frame.Width,
+			Height:    frame.Height,
+			OffsetX:   frame.OffsetX,
+			OffsetY:   frame.OffsetY,
+		}
+	}
+
+	sheetPath := filepath.Join(dir, atlas.Image)
+	if err := writePNG(sheetPath, sheet); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(atlas, "", "   ")
+	if err != nil {
+		return nil, err
+	}
+
+	atlasPath := filepath.Join(dir, name+".json")
+	if err := ioutil.WriteFile(atlasPath, data, exportFileMode); err != nil {
+		return []string{sheetPath}, err
+	}
+
+	return []string{sheetPath, atlasPath}, nil
+}
+
+// packFrames places the frames on shelves, the tallest frames first. The sheet is about as wide as it is high.
+func packFrames(frames []*Frame) []image.Rectangle {
+	order := make([]int, len(frames))
+	area, maxWidth := 0, 0
+
+	for idx, frame := range frames {
+		order[idx] = idx
+		area += (frame.Width + sheetPadding) * (frame.Height + sheetPadding)
+
+		if frame.Width > maxWidth {
+			maxWidth = frame.Width
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return frames[order[i]].Height > frames[order[j]].Height })
+
+	sheetWidth := int(math.Ceil(math.Sqrt(float64(area))))
+	if sheetWidth < maxWidth {
+		sheetWidth = maxWidth
+	}
+
+	result := make([]image.Rectangle, len(frames))
+	x, y, shelfHeight := 0, 0, 0
+
+	for _, idx := range order {
+		frame := frames[idx]
+
+		if x > 0 && x+frame.Width > sheetWidth {
+			x, y, shelfHeight = 0, y+shelfHeight+sheetPadding, 0
+		}
+
+		result[idx] = image.Rect(x, y, x+frame.Width, y+frame.Height)
+		x += frame.Width + sheetPadding
+
+		if frame.Height > shelfHeight {
+			shelfHeight = frame.Height
+		}
+	}
+
+	return result
+}
+
+// exportGIFs writes <name>_<direction>.gif files. The frames are placed by their offsets, so the animation
+// doesn't jump around.
+func exportGIFs(frames []*Frame, directions int, palette d2interface.Palette, dir, name string) ([]string, error) {
+	framesPerDirection := len(frames) / directions
+	colors := gifPalette(palette)
+	result := make([]string, 0, directions)
+
+	for direction := 0; direction < directions; direction++ {
+		directionFrames := frames[direction*framesPerDirection : (direction+1)*framesPerDirection]
+
+		bounds := image.Rectangle{}
+		for _, frame := range directionFrames {
+			bounds = bounds.Union(frameBounds(frame))
+		}
+
+		animation := &gif.GIF{
+			Config: image.Config{ColorModel: colors, Width: bounds.Dx(), Height: bounds.Dy()},
+		}
+
+		for _, frame := range directionFrames {
+			img := image.NewPaletted(image.Rect(0, 0, bounds.Dx(), bounds.Dy()), colors)
+			origin := frameBounds(frame).Min.Sub(bounds.Min)
+
+			for y := 0; y < frame.Height; y++ {
+				copy(img.Pix[(origin.Y+y)*img.Stride+origin.X:], frame.Pixels[y*frame.Width:(y+1)*frame.Width])
+			}
+
+			animation.Image = append(animation.Image, img)
+			animation.Delay = append(animation.Delay, DefaultGIFDelay)
+			// every frame replaces the previous one, the transparent pixels must not show the previous frame
+			animation.Disposal = append(animation.Disposal, gif.DisposalBackground)
+		}
+
+		var buf bytes.Buffer
+		if err := gif.EncodeAll(&buf, animation); err != nil {
+			return result, err
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("%s_%d.gif", name, direction))
+		if err := ioutil.WriteFile(path, buf.Bytes(), exportFileMode); err != nil {
+			return result, err
+		}
+
+		result = append(result, path)
+	}
+
+	return result, nil
+}
+
+// frameBounds returns the rectangle a frame covers, relative to the origin of its sprite
+func frameBounds(frame *Frame) image.Rectangle {
+	x, y := int(frame.OffsetX), int(frame.OffsetY)
+
+	return image.Rect(x, y, x+frame.Width, y+frame.Height)
+}
+
+// gifPalette converts a palette for GIFs, the palette indices of the frames can be used as they are
+func gifPalette(palette d2interface.Palette) color.Palette {
+	result := make(color.Palette, numColors)
+
+	for idx := range result {
+		result[idx] = Color(palette, byte(idx))
+	}
+
+	return result
+}
+
+func writePNG(path string, img image.Image) error {
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), exportFileMode)
+}
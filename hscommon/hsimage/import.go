@@ -0,0 +1,238 @@
+package hsimage
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+const (
+	pngExtension = ".png"
+)
+
+// Grid describes how the frames are laid out in a sprite sheet: row by row, in cells of the same size
+type Grid struct {
+	Columns, Rows int
+}
+
+// ImportOptions controls how images are turned into indexed frames
+type ImportOptions struct {
+	// Directions is the number of directions, the frames are divided evenly between them
+	Directions int
+	// Dither spreads the difference between the colors of the images and the palette to the neighbouring pixels
+	Dither bool
+	// OffsetX and OffsetY are the offsets of every frame which has no entry in Offsets
+	OffsetX, OffsetY int32
+	// Offsets are the offsets of the frames, in the order of the frames
+	Offsets []image.Point
+}
+
+// LoadPNG reads a PNG image
+func LoadPNG(path string) (image.Image, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	return png.Decode(bytes.NewReader(data))
+}
+
+// LoadPNGFrames reads the PNG images of a directory, sorted by their names. Numbers in the names are compared
+// by their value, so frame2.png comes before frame10.png.
+func LoadPNGFrames(dir string) ([]image.Image, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(infos))
+
+	for _, info := range infos {
+		if !info.IsDir() && strings.EqualFold(filepath.Ext(info.Name()), pngExtension) {
+			names = append(names, info.Name())
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s contains no PNG files", dir)
+	}
+
+	sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+
+	result := make([]image.Image, len(names))
+
+	for idx, name := range names {
+		if result[idx], err = LoadPNG(filepath.Join(dir, name)); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return result, nil
+}
+
+// SplitSpriteSheet cuts a sprite sheet into the frames of a grid
+func SplitSpriteSheet(sheet image.Image, grid Grid) ([]image.Image, error) {
+	bounds := sheet.Bounds()
+
+	if grid.Columns < 1 || grid.Rows < 1 {
+		return nil, errors.New("the grid needs at least one column and one row")
+	}
+
+	if bounds.Dx()%grid.Columns != 0 || bounds.Dy()%grid.Rows != 0 {
+		return nil, fmt.Errorf("a %dx%d sprite sheet can't be divided into %d columns and %d rows",
+			bounds.Dx(), bounds.Dy(), grid.Columns, grid.Rows)
+	}
+
+	cellW, cellH := bounds.Dx()/grid.Columns, bounds.Dy()/grid.Rows
+	result := make([]image.Image, 0, grid.Columns*grid.Rows)
+
+	for row := 0; row < grid.Rows; row++ {
+		for column := 0; column < grid.Columns; column++ {
+			cell := image.NewNRGBA(image.Rect(0, 0, cellW, cellH))
+			origin := bounds.Min.Add(image.Pt(column*cellW, row*cellH))
+			draw.Draw(cell, cell.Bounds(), sheet, origin, draw.Src)
+
+			result = append(result, cell)
+		}
+	}
+
+	return result, nil
+}
+
+// ParseOffsets reads frame offsets, one "x,y" pair per line. Empty lines are ignored.
+func ParseOffsets(data []byte) ([]image.Point, error) {
+	result := make([]image.Point, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		fields := strings.Split(text, ",")
+		if len(fields) != 2 { // nolint:gomnd // x and y
+			return nil, fmt.Errorf("line %d: expected x,y", line)
+		}
+
+		x, xErr := strconv.Atoi(strings.TrimSpace(fields[0]))
+		y, yErr := strconv.Atoi(strings.TrimSpace(fields[1]))
+
+		if xErr != nil || yErr != nil {
+			return nil, fmt.Errorf("line %d: %s is not a pair of numbers", line, text)
+		}
+
+		result = append(result, image.Pt(x, y))
+	}
+
+	return result, scanner.Err()
+}
+
+// LoadOffsets reads a file of frame offsets, see ParseOffsets
+func LoadOffsets(path string) ([]image.Point, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s doesn't exist", path)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return ParseOffsets(data)
+}
+
+// QuantizeFrames converts images into frames of palette indices
+func QuantizeFrames(images []image.Image, palette d2interface.Palette, options ImportOptions) []*Frame {
+	quantizer := NewQuantizer(palette)
+	result := make([]*Frame, len(images))
+
+	for idx, img := range images {
+		frame := &Frame{
+			Width:   img.Bounds().Dx(),
+			Height:  img.Bounds().Dy(),
+			OffsetX: options.OffsetX,
+			OffsetY: options.OffsetY,
+			Pixels:  quantizer.Quantize(img, options.Dither),
+		}
+
+		if idx < len(options.Offsets) {
+			frame.OffsetX, frame.OffsetY = int32(options.Offsets[idx].X), int32(options.Offsets[idx].Y)
+		}
+
+		result[idx] = frame
+	}
+
+	return result
+}
+
+// ImportDC6 builds a DC6 from images, which are quantized to the palette
+func ImportDC6(images []image.Image, palette d2interface.Palette, options ImportOptions) (*d2dc6.DC6, error) {
+	if err := checkImport(images, palette, options); err != nil {
+		return nil, err
+	}
+
+	return NewDC6(QuantizeFrames(images, palette, options), options.Directions)
+}
+
+// checkImport verifies that the images can be quantized and that every image has an offset
+func checkImport(images []image.Image, palette d2interface.Palette, options ImportOptions) error {
+	if palette == nil {
+		return errors.New("a palette is needed to import images")
+	}
+
+	if len(options.Offsets) > 0 && len(options.Offsets) != len(images) {
+		return fmt.Errorf("there are %d offsets for %d frames", len(options.Offsets), len(images))
+	}
+
+	return nil
+}
+
+// naturalLess compares names like a person would, the numbers in the names are compared by their value
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		chunkA, restA := nextChunk(a)
+		chunkB, restB := nextChunk(b)
+
+		numberA, errA := strconv.Atoi(chunkA)
+		numberB, errB := strconv.Atoi(chunkB)
+
+		switch {
+		case errA == nil && errB == nil && numberA != numberB:
+			return numberA < numberB
+		case errA != nil || errB != nil:
+			if lowerA, lowerB := strings.ToLower(chunkA), strings.ToLower(chunkB); lowerA != lowerB {
+				return lowerA < lowerB
+			}
+		}
+
+		a, b = restA, restB
+	}
+
+	return len(a) < len(b)
+}
+
+// nextChunk splits a string after its leading run of digits or of other characters
+func nextChunk(s string) (chunk, rest string) {
+	isDigit := unicode.IsDigit(rune(s[0]))
+
+	for idx, r := range s {
+		if unicode.IsDigit(r) != isDigit {
+			return s[:idx], s[idx:]
+		}
+	}
+
+	return s, ""
+}
@@ -0,0 +1,128 @@
+// Package hsimage converts the indexed images of the game (DC6, DCC) from and to common image formats
+package hsimage
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+const (
+	numColors = 256
+
+	// pixels which are more transparent than this become the transparent palette index 0
+	alphaThreshold = 0x80
+
+	// Floyd-Steinberg weights, in sixteenths
+	ditherRight, ditherBelowLeft, ditherBelow, ditherBelowRight, ditherDivisor = 7, 3, 5, 1, 16
+)
+
+// Quantizer maps colors to the nearest colors of a palette. Index 0 is transparent, so opaque colors
+// are never mapped to it.
+type Quantizer struct {
+	colors [numColors][3]int
+	cache  map[[3]int]byte
+}
+
+// NewQuantizer creates a quantizer for a palette
+func NewQuantizer(palette d2interface.Palette) *Quantizer {
+	result := &Quantizer{
+		cache: make(map[[3]int]byte),
+	}
+
+	for idx, c := range palette.GetColors() {
+		if c != nil {
+			result.colors[idx] = [3]int{int(c.R()), int(c.G()), int(c.B())}
+		}
+	}
+
+	return result
+}
+
+// Index returns the palette index of the color which is nearest to an opaque color
+func (q *Quantizer) Index(rgb [3]int) byte {
+	if idx, found := q.cache[rgb]; found {
+		return idx
+	}
+
+	best, bestDistance := 1, -1
+
+	for idx := 1; idx < numColors; idx++ {
+		dr, dg, db := rgb[0]-q.colors[idx][0], rgb[1]-q.colors[idx][1], rgb[2]-q.colors[idx][2]
+
+		distance := dr*dr + dg*dg + db*db
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = idx, distance
+		}
+	}
+
+	q.cache[rgb] = byte(best)
+
+	return byte(best)
+}
+
+// Quantize returns the palette indices of the image's pixels, row by row. Transparent pixels get the index 0.
+// With dithering, the difference between a pixel and its palette color is spread to the neighbouring pixels.
+func (q *Quantizer) Quantize(img image.Image, dither bool) []byte {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	result := make([]byte, width*height)
+
+	// the errors carried to the current and the next row, with a pixel of padding on each side
+	current, next := make([][3]int, width+2), make([][3]int, width+2)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			if c.A < alphaThreshold {
+				continue
+			}
+
+			rgb := [3]int{int(c.R), int(c.G), int(c.B)}
+
+			if dither {
+				for channel := range rgb {
+					rgb[channel] = clampChannel(rgb[channel] + current[x+1][channel]/ditherDivisor)
+				}
+			}
+
+			idx := q.Index(rgb)
+			result[x+y*width] = idx
+
+			if !dither {
+				continue
+			}
+
+			for channel := range rgb {
+				diff := rgb[channel] - q.colors[idx][channel]
+
+				current[x+2][channel] += diff * ditherRight
+				next[x][channel] += diff * ditherBelowLeft
+				next[x+1][channel] += diff * ditherBelow
+				next[x+2][channel] += diff * ditherBelowRight
+			}
+		}
+
+		current, next = next, current
+
+		for idx := range next {
+			next[idx] = [3]int{}
+		}
+	}
+
+	return result
+}
+
+func clampChannel(value int) int {
+	const maxValue = 0xff
+
+	switch {
+	case value < 0:
+		return 0
+	case value > maxValue:
+		return maxValue
+	default:
+		return value
+	}
+}
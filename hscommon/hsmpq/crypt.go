@@ -0,0 +1,93 @@
+package hsmpq
+
+import "strings"
+
+// hash types used by hashString
+const (
+	hashTypeTableOffset uint32 = iota
+	hashTypeNameA
+	hashTypeNameB
+	hashTypeFileKey
+)
+
+const (
+	cryptTableSize   = 0x500
+	cryptTableStride = 0x100
+	cryptSeed        = 0x00100001
+	cryptMultiplier  = 125
+	cryptIncrement   = 3
+	cryptModulus     = 0x2AAAAB
+	cryptKeyTable    = 0x400
+)
+
+// nolint:gochecknoglobals // the crypt table is constant, it is just too large to be written out by hand
+var cryptTable = buildCryptTable()
+
+func buildCryptTable() [cryptTableSize]uint32 {
+	var table [cryptTableSize]uint32
+
+	seed := uint32(cryptSeed)
+
+	for i := 0; i < cryptTableStride; i++ {
+		idx := i
+
+		// nolint:gomnd // every index has 5 entries, one per hash type (and one for encryption)
+		for j := 0; j < 5; j++ {
+			seed = (seed*cryptMultiplier + cryptIncrement) % cryptModulus
+			high := (seed & 0xFFFF) << 16
+
+			seed = (seed*cryptMultiplier + cryptIncrement) % cryptModulus
+			low := seed & 0xFFFF
+
+			table[idx] = high | low
+			idx += cryptTableStride
+		}
+	}
+
+	return table
+}
+
+// normalizePath converts the given path to the form used for hashing (upper case, backslash separated)
+func normalizePath(path string) string {
+	return strings.ToUpper(strings.ReplaceAll(path, "/", "\\"))
+}
+
+// hashString hashes a file name (or a well-known key) the same way the game does
+func hashString(key string, hashType uint32) uint32 {
+	seed1 := uint32(0x7FED7FED)
+	seed2 := uint32(0xEEEEEEEE)
+
+	for _, c := range []byte(normalizePath(key)) {
+		ch := uint32(c)
+		seed1 = cryptTable[hashType*cryptTableStride+ch] ^ (seed1 + seed2)
+		seed2 = ch + seed1 + seed2 + (seed2 << 5) + 3 // nolint:gomnd // part of the hash algorithm
+	}
+
+	return seed1
+}
+
+// encrypt encrypts the given values in place
+func encrypt(data []uint32, key uint32) {
+	seed := uint32(0xEEEEEEEE)
+
+	for i := range data {
+		seed += cryptTable[cryptKeyTable+(key&0xFF)]
+		value := data[i]
+		data[i] = value ^ (key + seed)
+		key = ((^key << 0x15) + 0x11111111) | (key >> 0x0B)
+		seed = value + seed + (seed << 5) + 3 // nolint:gomnd // part of the encryption algorithm
+	}
+}
+
+// decrypt decrypts the given values in place
+func decrypt(data []uint32, key uint32) {
+	seed := uint32(0xEEEEEEEE)
+
+	for i := range data {
+		seed += cryptTable[cryptKeyTable+(key&0xFF)]
+		value := data[i] ^ (key + seed)
+		data[i] = value
+		key = ((^key << 0x15) + 0x11111111) | (key >> 0x0B)
+		seed = value + seed + (seed << 5) + 3 // nolint:gomnd // part of the encryption algorithm
+	}
+}
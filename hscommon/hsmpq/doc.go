@@ -0,0 +1,2 @@
+// Package hsmpq contains low-level helpers for building and inspecting MPQ archives
+package hsmpq
@@ -0,0 +1,120 @@
+package hsmpq
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2mpq"
+)
+
+// maxCheckedFileSize is the largest uncompressed size a block may claim, larger blocks are reported as damaged
+// instead of allocating memory for them (the game's largest files are a few megabytes)
+const maxCheckedFileSize = 256 << 20
+
+// IntegrityFailure describes a block which could not be read
+type IntegrityFailure struct {
+	Block Block
+	Err   error
+}
+
+// IntegrityReport is the result of CheckIntegrity
+type IntegrityReport struct {
+	// Checked is the number of blocks which were decompressed
+	Checked int
+	// Skipped is the number of encrypted blocks which couldn't be checked because their name is unknown
+	Skipped  int
+	Failures []IntegrityFailure
+	// Canceled is true if the check was stopped before every block was checked
+	Canceled bool
+}
+
+// CheckIntegrity reads and decompresses every block in use, the same way HellSpawner reads files from the archive.
+// Encrypted blocks can only be decrypted if their name is known (see AssignNames), the others are skipped.
+// progress is called after every block, the check is canceled if it returns false.
+func (a *ArchiveInfo) CheckIntegrity(progress func(done, total int) bool) (*IntegrityReport, error) {
+	mpq, err := d2mpq.FromFile(a.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if closeErr := mpq.Close(); closeErr != nil {
+			log.Print(closeErr)
+		}
+	}()
+
+	report := &IntegrityReport{Failures: make([]IntegrityFailure, 0)}
+
+	for idx := range a.Blocks {
+		block := a.Blocks[idx]
+
+		switch {
+		case !block.Flags.Has(FlagExists), block.Flags.Has(FlagDeleteMarker):
+			// nothing to read
+		case block.Flags.Has(FlagEncrypted) && block.Name == unknownName:
+			report.Skipped++
+		default:
+			report.Checked++
+
+			if checkErr := a.checkBlock(mpq, &block); checkErr != nil {
+				report.Failures = append(report.Failures, IntegrityFailure{Block: block, Err: checkErr})
+			}
+		}
+
+		if progress != nil && !progress(idx+1, len(a.Blocks)) {
+			report.Canceled = true
+			break
+		}
+	}
+
+	return report, nil
+}
+
+// checkBlock decompresses a single block, the decoders panic on some damaged data
+func (a *ArchiveInfo) checkBlock(mpq *d2mpq.MPQ, block *Block) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("the decoder failed: %v", r)
+		}
+	}()
+
+	if int64(block.FilePosition)+int64(block.CompressedSize) > a.FileSize {
+		return errors.New("the block ends behind the end of the archive")
+	}
+
+	if block.UncompressedSize > maxCheckedFileSize {
+		return fmt.Errorf("implausible size of %d bytes", block.UncompressedSize)
+	}
+
+	streamBlock := &d2mpq.Block{
+		FilePosition:         block.FilePosition,
+		CompressedFileSize:   block.CompressedSize,
+		UncompressedFileSize: block.UncompressedSize,
+		Flags:                d2mpq.FileFlag(block.Flags),
+		FileName:             strings.ToLower(block.Name),
+	}
+
+	if block.Name != unknownName {
+		streamBlock.EncryptionSeed = block.Key()
+	}
+
+	stream, err := d2mpq.CreateStream(mpq, streamBlock, block.Name)
+	if err != nil {
+		return err
+	}
+
+	buffer := make([]byte, block.UncompressedSize)
+
+	read, err := stream.Read(buffer, 0, block.UncompressedSize)
+	if err != nil {
+		return err
+	}
+
+	if read != block.UncompressedSize {
+		return fmt.Errorf("only %d of %d bytes could be read", read, block.UncompressedSize)
+	}
+
+	return nil
+}
@@ -0,0 +1,351 @@
+package hsmpq
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileFlags are the flags of a block table entry
+type FileFlags uint32
+
+// flags of a block table entry
+const (
+	// FlagImplode - the file is compressed using the PKWARE data compression library
+	FlagImplode FileFlags = 0x00000100
+	// FlagCompress - the file is compressed using a combination of compression methods
+	FlagCompress FileFlags = 0x00000200
+	// FlagEncrypted - the file is encrypted
+	FlagEncrypted FileFlags = 0x00010000
+	// FlagFixKey - the encryption key depends on the position and size of the file
+	FlagFixKey FileFlags = 0x00020000
+	// FlagPatchFile - the file is an incremental patch
+	FlagPatchFile FileFlags = 0x00100000
+	// FlagSingleUnit - the file is stored as a single unit instead of sectors
+	FlagSingleUnit FileFlags = 0x01000000
+	// FlagDeleteMarker - the file marks a file of a lower priority archive as deleted
+	FlagDeleteMarker FileFlags = 0x02000000
+	// FlagSectorCRC - the file has a checksum for every sector
+	FlagSectorCRC FileFlags = 0x04000000
+	// FlagExists - the entry is in use
+	FlagExists FileFlags = 0x80000000
+)
+
+// Has returns true if the given flag is set
+func (f FileFlags) Has(flag FileFlags) bool {
+	return f&flag != 0
+}
+
+// String returns the names of the set flags
+func (f FileFlags) String() string {
+	names := []struct {
+		flag FileFlags
+		name string
+	}{
+		{FlagEncrypted, "encrypted"},
+		{FlagFixKey, "fix key"},
+		{FlagImplode, "imploded"},
+		{FlagCompress, "compressed"},
+		{FlagSingleUnit, "single unit"},
+		{FlagPatchFile, "patch"},
+		{FlagDeleteMarker, "delete marker"},
+		{FlagSectorCRC, "sector CRC"},
+	}
+
+	result := make([]string, 0)
+
+	for _, entry := range names {
+		if f.Has(entry.flag) {
+			result = append(result, entry.name)
+		}
+	}
+
+	if !f.Has(FlagExists) {
+		result = append(result, "unused")
+	}
+
+	return strings.Join(result, ", ")
+}
+
+const (
+	// hash table entries which are not (or no longer) in use
+	hashEntryDeleted = 0xFFFFFFFE
+	// 4 values per hash and block table entry
+	tableEntryValues = 4
+	// locale and platform are stored in the same value
+	localeShift = 16
+	// blocks which weren't found by AssignNames have no name
+	unknownName = ""
+)
+
+// Header is the header of an MPQ archive
+type Header struct {
+	HeaderSize        uint32
+	ArchiveSize       uint32
+	FormatVersion     uint16
+	SectorSizeShift   uint16
+	HashTableOffset   uint32
+	BlockTableOffset  uint32
+	HashTableEntries  uint32
+	BlockTableEntries uint32
+}
+
+// SectorSize returns the size of a file sector in bytes
+func (h *Header) SectorSize() int {
+	return baseSectorSize << h.SectorSizeShift
+}
+
+// HashEntry is an entry of the hash table
+type HashEntry struct {
+	NameA      uint32
+	NameB      uint32
+	Locale     uint16
+	Platform   uint16
+	BlockIndex uint32
+}
+
+// IsEmpty returns true if the entry has never been used
+func (h *HashEntry) IsEmpty() bool {
+	return h.BlockIndex == hashEntryEmpty
+}
+
+// IsDeleted returns true if the entry's file has been deleted
+func (h *HashEntry) IsDeleted() bool {
+	return h.BlockIndex == hashEntryDeleted
+}
+
+// Block is an entry of the block table
+type Block struct {
+	Index            int
+	FilePosition     uint32
+	CompressedSize   uint32
+	UncompressedSize uint32
+	Flags            FileFlags
+	// Name is the file's name, it is empty unless it was found by AssignNames
+	Name string
+	// HashEntries is the number of hash table entries referring to the block
+	HashEntries int
+}
+
+// Ratio returns the compressed size divided by the uncompressed size
+func (b *Block) Ratio() float64 {
+	if b.UncompressedSize == 0 {
+		return 1
+	}
+
+	return float64(b.CompressedSize) / float64(b.UncompressedSize)
+}
+
+// Key returns the key the block's data is encrypted with, it is only valid if the block has a name
+func (b *Block) Key() uint32 {
+	name := b.Name[strings.LastIndex(b.Name, `\`)+1:]
+	key := hashString(name, hashTypeFileKey)
+
+	if b.Flags.Has(FlagFixKey) {
+		key = (key + b.FilePosition) ^ b.UncompressedSize
+	}
+
+	return key
+}
+
+// ArchiveInfo describes the internal structure of an MPQ archive
+type ArchiveInfo struct {
+	Path     string
+	FileSize int64
+	Header   Header
+	Hashes   []HashEntry
+	Blocks   []Block
+}
+
+// Inspect reads the header, the hash table and the block table of an archive
+func Inspect(fileName string) (*ArchiveInfo, error) {
+	file, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Print(closeErr)
+		}
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ArchiveInfo{
+		Path:     fileName,
+		FileSize: info.Size(),
+	}
+
+	var magic [4]byte
+	if err := binary.Read(file, binary.LittleEndian, &magic); err != nil {
+		return nil, err
+	}
+
+	if magic != headerMagic {
+		return nil, errors.New("invalid mpq header")
+	}
+
+	if err := binary.Read(file, binary.LittleEndian, &result.Header); err != nil {
+		return nil, err
+	}
+
+	hashes, err := readTable(file, result.FileSize, result.Header.HashTableOffset, result.Header.HashTableEntries, hashTableKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hash table: %w", err)
+	}
+
+	blocks, err := readTable(file, result.FileSize, result.Header.BlockTableOffset, result.Header.BlockTableEntries, blockTableKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block table: %w", err)
+	}
+
+	result.Blocks = make([]Block, result.Header.BlockTableEntries)
+	for idx := range result.Blocks {
+		values := blocks[idx*tableEntryValues:]
+		result.Blocks[idx] = Block{
+			Index:            idx,
+			FilePosition:     values[0],
+			CompressedSize:   values[1],
+			UncompressedSize: values[2],
+			Flags:            FileFlags(values[3]),
+		}
+	}
+
+	result.Hashes = make([]HashEntry, result.Header.HashTableEntries)
+	for idx := range result.Hashes {
+		values := hashes[idx*tableEntryValues:]
+		result.Hashes[idx] = HashEntry{
+			NameA:      values[0],
+			NameB:      values[1],
+			Locale:     uint16(values[2] >> localeShift),
+			Platform:   uint16(values[2]),
+			BlockIndex: values[3],
+		}
+
+		if values[3] < uint32(len(result.Blocks)) {
+			result.Blocks[values[3]].HashEntries++
+		}
+	}
+
+	return result, nil
+}
+
+// readTable reads and decrypts the hash or block table
+func readTable(file io.ReadSeeker, fileSize int64, offset, entries uint32, key string) ([]uint32, error) {
+	// hash and block table entries have the same size
+	size := int64(entries) * hashEntrySize
+	if int64(offset)+size > fileSize {
+		return nil, fmt.Errorf("%d entries at offset %d exceed the archive's size", entries, offset)
+	}
+
+	if _, err := file.Seek(int64(offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	values := make([]uint32, entries*tableEntryValues)
+	if err := binary.Read(file, binary.LittleEndian, values); err != nil {
+		return nil, err
+	}
+
+	decrypt(values, hashString(key, hashTypeFileKey))
+
+	return values, nil
+}
+
+// AssignNames sets the names of the blocks which belong to one of the given file names.
+// It returns the number of blocks named.
+func (a *ArchiveInfo) AssignNames(names []string) int {
+	if len(a.Hashes) == 0 {
+		return 0
+	}
+
+	byName := make(map[uint64]uint32, len(a.Hashes))
+
+	for idx := range a.Hashes {
+		hash := &a.Hashes[idx]
+		if hash.IsEmpty() || hash.IsDeleted() || hash.BlockIndex >= uint32(len(a.Blocks)) {
+			continue
+		}
+
+		byName[uint64(hash.NameA)<<32|uint64(hash.NameB)] = hash.BlockIndex
+	}
+
+	count := 0
+
+	candidates := make([]string, 0, len(names)+1)
+	candidates = append(candidates, names...)
+	candidates = append(candidates, ListFileName)
+
+	for _, name := range candidates {
+		key := uint64(hashString(name, hashTypeNameA))<<32 | uint64(hashString(name, hashTypeNameB))
+
+		blockIndex, found := byName[key]
+		if !found || a.Blocks[blockIndex].Name != unknownName {
+			continue
+		}
+
+		a.Blocks[blockIndex].Name = strings.ReplaceAll(name, "/", `\`)
+		count++
+	}
+
+	return count
+}
+
+// Problems returns the structural problems of the archive: blocks outside of the archive,
+// hash entries referring to missing blocks and blocks without hash entries
+func (a *ArchiveInfo) Problems() []string {
+	result := make([]string, 0)
+
+	for idx := range a.Hashes {
+		hash := &a.Hashes[idx]
+		if hash.IsEmpty() || hash.IsDeleted() {
+			continue
+		}
+
+		if hash.BlockIndex >= uint32(len(a.Blocks)) {
+			result = append(result, fmt.Sprintf("hash entry %d refers to block %d, the block table has %d entries",
+				idx, hash.BlockIndex, len(a.Blocks)))
+		}
+	}
+
+	for idx := range a.Blocks {
+		block := &a.Blocks[idx]
+		if !block.Flags.Has(FlagExists) {
+			continue
+		}
+
+		if int64(block.FilePosition)+int64(block.CompressedSize) > a.FileSize {
+			result = append(result, fmt.Sprintf("block %d (%s) ends at %d, behind the end of the archive (%d bytes)",
+				idx, a.BlockName(block), int64(block.FilePosition)+int64(block.CompressedSize), a.FileSize))
+		}
+
+		if block.HashEntries == 0 {
+			result = append(result, fmt.Sprintf("block %d is not referred to by the hash table", idx))
+		}
+
+		if !block.Flags.Has(FlagCompress|FlagImplode) && block.CompressedSize != block.UncompressedSize {
+			result = append(result, fmt.Sprintf("block %d (%s) is not compressed, but its sizes differ (%d and %d bytes)",
+				idx, a.BlockName(block), block.CompressedSize, block.UncompressedSize))
+		}
+	}
+
+	return result
+}
+
+// BlockName returns the name of a block, or a placeholder if it is unknown
+func (a *ArchiveInfo) BlockName(block *Block) string {
+	if block.Name == unknownName {
+		return fmt.Sprintf("(unknown #%d)", block.Index)
+	}
+
+	return block.Name
+}
@@ -0,0 +1,365 @@
+package hsmpq
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Compression represents the way a file's data is stored inside of the archive
+type Compression int
+
+// Compression types supported by the writer
+const (
+	// CompressionNone stores the file as-is
+	CompressionNone Compression = iota
+	// CompressionZLib compresses every sector of the file using zlib (deflate)
+	CompressionZLib
+)
+
+// String returns the name of the compression type
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "None"
+	case CompressionZLib:
+		return "ZLib"
+	}
+
+	return "Unknown"
+}
+
+// ListFileName is the name of the file inside of the archive which lists all other files
+const ListFileName = "(listfile)"
+
+const (
+	headerSize      = 32
+	hashEntrySize   = 16
+	blockEntrySize  = 16
+	minHashEntries  = 16
+	sectorSizeShift = 3
+	baseSectorSize  = 512
+	formatVersion   = 0
+)
+
+// block flags
+const (
+	blockFlagCompress uint32 = 0x00000200
+	blockFlagExists   uint32 = 0x80000000
+)
+
+// sector compression masks
+const (
+	compressionMaskZLib byte = 0x02
+)
+
+const (
+	hashEntryEmpty  = 0xFFFFFFFF
+	neutralLocale   = 0
+	hashTableKey    = "(hash table)"
+	blockTableKey   = "(block table)"
+	listFileLineSep = "\r\n"
+	newFileMode     = 0644
+)
+
+// nolint:gochecknoglobals // magic value of the mpq header
+var headerMagic = [4]byte{'M', 'P', 'Q', 0x1A}
+
+type blockEntry struct {
+	FilePos          uint32
+	CompressedSize   uint32
+	UncompressedSize uint32
+	Flags            uint32
+}
+
+// Writer builds an MPQ archive. File data is written to disk as soon as it is added;
+// the hash table, block table and listfile are written when the writer is closed.
+type Writer struct {
+	file     *os.File
+	offset   uint32
+	blocks   []blockEntry
+	names    []string
+	nameToID map[string]int
+}
+
+// Create creates a new archive at the given path, overwriting any existing file
+func Create(fileName string) (*Writer, error) {
+	file, err := os.OpenFile(filepath.Clean(fileName), os.O_RDWR|os.O_CREATE|os.O_TRUNC, newFileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Writer{
+		file:     file,
+		blocks:   make([]blockEntry, 0),
+		names:    make([]string, 0),
+		nameToID: make(map[string]int),
+	}
+
+	// reserve space for the header, it is written once the table positions are known
+	if err := result.write(make([]byte, headerSize)); err != nil {
+		result.closeFile()
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SectorSize returns the size of a single file sector in the archive
+func SectorSize() int {
+	return baseSectorSize << sectorSizeShift
+}
+
+// AddFile adds a file to the archive. The archive path should be relative to the root of the archive,
+// for example data\global\ui\panel\invchar6.dc6
+func (w *Writer) AddFile(archivePath string, data []byte, compression Compression) error {
+	if w.file == nil {
+		return errors.New("mpq writer is closed")
+	}
+
+	archivePath = strings.ReplaceAll(archivePath, "/", "\\")
+
+	if archivePath == "" {
+		return errors.New("cannot add a file with a blank name")
+	}
+
+	key := normalizePath(archivePath)
+	if _, found := w.nameToID[key]; found {
+		return fmt.Errorf("file %s was already added to the archive", archivePath)
+	}
+
+	block := blockEntry{
+		FilePos:          w.offset,
+		UncompressedSize: uint32(len(data)),
+		Flags:            blockFlagExists,
+	}
+
+	var fileData []byte
+
+	switch compression {
+	case CompressionNone:
+		fileData = data
+	case CompressionZLib:
+		compressed, err := compressSectors(data)
+		if err != nil {
+			return err
+		}
+
+		fileData = compressed
+		block.Flags |= blockFlagCompress
+	default:
+		return fmt.Errorf("unsupported compression type %d", compression)
+	}
+
+	block.CompressedSize = uint32(len(fileData))
+
+	if err := w.write(fileData); err != nil {
+		return err
+	}
+
+	w.nameToID[key] = len(w.blocks)
+	w.blocks = append(w.blocks, block)
+	w.names = append(w.names, archivePath)
+
+	return nil
+}
+
+// Close writes the listfile, the hash table, the block table and the header, then closes the archive
+func (w *Writer) Close() error {
+	if w.file == nil {
+		return errors.New("mpq writer is already closed")
+	}
+
+	defer w.closeFile()
+
+	listFile := strings.Join(w.names, listFileLineSep) + listFileLineSep
+	if err := w.AddFile(ListFileName, []byte(listFile), CompressionZLib); err != nil {
+		return err
+	}
+
+	hashTable := w.buildHashTable()
+	hashTableOffset := w.offset
+
+	if err := w.write(tableBytes(hashTable, hashString(hashTableKey, hashTypeFileKey))); err != nil {
+		return err
+	}
+
+	blockTable := make([]uint32, 0, len(w.blocks)*blockEntrySize/4)
+	for idx := range w.blocks {
+		b := &w.blocks[idx]
+		blockTable = append(blockTable, b.FilePos, b.CompressedSize, b.UncompressedSize, b.Flags)
+	}
+
+	blockTableOffset := w.offset
+
+	if err := w.write(tableBytes(blockTable, hashString(blockTableKey, hashTypeFileKey))); err != nil {
+		return err
+	}
+
+	header := new(bytes.Buffer)
+	header.Write(headerMagic[:])
+
+	fields := []interface{}{
+		uint32(headerSize),
+		w.offset,
+		uint16(formatVersion),
+		uint16(sectorSizeShift),
+		hashTableOffset,
+		blockTableOffset,
+		uint32(len(hashTable) * 4 / hashEntrySize),
+		uint32(len(w.blocks)),
+	}
+
+	for _, field := range fields {
+		if err := binary.Write(header, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.file.WriteAt(header.Bytes(), 0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Abort closes the archive without finishing it and removes the partially written file
+func (w *Writer) Abort() {
+	if w.file == nil {
+		return
+	}
+
+	fileName := w.file.Name()
+
+	w.closeFile()
+
+	if err := os.Remove(fileName); err != nil {
+		log.Print(err)
+	}
+}
+
+func (w *Writer) write(data []byte) error {
+	n, err := w.file.Write(data)
+	w.offset += uint32(n)
+
+	return err
+}
+
+func (w *Writer) closeFile() {
+	if err := w.file.Close(); err != nil {
+		log.Print(err)
+	}
+
+	w.file = nil
+}
+
+// buildHashTable returns the (unencrypted) hash table as a list of uint32 values
+func (w *Writer) buildHashTable() []uint32 {
+	numEntries := minHashEntries
+	for numEntries < len(w.names)*2 {
+		numEntries *= 2
+	}
+
+	// 4 values per entry: name A, name B, locale + platform, block index
+	const valuesPerEntry = hashEntrySize / 4
+
+	table := make([]uint32, numEntries*valuesPerEntry)
+	for idx := range table {
+		table[idx] = hashEntryEmpty
+	}
+
+	mask := uint32(numEntries - 1)
+
+	for blockIdx, name := range w.names {
+		entry := hashString(name, hashTypeTableOffset) & mask
+
+		// linear probing, the table is always at least twice as big as the number of files
+		for table[entry*valuesPerEntry+3] != hashEntryEmpty {
+			entry = (entry + 1) & mask
+		}
+
+		table[entry*valuesPerEntry] = hashString(name, hashTypeNameA)
+		table[entry*valuesPerEntry+1] = hashString(name, hashTypeNameB)
+		table[entry*valuesPerEntry+2] = neutralLocale
+		table[entry*valuesPerEntry+3] = uint32(blockIdx)
+	}
+
+	return table
+}
+
+// tableBytes encrypts the given table values and returns them as bytes
+func tableBytes(values []uint32, key uint32) []byte {
+	encrypted := make([]uint32, len(values))
+	copy(encrypted, values)
+	encrypt(encrypted, key)
+
+	result := make([]byte, len(encrypted)*4)
+	for idx, value := range encrypted {
+		binary.LittleEndian.PutUint32(result[idx*4:], value)
+	}
+
+	return result
+}
+
+// compressSectors splits the data into sectors and compresses each of them; the result is prefixed
+// with the sector offset table. Sectors which do not get smaller are stored uncompressed.
+func compressSectors(data []byte) ([]byte, error) {
+	sectorSize := SectorSize()
+	numSectors := (len(data) + sectorSize - 1) / sectorSize
+
+	offsets := make([]uint32, numSectors+1)
+	body := new(bytes.Buffer)
+	offsetTableSize := uint32(len(offsets) * 4)
+
+	for sector := 0; sector < numSectors; sector++ {
+		offsets[sector] = offsetTableSize + uint32(body.Len())
+
+		start := sector * sectorSize
+		end := start + sectorSize
+
+		if end > len(data) {
+			end = len(data)
+		}
+
+		raw := data[start:end]
+
+		compressed := new(bytes.Buffer)
+		compressed.WriteByte(compressionMaskZLib)
+
+		zw := zlib.NewWriter(compressed)
+
+		if _, err := zw.Write(raw); err != nil {
+			return nil, err
+		}
+
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+
+		if compressed.Len() < len(raw) {
+			body.Write(compressed.Bytes())
+		} else {
+			body.Write(raw)
+		}
+	}
+
+	offsets[numSectors] = offsetTableSize + uint32(body.Len())
+
+	result := make([]byte, 0, int(offsetTableSize)+body.Len())
+
+	for _, offset := range offsets {
+		var buf [4]byte
+
+		binary.LittleEndian.PutUint32(buf[:], offset)
+		result = append(result, buf[:]...)
+	}
+
+	return append(result, body.Bytes()...), nil
+}
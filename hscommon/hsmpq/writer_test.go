@@ -0,0 +1,140 @@
+package hsmpq
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2mpq"
+)
+
+type testFile struct {
+	path        string
+	data        []byte
+	compression Compression
+}
+
+func testFiles() []testFile {
+	random := make([]byte, 3*SectorSize()+17)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	return []testFile{
+		{path: `data\global\excel\armor.txt`, data: []byte("name\tcode\r\nCap\tcap\r\n"), compression: CompressionNone},
+		{path: `data\global\ui\cursor.dc6`, data: bytes.Repeat([]byte("compressible "), SectorSize()), compression: CompressionZLib},
+		{path: `data\global\sfx\random.wav`, data: random, compression: CompressionNone},
+		// sectors which don't get smaller are stored as they are, inside of a compressed file
+		{path: `data\global\chars\random.dcc`, data: random, compression: CompressionZLib},
+		{path: `data\local\font\single.tbl`, data: []byte{42}, compression: CompressionZLib},
+	}
+}
+
+func writeTestArchive(t *testing.T, files []testFile) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "hsmpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	fileName := filepath.Join(dir, "test.mpq")
+
+	writer, err := Create(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		if err := writer.AddFile(file.path, file.data, file.compression); err != nil {
+			t.Fatalf("adding %s: %s", file.path, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return fileName
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	files := testFiles()
+
+	archive, err := d2mpq.FromFile(writeTestArchive(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = archive.Close() }()
+
+	for _, file := range files {
+		data, err := archive.ReadFile(file.path)
+		if err != nil {
+			t.Errorf("reading %s: %s", file.path, err)
+			continue
+		}
+
+		switch {
+		case len(data) != len(file.data):
+			t.Errorf("%s (%s): got %d bytes, expected %d", file.path, file.compression, len(data), len(file.data))
+		case !bytes.Equal(data, file.data):
+			t.Errorf("%s (%s) differs from offset %d", file.path, file.compression, firstDifference(data, file.data))
+		}
+	}
+
+	listFile, err := archive.ReadFile(ListFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := strings.Split(strings.TrimSuffix(string(listFile), listFileLineSep), listFileLineSep)
+	if len(names) != len(files) {
+		t.Fatalf("the listfile has %d names, expected %d", len(names), len(files))
+	}
+
+	for idx, file := range files {
+		if names[idx] != file.path {
+			t.Errorf("listfile entry %d is %s, expected %s", idx, names[idx], file.path)
+		}
+	}
+}
+
+func TestWriterRejectsDuplicates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hsmpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	writer, err := Create(filepath.Join(dir, "test.mpq"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer writer.Abort()
+
+	if err := writer.AddFile("data/global/a.txt", []byte("a"), CompressionNone); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.AddFile(`DATA\GLOBAL\A.TXT`, []byte("b"), CompressionZLib); err == nil {
+		t.Error("adding the same path twice should fail")
+	}
+}
+
+// firstDifference returns the offset of the first byte which differs between two slices of the same length
+func firstDifference(a, b []byte) int {
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return idx
+		}
+	}
+
+	return -1
+}
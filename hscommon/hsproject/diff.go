@@ -0,0 +1,256 @@
+package hsproject
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsarchive"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+// DiffStatus describes how a file differs between the two sides of a diff
+type DiffStatus string
+
+// Diff statuses
+const (
+	// DiffStatusAdded is used for files which only exist in the second source
+	DiffStatusAdded = DiffStatus("added")
+	// DiffStatusRemoved is used for files which only exist in the first source
+	DiffStatusRemoved = DiffStatus("removed")
+	// DiffStatusChanged is used for files whose content differs
+	DiffStatusChanged = DiffStatus("changed")
+	// DiffStatusError is used when one of the copies could not be read
+	DiffStatusError = DiffStatus("error")
+)
+
+// DiffSource is one side of a diff: a single MPQ, a set of MPQs or the project on top of its auxiliary MPQs.
+// When more than one source provides a file, the first one wins, like in the game.
+type DiffSource struct {
+	Name     string
+	archives *hsarchive.Registry
+	files    map[string]diffFile
+}
+
+// diffFile is a file of a diff source along with its path inside of the game's file system
+type diffFile struct {
+	path  string
+	entry *hscommon.PathEntry
+}
+
+// NewMPQDiffSource creates a diff source from MPQ files, the first one has the highest priority
+func NewMPQDiffSource(name string, archives *hsarchive.Registry, mpqPaths []string, config *hsconfig.Config) (*DiffSource, error) {
+	result := &DiffSource{
+		Name:     name,
+		archives: archives,
+		files:    make(map[string]diffFile),
+	}
+
+	for _, mpqPath := range mpqPaths {
+		mpq, err := archives.Open(mpqPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %w", mpqPath, err)
+		}
+
+		files, err := MPQFileList(archives, mpq, config)
+		if err != nil {
+			return nil, fmt.Errorf("could not list the files of %s: %w", mpqPath, err)
+		}
+
+		for _, fileName := range files {
+			if strings.TrimSpace(fileName) == "" {
+				continue
+			}
+
+			result.add(fileName, &hscommon.PathEntry{
+				Name:     fileName[strings.LastIndexAny(fileName, `\/`)+1:],
+				FullPath: fileName,
+				Source:   hscommon.PathEntrySourceMPQ,
+				MPQFile:  mpq.Path(),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// AuxiliaryDiffSource returns the project's auxiliary MPQs as a diff source.
+// The MPQs have to be loaded with ReloadAuxiliaryMPQs first.
+func (p *Project) AuxiliaryDiffSource(config *hsconfig.Config) (*DiffSource, error) {
+	mpqPaths := make([]string, 0, len(p.mpqs))
+
+	for _, mpq := range p.mpqs {
+		if mpq != nil {
+			mpqPaths = append(mpqPaths, mpq.Path())
+		}
+	}
+
+	return NewMPQDiffSource("Auxiliary MPQs", p.Archives(), mpqPaths, config)
+}
+
+// ProjectDiffSource returns the project's content and its auxiliary MPQs in load order as a diff source,
+// this is what the game sees when the project is exported
+func (p *Project) ProjectDiffSource(config *hsconfig.Config) *DiffSource {
+	result := &DiffSource{
+		Name:     p.ProjectName,
+		archives: p.Archives(),
+		files:    make(map[string]diffFile),
+	}
+
+	for _, file := range p.BuildVirtualFileSystem(config).Files() {
+		result.add(file.Path, file.Winner())
+	}
+
+	return result
+}
+
+func (d *DiffSource) add(path string, entry *hscommon.PathEntry) {
+	path = strings.ReplaceAll(path, "/", `\`)
+	key := strings.ToLower(path)
+
+	if _, found := d.files[key]; !found {
+		d.files[key] = diffFile{path: path, entry: entry}
+	}
+}
+
+func (d *DiffSource) read(entry *hscommon.PathEntry) ([]byte, error) {
+	if entry.Source == hscommon.PathEntrySourceProject {
+		return ioutil.ReadFile(filepath.Clean(entry.FullPath))
+	}
+
+	return d.archives.ReadFile(entry.MPQFile, entry.FullPath)
+}
+
+// DiffEntry describes a file which differs between the two sources
+type DiffEntry struct {
+	Path   string     `json:"path"`
+	Status DiffStatus `json:"status"`
+	// Type is the name of the file type, it is empty for unknown types
+	Type  string `json:"type,omitempty"`
+	SizeA int    `json:"size_a"`
+	SizeB int    `json:"size_b"`
+	// Summary describes the change, for known file types it is based on the decoded content
+	Summary string `json:"summary,omitempty"`
+
+	// EntryA and EntryB are the files of both sources, one of them is nil for added and removed files
+	EntryA *hscommon.PathEntry `json:"-"`
+	EntryB *hscommon.PathEntry `json:"-"`
+}
+
+// DiffReport lists the differences between two sources
+type DiffReport struct {
+	A         string      `json:"a"`
+	B         string      `json:"b"`
+	Identical int         `json:"identical"`
+	Entries   []DiffEntry `json:"entries"`
+	// Canceled is true if the diff was stopped before every file was compared
+	Canceled bool `json:"canceled,omitempty"`
+}
+
+// Count returns the number of entries with the given status
+func (r *DiffReport) Count(status DiffStatus) int {
+	result := 0
+
+	for idx := range r.Entries {
+		if r.Entries[idx].Status == status {
+			result++
+		}
+	}
+
+	return result
+}
+
+// Diff compares two sources, a is treated as the old and b as the new version.
+// progress is called after every file, the diff is canceled if it returns false.
+func Diff(a, b *DiffSource, progress func(done, total int) bool) *DiffReport {
+	result := &DiffReport{
+		A:       a.Name,
+		B:       b.Name,
+		Entries: make([]DiffEntry, 0),
+	}
+
+	keys := make([]string, 0, len(a.files)+len(b.files))
+
+	for key := range a.files {
+		keys = append(keys, key)
+	}
+
+	for key := range b.files {
+		if _, found := a.files[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for idx, key := range keys {
+		if entry, differs := compareEntries(a, b, key); differs {
+			result.Entries = append(result.Entries, entry)
+		} else {
+			result.Identical++
+		}
+
+		if progress != nil && !progress(idx+1, len(keys)) {
+			result.Canceled = true
+			break
+		}
+	}
+
+	return result
+}
+
+func compareEntries(a, b *DiffSource, key string) (entry DiffEntry, differs bool) {
+	fileA, inA := a.files[key]
+	fileB, inB := b.files[key]
+
+	entry = DiffEntry{EntryA: fileA.entry, EntryB: fileB.entry}
+
+	var dataA, dataB []byte
+
+	var errA, errB error
+
+	if inA {
+		entry.Path = fileA.path
+		dataA, errA = a.read(fileA.entry)
+		entry.SizeA = len(dataA)
+	}
+
+	if inB {
+		entry.Path = fileB.path
+		dataB, errB = b.read(fileB.entry)
+		entry.SizeB = len(dataB)
+	}
+
+	entry.Type = fileTypeName(entry.Path, dataA, dataB)
+
+	switch {
+	case errA != nil || errB != nil:
+		entry.Status = DiffStatusError
+		entry.Summary = fmt.Sprintf("could not read the file: %s", firstError(errA, errB))
+	case !inA:
+		entry.Status = DiffStatusAdded
+	case !inB:
+		entry.Status = DiffStatusRemoved
+	case bytes.Equal(dataA, dataB):
+		return entry, false
+	default:
+		entry.Status = DiffStatusChanged
+		entry.Summary = summarizeChange(entry.Path, dataA, dataB)
+	}
+
+	return entry, true
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
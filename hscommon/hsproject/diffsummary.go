@@ -0,0 +1,387 @@
+package hsproject
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2cof"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dcc"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2ds1"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dt1"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2tbl"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes"
+)
+
+// fileTypeName returns the name of the file type of a diffed file, the data of
+// either side is used to tell string tables from font tables
+func fileTypeName(path string, dataA, dataB []byte) (name string) {
+	defer func() {
+		// the TBL check panics on files shorter than its signature
+		if r := recover(); r != nil {
+			name = hsfiletypes.FileTypeTBL.String()
+		}
+	}()
+
+	data := dataB
+	if data == nil {
+		data = dataA
+	}
+
+	fileType, err := hsfiletypes.GetFileTypeFromExtension(filepath.Ext(path), &data)
+	if err != nil {
+		return ""
+	}
+
+	switch fileType {
+	case hsfiletypes.FileTypeTBLStringTable, hsfiletypes.FileTypeTBLFontTable:
+		return hsfiletypes.FileTypeTBL.String()
+	default:
+		return fileType.String()
+	}
+}
+
+// summarizeChange describes how a file changed. Files of known types are decoded with the same
+// decoders the editors use, other files (and files which fail to decode) only report their size.
+func summarizeChange(path string, dataA, dataB []byte) (summary string) {
+	defer func() {
+		// some of the decoders panic on malformed data
+		if r := recover(); r != nil {
+			summary = sizeSummary(dataA, dataB)
+		}
+	}()
+
+	fileType, err := hsfiletypes.GetFileTypeFromExtension(filepath.Ext(path), &dataB)
+	if err != nil {
+		return sizeSummary(dataA, dataB)
+	}
+
+	var result string
+
+	switch fileType {
+	case hsfiletypes.FileTypeText:
+		result = summarizeText(dataA, dataB)
+	case hsfiletypes.FileTypeTBLStringTable:
+		result, err = summarizeTBL(dataA, dataB)
+	case hsfiletypes.FileTypeDC6:
+		result, err = summarizeDC6(dataA, dataB)
+	case hsfiletypes.FileTypeDCC:
+		result, err = summarizeDCC(dataA, dataB)
+	case hsfiletypes.FileTypeCOF:
+		result, err = summarizeCOF(dataA, dataB)
+	case hsfiletypes.FileTypeDS1:
+		result, err = summarizeDS1(dataA, dataB)
+	case hsfiletypes.FileTypeDT1:
+		result, err = summarizeDT1(dataA, dataB)
+	case hsfiletypes.FileTypePalette:
+		result, err = summarizePalette(dataA, dataB)
+	case hsfiletypes.FileTypeFont:
+		result = "font settings changed"
+	}
+
+	if err != nil || result == "" {
+		return sizeSummary(dataA, dataB)
+	}
+
+	return result
+}
+
+func sizeSummary(dataA, dataB []byte) string {
+	if len(dataA) == len(dataB) {
+		return fmt.Sprintf("content changed (%d bytes)", len(dataB))
+	}
+
+	return fmt.Sprintf("size changed from %d to %d bytes", len(dataA), len(dataB))
+}
+
+// changes collects the parts of a summary, each part is only added if its values differ
+type changes []string
+
+func (c *changes) add(name string, a, b interface{}) {
+	if a != b {
+		*c = append(*c, fmt.Sprintf("%s %v -> %v", name, a, b))
+	}
+}
+
+func (c *changes) count(count int, format string) {
+	if count > 0 {
+		*c = append(*c, fmt.Sprintf(format, count))
+	}
+}
+
+func (c changes) String() string {
+	return strings.Join(c, ", ")
+}
+
+func summarizeTBL(dataA, dataB []byte) (string, error) {
+	tableA, err := d2tbl.LoadTextDictionary(dataA)
+	if err != nil {
+		return "", err
+	}
+
+	tableB, err := d2tbl.LoadTextDictionary(dataB)
+	if err != nil {
+		return "", err
+	}
+
+	added, removed, changed := 0, 0, 0
+
+	for key, valueA := range tableA {
+		valueB, found := tableB[key]
+
+		switch {
+		case !found:
+			removed++
+		case valueA != valueB:
+			changed++
+		}
+	}
+
+	for key := range tableB {
+		if _, found := tableA[key]; !found {
+			added++
+		}
+	}
+
+	result := changes{}
+	result.count(added, "%d keys added")
+	result.count(removed, "%d keys removed")
+	result.count(changed, "%d keys changed")
+
+	return result.String(), nil
+}
+
+// summarizeText compares the rows of tab separated tables (the excel files). Rows are matched by their
+// first column, which is the name or id in most of the tables.
+func summarizeText(dataA, dataB []byte) string {
+	headerA, rowsA := textRows(dataA)
+	headerB, rowsB := textRows(dataB)
+
+	added, removed, changed := 0, 0, 0
+
+	for key, rowA := range rowsA {
+		rowB, found := rowsB[key]
+
+		switch {
+		case !found:
+			removed++
+		case rowA != rowB:
+			changed++
+		}
+	}
+
+	for key := range rowsB {
+		if _, found := rowsA[key]; !found {
+			added++
+		}
+	}
+
+	result := changes{}
+
+	if headerA != headerB {
+		result = append(result, "columns changed")
+	}
+
+	result.count(added, "%d rows added")
+	result.count(removed, "%d rows removed")
+	result.count(changed, "%d rows changed")
+
+	if len(result) == 0 {
+		// only the order of the rows or the line endings changed
+		return "rows reordered or reformatted"
+	}
+
+	return result.String()
+}
+
+// textRows splits a table into its header and its rows, keyed by their first column. Rows sharing
+// a first column (e.g. the "Expansion" separator rows) are told apart by their occurrence.
+func textRows(data []byte) (header string, rows map[string]string) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	rows = make(map[string]string, len(lines))
+	occurrences := make(map[string]int)
+
+	for idx, line := range lines {
+		if idx == 0 {
+			header = line
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		name := line
+		if tab := strings.IndexByte(line, '\t'); tab >= 0 {
+			name = line[:tab]
+		}
+
+		occurrences[name]++
+		rows[fmt.Sprintf("%s#%d", name, occurrences[name])] = line
+	}
+
+	return header, rows
+}
+
+func summarizeDC6(dataA, dataB []byte) (string, error) {
+	dc6A, err := d2dc6.Load(dataA)
+	if err != nil {
+		return "", err
+	}
+
+	dc6B, err := d2dc6.Load(dataB)
+	if err != nil {
+		return "", err
+	}
+
+	result := changes{}
+	result.add("directions", dc6A.Directions, dc6B.Directions)
+	result.add("frames per direction", dc6A.FramesPerDirection, dc6B.FramesPerDirection)
+
+	if len(dc6A.Frames) == len(dc6B.Frames) {
+		changed := 0
+
+		for idx := range dc6A.Frames {
+			a, b := dc6A.Frames[idx], dc6B.Frames[idx]
+			if a.Width != b.Width || a.Height != b.Height || a.OffsetX != b.OffsetX || a.OffsetY != b.OffsetY ||
+				!bytes.Equal(a.FrameData, b.FrameData) {
+				changed++
+			}
+		}
+
+		result.count(changed, "%d frames changed")
+	}
+
+	return result.String(), nil
+}
+
+func summarizeDCC(dataA, dataB []byte) (string, error) {
+	dccA, err := d2dcc.Load(dataA)
+	if err != nil {
+		return "", err
+	}
+
+	dccB, err := d2dcc.Load(dataB)
+	if err != nil {
+		return "", err
+	}
+
+	result := changes{}
+	result.add("directions", dccA.NumberOfDirections, dccB.NumberOfDirections)
+	result.add("frames per direction", dccA.FramesPerDirection, dccB.FramesPerDirection)
+
+	if len(result) == 0 {
+		return "frame data changed", nil
+	}
+
+	return result.String(), nil
+}
+
+func summarizeCOF(dataA, dataB []byte) (string, error) {
+	cofA, err := d2cof.Load(dataA)
+	if err != nil {
+		return "", err
+	}
+
+	cofB, err := d2cof.Load(dataB)
+	if err != nil {
+		return "", err
+	}
+
+	result := changes{}
+	result.add("layers", cofA.NumberOfLayers, cofB.NumberOfLayers)
+	result.add("directions", cofA.NumberOfDirections, cofB.NumberOfDirections)
+	result.add("frames per direction", cofA.FramesPerDirection, cofB.FramesPerDirection)
+	result.add("speed", cofA.Speed, cofB.Speed)
+
+	if len(result) == 0 {
+		return "layer settings or draw order changed", nil
+	}
+
+	return result.String(), nil
+}
+
+func summarizeDS1(dataA, dataB []byte) (string, error) {
+	ds1A, err := d2ds1.LoadDS1(dataA)
+	if err != nil {
+		return "", err
+	}
+
+	ds1B, err := d2ds1.LoadDS1(dataB)
+	if err != nil {
+		return "", err
+	}
+
+	result := changes{}
+	result.add("act", ds1A.Act, ds1B.Act)
+	result.add("size", fmt.Sprintf("%dx%d", ds1A.Width, ds1A.Height), fmt.Sprintf("%dx%d", ds1B.Width, ds1B.Height))
+	result.add("files", len(ds1A.Files), len(ds1B.Files))
+	result.add("objects", len(ds1A.Objects), len(ds1B.Objects))
+
+	if len(result) == 0 {
+		return "tiles changed", nil
+	}
+
+	return result.String(), nil
+}
+
+func summarizeDT1(dataA, dataB []byte) (string, error) {
+	dt1A, err := d2dt1.LoadDT1(dataA)
+	if err != nil {
+		return "", err
+	}
+
+	dt1B, err := d2dt1.LoadDT1(dataB)
+	if err != nil {
+		return "", err
+	}
+
+	result := changes{}
+	result.add("tiles", len(dt1A.Tiles), len(dt1B.Tiles))
+
+	if len(result) == 0 {
+		return "tile data changed", nil
+	}
+
+	return result.String(), nil
+}
+
+func summarizePalette(dataA, dataB []byte) (string, error) {
+	paletteA, err := d2dat.Load(dataA)
+	if err != nil {
+		return "", err
+	}
+
+	paletteB, err := d2dat.Load(dataB)
+	if err != nil {
+		return "", err
+	}
+
+	if paletteA.NumColors() != paletteB.NumColors() {
+		return fmt.Sprintf("colors %d -> %d", paletteA.NumColors(), paletteB.NumColors()), nil
+	}
+
+	changed := 0
+
+	for idx := 0; idx < paletteA.NumColors(); idx++ {
+		colorA, colorErr := paletteA.GetColor(idx)
+		if colorErr != nil {
+			return "", colorErr
+		}
+
+		colorB, colorErr := paletteB.GetColor(idx)
+		if colorErr != nil {
+			return "", colorErr
+		}
+
+		if colorA.RGBA() != colorB.RGBA() {
+			changed++
+		}
+	}
+
+	return fmt.Sprintf("%d colors changed", changed), nil
+}
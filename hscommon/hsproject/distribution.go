@@ -0,0 +1,484 @@
+package hsproject
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsutil"
+)
+
+// ManifestFileName is the name of the manifest written into every distribution
+const ManifestFileName = "hellspawner-manifest.json"
+
+// DistributionFormat is the layout of an exported distribution
+type DistributionFormat int
+
+// Distribution formats
+const (
+	// DistributionDirectory writes loose files into a directory
+	DistributionDirectory DistributionFormat = iota
+	// DistributionZip writes a zip archive
+	DistributionZip
+)
+
+// editorOnlyExtensions returns the extensions of files which are only used by HellSpawner itself
+func editorOnlyExtensions() []string {
+	return []string{".hsf"}
+}
+
+// DistributionOptions controls how ExportDistribution lays out the exported files
+type DistributionOptions struct {
+	Format DistributionFormat
+	// Lowercase converts every path to lower case, for engines on case sensitive file systems
+	Lowercase bool
+	// DataPrefix places the files under data/, the way they are stored in the MPQs
+	DataPrefix bool
+	// IncludeEditorFiles exports files which are only used by HellSpawner, such as fonts (.hsf)
+	IncludeEditorFiles bool
+}
+
+// ManifestFile is a single file of a distribution manifest
+type ManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists the files of a distribution along with their hashes
+type Manifest struct {
+	Project string         `json:"project"`
+	Files   []ManifestFile `json:"files"`
+}
+
+// distributionPath converts a path relative to the content directory into the path of the exported file
+func distributionPath(relPath string, options DistributionOptions) string {
+	result := strings.Trim(strings.ReplaceAll(relPath, `\`, "/"), "/")
+
+	if options.DataPrefix {
+		result = archiveDataDir + "/" + result
+	}
+
+	if options.Lowercase {
+		result = strings.ToLower(result)
+	}
+
+	return result
+}
+
+// distributionWriter writes the files of a distribution to a directory or a zip archive
+type distributionWriter interface {
+	addFile(name string, data []byte) error
+	close() error
+	abort()
+}
+
+// ExportDistribution writes the project's content into a directory or a zip archive, the way engines loading
+// loose files expect them. Editor-only files are skipped unless requested, and a manifest with the hashes
+// of the exported files (see VerifyDistribution) is written along with them.
+func (p *Project) ExportDistribution(target string, options DistributionOptions) (*Manifest, error) {
+	files, err := p.GetContentFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Project: p.ProjectName,
+		Files:   make([]ManifestFile, 0, len(files)),
+	}
+
+	// several files may end up at the same path when the paths are converted to lower case,
+	// and no file may replace the manifest
+	exported := map[string]string{strings.ToLower(ManifestFileName): "the manifest"}
+
+	var writer distributionWriter
+
+	if options.Format == DistributionZip {
+		writer, err = newZipDistribution(target)
+	} else {
+		writer, err = newDirectoryDistribution(target)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, relPath := range files {
+		if !options.IncludeEditorFiles && isEditorOnlyFile(relPath) {
+			continue
+		}
+
+		name := distributionPath(relPath, options)
+
+		if other, found := exported[strings.ToLower(name)]; found {
+			writer.abort()
+			return nil, fmt.Errorf("%s and %s would be exported to the same path %s", other, relPath, name)
+		}
+
+		exported[strings.ToLower(name)] = relPath
+
+		data, readErr := ioutil.ReadFile(filepath.Join(p.GetProjectFileContentPath(), filepath.FromSlash(relPath)))
+		if readErr != nil {
+			writer.abort()
+			return nil, readErr
+		}
+
+		if addErr := writer.addFile(name, data); addErr != nil {
+			writer.abort()
+			return nil, addErr
+		}
+
+		manifest.Files = append(manifest.Files, ManifestFile{Path: name, Size: int64(len(data)), SHA256: hashData(data)})
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+
+	data, err := json.MarshalIndent(manifest, "", "   ")
+	if err != nil {
+		writer.abort()
+		return nil, err
+	}
+
+	if err := writer.addFile(ManifestFileName, data); err != nil {
+		writer.abort()
+		return nil, err
+	}
+
+	if err := writer.close(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func isEditorOnlyFile(relPath string) bool {
+	for _, extension := range editorOnlyExtensions() {
+		if strings.EqualFold(path.Ext(relPath), extension) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hashData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// directoryDistribution writes loose files. The files of a previous export, listed in its manifest, are removed
+// first, so that the files deleted from the project don't stay in the distribution. Other files are kept.
+type directoryDistribution struct {
+	dir string
+}
+
+func newDirectoryDistribution(dir string) (*directoryDistribution, error) {
+	if err := os.MkdirAll(dir, os.FileMode(newDirMode)); err != nil {
+		return nil, err
+	}
+
+	if err := removePreviousDistribution(dir); err != nil {
+		return nil, err
+	}
+
+	// the manifest of a previous export must not outlive a failed export
+	if err := os.Remove(filepath.Join(dir, ManifestFileName)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return &directoryDistribution{dir: dir}, nil
+}
+
+// removePreviousDistribution removes the files listed in the manifest of the directory, along with the
+// directories which became empty
+func removePreviousDistribution(dir string) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFileName))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("could not read the manifest of the previous export: %w", err)
+	}
+
+	parents := make(map[string]bool)
+
+	for _, file := range manifest.Files {
+		fileName := filepath.Join(dir, filepath.FromSlash(file.Path))
+
+		// the manifest may have been edited, nothing outside of the distribution is removed
+		if !hsutil.IsInsideDir(fileName, dir) {
+			continue
+		}
+
+		if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		for parent := filepath.Dir(fileName); parent != dir && hsutil.IsInsideDir(parent, dir); parent = filepath.Dir(parent) {
+			parents[parent] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(parents))
+	for parent := range parents {
+		sorted = append(sorted, parent)
+	}
+
+	// the deepest directories first, removing a directory which isn't empty fails and keeps it
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	for _, parent := range sorted {
+		_ = os.Remove(parent)
+	}
+
+	return nil
+}
+
+func (d *directoryDistribution) addFile(name string, data []byte) error {
+	fileName := filepath.Join(d.dir, filepath.FromSlash(name))
+
+	if err := os.MkdirAll(filepath.Dir(fileName), os.FileMode(newDirMode)); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(fileName, data, os.FileMode(newFileMode))
+}
+
+func (d *directoryDistribution) close() error {
+	return nil
+}
+
+func (d *directoryDistribution) abort() {
+	// the files written so far are kept, the missing manifest shows that the export is incomplete
+}
+
+// zipDistribution writes a zip archive. It is written to a temporary file first,
+// so an existing archive is only replaced once the export succeeded.
+type zipDistribution struct {
+	fileName string
+	file     *os.File
+	writer   *zip.Writer
+}
+
+func newZipDistribution(fileName string) (*zipDistribution, error) {
+	file, err := ioutil.TempFile(filepath.Dir(fileName), filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &zipDistribution{fileName: fileName, file: file, writer: zip.NewWriter(file)}, nil
+}
+
+func (z *zipDistribution) addFile(name string, data []byte) error {
+	writer, err := z.writer.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(data)
+
+	return err
+}
+
+func (z *zipDistribution) close() error {
+	if err := z.writer.Close(); err != nil {
+		z.abort()
+		return err
+	}
+
+	if err := z.file.Close(); err != nil {
+		z.abort()
+		return err
+	}
+
+	// temporary files are only accessible by their owner
+	if err := os.Chmod(z.file.Name(), os.FileMode(newFileMode)); err != nil {
+		z.abort()
+		return err
+	}
+
+	if err := os.Rename(z.file.Name(), z.fileName); err != nil {
+		z.abort()
+		return err
+	}
+
+	return nil
+}
+
+func (z *zipDistribution) abort() {
+	// the errors don't matter, the file is removed anyway
+	_ = z.file.Close()
+
+	if err := os.Remove(z.file.Name()); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove %s: %s", z.file.Name(), err)
+	}
+}
+
+// DistributionProblem is a difference between a distribution and its manifest
+type DistributionProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// VerificationReport is the result of VerifyDistribution
+type VerificationReport struct {
+	Project  string                `json:"project"`
+	Checked  int                   `json:"checked"`
+	Problems []DistributionProblem `json:"problems"`
+}
+
+// VerifyDistribution checks the files of an exported directory or zip archive against its manifest.
+// Files which are missing, modified or not listed in the manifest are reported.
+func VerifyDistribution(target string) (*VerificationReport, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var files map[string]func() ([]byte, error)
+
+	if info.IsDir() {
+		files, err = directoryFiles(target)
+	} else {
+		var reader *zip.ReadCloser
+
+		if reader, err = zip.OpenReader(target); err != nil {
+			return nil, err
+		}
+
+		defer func() {
+			if closeErr := reader.Close(); closeErr != nil {
+				log.Print(closeErr)
+			}
+		}()
+
+		files = zipFiles(&reader.Reader)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	readManifest, found := files[ManifestFileName]
+	if !found {
+		return nil, fmt.Errorf("%s does not contain %s", target, ManifestFileName)
+	}
+
+	data, err := readManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if jsonErr := json.Unmarshal(data, &manifest); jsonErr != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", jsonErr)
+	}
+
+	report := &VerificationReport{
+		Project:  manifest.Project,
+		Problems: make([]DistributionProblem, 0),
+	}
+
+	addProblem := func(path, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, DistributionProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	listed := map[string]bool{ManifestFileName: true}
+
+	for _, file := range manifest.Files {
+		listed[file.Path] = true
+		report.Checked++
+
+		read, exists := files[file.Path]
+		if !exists {
+			addProblem(file.Path, "missing")
+			continue
+		}
+
+		content, readErr := read()
+
+		switch {
+		case readErr != nil:
+			addProblem(file.Path, "could not be read: %s", readErr)
+		case int64(len(content)) != file.Size:
+			addProblem(file.Path, "size is %d bytes instead of %d", len(content), file.Size)
+		case hashData(content) != file.SHA256:
+			addProblem(file.Path, "modified")
+		}
+	}
+
+	for name := range files {
+		if !listed[name] {
+			addProblem(name, "not listed in the manifest")
+		}
+	}
+
+	sort.SliceStable(report.Problems, func(i, j int) bool { return report.Problems[i].Path < report.Problems[j].Path })
+
+	return report, nil
+}
+
+// directoryFiles lists the files of an exported directory, keyed by their slash separated path
+func directoryFiles(dir string) (map[string]func() ([]byte, error), error) {
+	result := make(map[string]func() ([]byte, error))
+
+	err := filepath.Walk(dir, func(fileName string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relPath, relErr := filepath.Rel(dir, fileName)
+		if relErr != nil {
+			return relErr
+		}
+
+		result[filepath.ToSlash(relPath)] = func() ([]byte, error) {
+			return ioutil.ReadFile(filepath.Clean(fileName))
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// zipFiles lists the files of an exported zip archive
+func zipFiles(reader *zip.Reader) map[string]func() ([]byte, error) {
+	result := make(map[string]func() ([]byte, error))
+
+	for _, file := range reader.File {
+		if strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+
+		file := file
+		result[file.Name] = func() ([]byte, error) {
+			rc, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+
+			data, err := ioutil.ReadAll(rc)
+			if closeErr := rc.Close(); err == nil {
+				err = closeErr
+			}
+
+			return data, err
+		}
+	}
+
+	return result
+}
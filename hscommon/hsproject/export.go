@@ -0,0 +1,140 @@
+package hsproject
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsmpq"
+)
+
+// CompressionSelector decides how a file is stored in an exported MPQ, based on its path inside of the archive
+type CompressionSelector func(archivePath string) hsmpq.Compression
+
+// DefaultMPQCompression compresses everything with zlib, except for audio files
+// which gain almost nothing from it and are streamed by the game
+func DefaultMPQCompression(archivePath string) hsmpq.Compression {
+	if strings.EqualFold(filepath.Ext(archivePath), ".wav") {
+		return hsmpq.CompressionNone
+	}
+
+	return hsmpq.CompressionZLib
+}
+
+// ZLibMPQCompression compresses every file with zlib
+func ZLibMPQCompression(string) hsmpq.Compression {
+	return hsmpq.CompressionZLib
+}
+
+// StoredMPQCompression stores every file uncompressed
+func StoredMPQCompression(string) hsmpq.Compression {
+	return hsmpq.CompressionNone
+}
+
+// MPQCompressionMode is a named compression selector, which can be chosen when a project is exported
+type MPQCompressionMode struct {
+	Name   string
+	Select CompressionSelector
+}
+
+// MPQCompressionModes returns the compression modes offered by the exports, the default mode comes first
+func MPQCompressionModes() []MPQCompressionMode {
+	return []MPQCompressionMode{
+		{Name: "ZLib, audio stored", Select: DefaultMPQCompression},
+		{Name: "ZLib", Select: ZLibMPQCompression},
+		{Name: "Stored (no compression)", Select: StoredMPQCompression},
+	}
+}
+
+// ErrExportCanceled is returned when an export was canceled by its progress function
+var ErrExportCanceled = errors.New("the export was canceled")
+
+// ExportMPQ builds an MPQ archive at the given path containing every file in the project's content directory.
+// Files are placed in the archive under the data directory, e.g. content/global/ui/cursor.dc6 is stored
+// as data\global\ui\cursor.dc6. If compression is nil, DefaultMPQCompression is used.
+// progress is called after every file, the export is canceled and the archive removed if it returns false.
+func (p *Project) ExportMPQ(fileName string, compression CompressionSelector, progress func(done, total int) bool) error {
+	if compression == nil {
+		compression = DefaultMPQCompression
+	}
+
+	files, err := p.GetContentFiles()
+	if err != nil {
+		return err
+	}
+
+	writer, err := hsmpq.Create(fileName)
+	if err != nil {
+		return err
+	}
+
+	for idx, relPath := range files {
+		data, err := ioutil.ReadFile(filepath.Join(p.GetProjectFileContentPath(), relPath))
+		if err != nil {
+			writer.Abort()
+			return err
+		}
+
+		archivePath := ArchivePathFromContentPath(relPath)
+
+		if err := writer.AddFile(archivePath, data, compression(archivePath)); err != nil {
+			writer.Abort()
+			return err
+		}
+
+		if progress != nil && !progress(idx+1, len(files)) {
+			writer.Abort()
+			return ErrExportCanceled
+		}
+	}
+
+	return writer.Close()
+}
+
+// GetContentFiles returns the paths (relative to the content directory) of every file in the project.
+// Hidden files and directories (starting with a dot) are skipped, the same way the project explorer does.
+func (p *Project) GetContentFiles() ([]string, error) {
+	contentPath := p.GetProjectFileContentPath()
+	result := make([]string, 0)
+
+	err := filepath.Walk(contentPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == contentPath {
+			return nil
+		}
+
+		if info.Name()[0] == '.' || path == p.filePath {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(contentPath, path)
+		if err != nil {
+			return err
+		}
+
+		result = append(result, filepath.ToSlash(relPath))
+
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("failed to walk project content %s: %s", contentPath, err)
+		return nil, err
+	}
+
+	return result, nil
+}
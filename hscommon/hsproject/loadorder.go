@@ -0,0 +1,149 @@
+package hsproject
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+// ContentFolderName is the name of the project's content folder in the load order
+const ContentFolderName = "Project"
+
+// LoadOrderEntry is a source of the project's load order, either the content folder or an auxiliary MPQ
+type LoadOrderEntry struct {
+	// Name is ContentFolderName, or the path of the MPQ relative to the auxiliary MPQ directory
+	Name string
+	// Archive is the auxiliary MPQ, it is nil for the content folder and for MPQs which haven't been loaded
+	Archive d2interface.Archive
+}
+
+// IsContentFolder returns true if the entry is the project's content folder
+func (l *LoadOrderEntry) IsContentFolder() bool {
+	return l.Name == ContentFolderName && l.Archive == nil
+}
+
+// contentPosition returns ContentPosition, limited to the positions that exist
+func (p *Project) contentPosition() int {
+	switch {
+	case p.ContentPosition < 0:
+		return 0
+	case p.ContentPosition > len(p.AuxiliaryMPQs):
+		return len(p.AuxiliaryMPQs)
+	default:
+		return p.ContentPosition
+	}
+}
+
+// LoadOrder returns the sources of the project's files, the first source containing a file provides it.
+// The auxiliary MPQs are listed in the order of AuxiliaryMPQs, with the content folder at ContentPosition.
+func (p *Project) LoadOrder() []LoadOrderEntry {
+	result := make([]LoadOrderEntry, 0, len(p.AuxiliaryMPQs)+1)
+	contentPosition := p.contentPosition()
+
+	for idx, mpqName := range p.AuxiliaryMPQs {
+		if idx == contentPosition {
+			result = append(result, LoadOrderEntry{Name: ContentFolderName})
+		}
+
+		entry := LoadOrderEntry{Name: mpqName}
+		if idx < len(p.mpqs) {
+			entry.Archive = p.mpqs[idx]
+		}
+
+		result = append(result, entry)
+	}
+
+	if contentPosition == len(p.AuxiliaryMPQs) {
+		result = append(result, LoadOrderEntry{Name: ContentFolderName})
+	}
+
+	return result
+}
+
+// LoadOrderNames returns the names of the sources of the load order
+func (p *Project) LoadOrderNames() []string {
+	order := p.LoadOrder()
+
+	result := make([]string, len(order))
+	for idx := range order {
+		result[idx] = order[idx].Name
+	}
+
+	return result
+}
+
+// LoadOrderPaths returns the absolute paths of the sources of the load order: the content folder and the auxiliary MPQs
+func (p *Project) LoadOrderPaths(config *hsconfig.Config) []string {
+	order := p.LoadOrder()
+
+	result := make([]string, len(order))
+
+	for idx := range order {
+		if order[idx].IsContentFolder() {
+			result[idx] = p.GetProjectFileContentPath()
+			continue
+		}
+
+		result[idx] = filepath.Join(config.AuxiliaryMpqPath, order[idx].Name)
+	}
+
+	return result
+}
+
+// ResolveFile returns the path entry providing a file of the game's file system (e.g. data\global\palette\act1\pal.dat),
+// by searching the sources of the load order. It returns nil if no source contains the file.
+// The auxiliary MPQs have to be loaded with ReloadAuxiliaryMPQs first.
+func (p *Project) ResolveFile(path string) *hscommon.PathEntry {
+	path = strings.ReplaceAll(path, "/", `\`)
+	name := path[strings.LastIndex(path, `\`)+1:]
+
+	for _, source := range p.LoadOrder() {
+		if source.IsContentFolder() {
+			fullPath := filepath.Join(p.GetProjectFileContentPath(), filepath.FromSlash(ContentPathFromArchivePath(path)))
+
+			if info, err := os.Stat(fullPath); err == nil && !info.IsDir() {
+				return &hscommon.PathEntry{
+					Name:     name,
+					FullPath: fullPath,
+					Source:   hscommon.PathEntrySourceProject,
+				}
+			}
+
+			continue
+		}
+
+		if source.Archive == nil || !p.Archives().Contains(source.Archive.Path(), path) {
+			continue
+		}
+
+		return &hscommon.PathEntry{
+			Name:     name,
+			FullPath: path,
+			Source:   hscommon.PathEntrySourceMPQ,
+			MPQFile:  source.Archive.Path(),
+		}
+	}
+
+	return nil
+}
+
+// ReadResolvedFile reads a file of the game's file system from the first source of the load order containing it
+func (p *Project) ReadResolvedFile(path string) ([]byte, *hscommon.PathEntry, error) {
+	entry := p.ResolveFile(path)
+	if entry == nil {
+		return nil, nil, errors.New("file not found in the project or the auxiliary MPQs")
+	}
+
+	data, err := entry.GetFileBytes()
+	if err != nil {
+		return nil, entry, err
+	}
+
+	return data, entry, nil
+}
@@ -0,0 +1,480 @@
+package hsproject
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes/hsfont"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsreference"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+const (
+	fontExtension = ".hsf"
+	ds1Extension  = ".ds1"
+	dt1Extension  = ".dt1"
+	dccExtension  = ".dcc"
+	cofExtension  = ".cof"
+)
+
+// layout of the header of a DS1 file, up to its file table
+const (
+	ds1FieldSize           = 4
+	ds1FileTableVersion    = 3
+	ds1ActVersion          = 8
+	ds1SubstitutionVersion = 10
+	// version, width and height
+	ds1HeaderFields = 3
+)
+
+// MoveReference is a reference to a moved file which is rewritten by ApplyMove
+type MoveReference struct {
+	// Path is the file containing the reference, as it is named before the move
+	Path   string
+	Kind   hsreference.Kind
+	Detail string
+	// OldTarget and NewTarget are the referenced path, as stored in the file
+	OldTarget string
+	NewTarget string
+}
+
+// MovePlan describes how moving a file or folder of the project affects the rest of the project.
+// It is created by PlanMove, so it can be previewed, and carried out by ApplyMove.
+type MovePlan struct {
+	OldPath string
+	NewPath string
+	// Files maps the absolute paths of the moved files to their new paths
+	Files map[string]string
+	// References lists the references which are rewritten, sorted by the file containing them
+	References []MoveReference
+	// EditorStates is the number of saved editor windows of the project which are updated
+	EditorStates int
+	// Warnings lists the effects of the move which can't be fixed automatically
+	Warnings []string
+
+	// rewrites holds the new content of the files containing references, keyed by their path before the move
+	rewrites map[string][]byte
+}
+
+// IsEmpty returns true if the move doesn't affect anything besides the moved files
+func (m *MovePlan) IsEmpty() bool {
+	return len(m.References) == 0 && m.EditorStates == 0 && len(m.Warnings) == 0
+}
+
+// newPathOf returns the path a file has after the move, files which aren't moved keep their path
+func (m *MovePlan) newPathOf(path string) (string, bool) {
+	for oldPath, newPath := range m.Files {
+		if strings.EqualFold(filepath.Clean(path), oldPath) {
+			return newPath, true
+		}
+	}
+
+	return path, false
+}
+
+// PlanMove works out what moving (or renaming) a file or folder of the project's content directory
+// from oldPath to newPath affects. Fonts and DS1 files pointing at the moved files, and the saved editor windows
+// of the project in the config, are updated when the plan is applied with ApplyMove.
+func (p *Project) PlanMove(oldPath, newPath string, config *hsconfig.Config) (*MovePlan, error) {
+	oldPath, newPath = filepath.Clean(oldPath), filepath.Clean(newPath)
+	contentPath := p.GetProjectFileContentPath()
+
+	if !isInsideAny(oldPath, []string{contentPath}) || oldPath == contentPath || oldPath == p.filePath {
+		return nil, errors.New("only files and folders of the project's content can be moved")
+	}
+
+	if !isInsideAny(newPath, []string{contentPath}) || newPath == contentPath {
+		return nil, errors.New("files can only be moved inside of the project's content")
+	}
+
+	// hidden files are not part of the project
+	if strings.HasPrefix(filepath.Base(newPath), ".") {
+		return nil, errors.New("the new name must not start with a dot")
+	}
+
+	oldInfo, err := os.Stat(oldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// a rename which only changes the case finds the file itself on case insensitive file systems
+	if newInfo, statErr := os.Stat(newPath); statErr == nil && !os.SameFile(oldInfo, newInfo) {
+		return nil, fmt.Errorf("%s already exists", newPath)
+	}
+
+	if oldInfo.IsDir() && isInsideAny(newPath, []string{oldPath}) {
+		return nil, errors.New("a folder can't be moved into itself")
+	}
+
+	plan := &MovePlan{
+		OldPath:    oldPath,
+		NewPath:    newPath,
+		Files:      make(map[string]string),
+		References: make([]MoveReference, 0),
+		Warnings:   make([]string, 0),
+		rewrites:   make(map[string][]byte),
+	}
+
+	if err := p.listMovedFiles(plan, oldInfo.IsDir()); err != nil {
+		return nil, err
+	}
+
+	if err := p.planReferences(plan); err != nil {
+		return nil, err
+	}
+
+	plan.EditorStates = len(p.movedEditorStates(plan, config))
+
+	sort.SliceStable(plan.References, func(i, j int) bool {
+		return strings.ToLower(plan.References[i].Path) < strings.ToLower(plan.References[j].Path)
+	})
+
+	return plan, nil
+}
+
+func (p *Project) listMovedFiles(plan *MovePlan, isDir bool) error {
+	if !isDir {
+		plan.Files[plan.OldPath] = plan.NewPath
+
+		if oldExt, newExt := filepath.Ext(plan.OldPath), filepath.Ext(plan.NewPath); !strings.EqualFold(oldExt, newExt) {
+			plan.Warnings = append(plan.Warnings,
+				fmt.Sprintf("the extension changes from %q to %q, which changes how the file is opened", oldExt, newExt))
+		}
+
+		return nil
+	}
+
+	return filepath.Walk(plan.OldPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relPath, relErr := filepath.Rel(plan.OldPath, path)
+		if relErr != nil {
+			return relErr
+		}
+
+		plan.Files[path] = filepath.Join(plan.NewPath, relPath)
+
+		return nil
+	})
+}
+
+// planReferences finds the references to the moved files. Fonts store the file system paths of their files,
+// DS1 files the game paths of their tiles. COF files find their layers by name, so those can't be rewritten.
+func (p *Project) planReferences(plan *MovePlan) error {
+	// the game paths of the moved files, keyed by their lower case path before the move
+	virtualPaths := make(map[string]string)
+	animations := 0
+
+	for oldPath, newPath := range plan.Files {
+		oldVirtual, newVirtual := p.VirtualPathFromFilePath(oldPath), p.VirtualPathFromFilePath(newPath)
+		virtualPaths[strings.ToLower(oldVirtual)] = newVirtual
+
+		switch strings.ToLower(filepath.Ext(oldPath)) {
+		case dccExtension, cofExtension:
+			if !strings.EqualFold(oldVirtual, newVirtual) {
+				animations++
+			}
+		}
+	}
+
+	if animations > 0 {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("%d DCC or COF files are moved: COF files find their layers "+
+			"by file name and directory, so these references are not updated", animations))
+	}
+
+	files, err := p.GetContentFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, relPath := range files {
+		path := filepath.Join(p.GetProjectFileContentPath(), filepath.FromSlash(relPath))
+
+		var rewrite func(path string, data []byte) ([]byte, []MoveReference, error)
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case fontExtension:
+			rewrite = plan.rewriteFont
+		case ds1Extension:
+			rewrite = func(path string, data []byte) ([]byte, []MoveReference, error) {
+				return plan.rewriteDS1(path, data, virtualPaths)
+			}
+		default:
+			continue
+		}
+
+		data, readErr := ioutil.ReadFile(filepath.Clean(path))
+		if readErr != nil {
+			return readErr
+		}
+
+		result, references, rewriteErr := rewrite(path, data)
+		if rewriteErr != nil {
+			// files which can't be read by the editors don't reference anything either
+			log.Printf("failed to find references of %s: %s", path, rewriteErr)
+			continue
+		}
+
+		if len(references) == 0 {
+			continue
+		}
+
+		plan.References = append(plan.References, references...)
+		plan.rewrites[path] = result
+	}
+
+	return nil
+}
+
+func (m *MovePlan) rewriteFont(path string, data []byte) ([]byte, []MoveReference, error) {
+	font, err := hsfont.LoadFromJSON(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	references := make([]MoveReference, 0)
+
+	for _, target := range []struct {
+		path *string
+		kind hsreference.Kind
+	}{
+		{&font.SpriteFile, hsreference.KindFontSprite},
+		{&font.TableFile, hsreference.KindFontTable},
+		{&font.PaletteFile, hsreference.KindFontPalette},
+	} {
+		if *target.path == "" {
+			continue
+		}
+
+		newPath, moved := m.newPathOf(*target.path)
+		if !moved {
+			continue
+		}
+
+		references = append(references, MoveReference{
+			Path:      path,
+			Kind:      target.kind,
+			OldTarget: *target.path,
+			NewTarget: newPath,
+		})
+
+		*target.path = newPath
+	}
+
+	if len(references) == 0 {
+		return nil, nil, nil
+	}
+
+	result, err := font.JSON()
+
+	return result, references, err
+}
+
+func (m *MovePlan) rewriteDS1(path string, data []byte, virtualPaths map[string]string) ([]byte, []MoveReference, error) {
+	start, end, files, err := readDS1FileTable(data)
+	if err != nil || files == nil {
+		return nil, nil, err
+	}
+
+	references := make([]MoveReference, 0)
+
+	for idx, file := range files {
+		if file == "" {
+			continue
+		}
+
+		newTarget, moved := virtualPaths[strings.ToLower(hsreference.DS1TilePath(file))]
+		if !moved {
+			continue
+		}
+
+		if !strings.EqualFold(filepath.Ext(newTarget), dt1Extension) {
+			m.Warnings = append(m.Warnings,
+				fmt.Sprintf("%s: file %d can't point at %s, which is no longer a DT1 file", path, idx, newTarget))
+
+			continue
+		}
+
+		files[idx] = hsreference.RewriteDS1TilePath(file, newTarget)
+
+		references = append(references, MoveReference{
+			Path:      path,
+			Kind:      hsreference.KindTile,
+			Detail:    fmt.Sprintf("file %d", idx),
+			OldTarget: file,
+			NewTarget: files[idx],
+		})
+	}
+
+	if len(references) == 0 {
+		return nil, nil, nil
+	}
+
+	result := make([]byte, 0, len(data))
+	result = append(result, data[:start]...)
+
+	for _, file := range files {
+		result = append(result, file...)
+		result = append(result, 0)
+	}
+
+	result = append(result, data[end:]...)
+
+	return result, references, nil
+}
+
+// readDS1FileTable returns the start and end offset of the file table of a DS1 file, along with its entries.
+// Nothing follows the file table that depends on its size, so it can be replaced as a whole.
+// Versions which have no file table return nil files.
+func readDS1FileTable(data []byte) (start, end int, files []string, err error) {
+	if len(data) < ds1FieldSize {
+		return 0, 0, nil, errors.New("unexpected end of file")
+	}
+
+	version := int32(binary.LittleEndian.Uint32(data))
+	if version < ds1FileTableVersion {
+		return 0, 0, nil, nil
+	}
+
+	offset := ds1HeaderFields * ds1FieldSize
+
+	if version >= ds1ActVersion {
+		offset += ds1FieldSize
+	}
+
+	if version >= ds1SubstitutionVersion {
+		offset += ds1FieldSize
+	}
+
+	if len(data) < offset+ds1FieldSize {
+		return 0, 0, nil, errors.New("unexpected end of file")
+	}
+
+	count := int(int32(binary.LittleEndian.Uint32(data[offset:])))
+	start = offset + ds1FieldSize
+	end = start
+
+	if count < 0 {
+		return 0, 0, nil, fmt.Errorf("invalid number of files: %d", count)
+	}
+
+	files = make([]string, count)
+
+	for idx := range files {
+		length := strings.IndexByte(string(data[end:]), 0)
+		if length < 0 {
+			return 0, 0, nil, errors.New("unexpected end of file")
+		}
+
+		files[idx] = string(data[end : end+length])
+		end += length + 1
+	}
+
+	return start, end, files, nil
+}
+
+// movedEditorStates returns the saved editor windows of the project which show a moved file,
+// with their paths updated
+func (p *Project) movedEditorStates(plan *MovePlan, config *hsconfig.Config) map[int][]byte {
+	result := make(map[int][]byte)
+
+	if config == nil {
+		return result
+	}
+
+	state, found := config.ProjectStates[p.GetProjectFilePath()]
+	if !found {
+		return result
+	}
+
+	for idx := range state.EditorWindows {
+		var path hscommon.PathEntry
+
+		if err := json.Unmarshal(state.EditorWindows[idx].Path, &path); err != nil {
+			continue
+		}
+
+		if path.Source != hscommon.PathEntrySourceProject {
+			continue
+		}
+
+		newPath, moved := plan.newPathOf(path.FullPath)
+		if !moved {
+			continue
+		}
+
+		path.FullPath = newPath
+		path.Name = filepath.Base(newPath)
+
+		data, err := json.Marshal(&path)
+		if err != nil {
+			log.Print("failed to marshal editor path to JSON: ", err)
+			continue
+		}
+
+		result[idx] = data
+	}
+
+	return result
+}
+
+// ApplyMove moves the files of a plan created by PlanMove and rewrites the references to them.
+// The files containing references are recorded in the local history before they are rewritten.
+func (p *Project) ApplyMove(plan *MovePlan, config *hsconfig.Config) error {
+	if err := os.MkdirAll(filepath.Dir(plan.NewPath), os.FileMode(newDirMode)); err != nil {
+		return err
+	}
+
+	if err := os.Rename(plan.OldPath, plan.NewPath); err != nil {
+		return err
+	}
+
+	p.InvalidateFileStructure()
+
+	failed := make([]string, 0)
+
+	for oldPath, data := range plan.rewrites {
+		path, _ := plan.newPathOf(oldPath)
+
+		if historyErr := p.History().Record(path, "move"); historyErr != nil {
+			log.Print("failed to record local history: ", historyErr)
+		}
+
+		if err := ioutil.WriteFile(path, data, os.FileMode(newFileMode)); err != nil {
+			log.Printf("failed to update the references of %s: %s", path, err)
+			failed = append(failed, path)
+		}
+	}
+
+	if states := p.movedEditorStates(plan, config); len(states) > 0 {
+		state := config.ProjectStates[p.GetProjectFilePath()]
+
+		for idx, path := range states {
+			state.EditorWindows[idx].Path = path
+		}
+
+		config.ProjectStates[p.GetProjectFilePath()] = state
+
+		if err := config.Save(); err != nil {
+			log.Print("failed to save config: ", err)
+		}
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("the references in these files could not be updated:\n%s", strings.Join(failed, "\n"))
+	}
+
+	return nil
+}
@@ -13,8 +13,40 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
 
 	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsarchive"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsharvest"
 )
 
+const (
+	// archiveDataDir is the directory that contains all game data inside of the MPQs.
+	// The project's content directory corresponds to this directory.
+	archiveDataDir = "data"
+)
+
+// ArchivePathFromContentPath converts a path relative to the project's content directory
+// into the path the file has inside of an MPQ (global/ui/cursor.dc6 becomes data\global\ui\cursor.dc6)
+func ArchivePathFromContentPath(relPath string) string {
+	relPath = strings.Trim(strings.ReplaceAll(relPath, "/", "\\"), "\\")
+
+	return archiveDataDir + "\\" + relPath
+}
+
+// ContentPathFromArchivePath converts a path inside of an MPQ into a slash separated path
+// relative to the project's content directory (the leading data directory is removed)
+func ContentPathFromArchivePath(archivePath string) string {
+	result := strings.Trim(strings.ReplaceAll(archivePath, "\\", "/"), "/")
+
+	if strings.EqualFold(result, archiveDataDir) {
+		return ""
+	}
+
+	if len(result) > len(archiveDataDir) && strings.EqualFold(result[:len(archiveDataDir)+1], archiveDataDir+"/") {
+		result = result[len(archiveDataDir)+1:]
+	}
+
+	return result
+}
+
 // GetMPQFileNodes returns mpq's node
 func (p *Project) GetMPQFileNodes(mpq d2interface.Archive, config *hsconfig.Config) *hscommon.PathEntry {
 	result := &hscommon.PathEntry{
@@ -24,13 +56,9 @@ func (p *Project) GetMPQFileNodes(mpq d2interface.Archive, config *hsconfig.Conf
 		MPQFile:     mpq.Path(),
 	}
 
-	files, err := mpq.Listfile()
-
+	files, err := p.GetMPQFileList(mpq, config)
 	if err != nil {
-		files, err = p.searchForMpqFiles(mpq, config)
-		if err != nil {
-			return result
-		}
+		return result
 	}
 
 	pathNodes := make(map[string]*hscommon.PathEntry)
@@ -69,33 +97,104 @@ func (p *Project) GetMPQFileNodes(mpq d2interface.Archive, config *hsconfig.Conf
 	return result
 }
 
-// searchForMpqFiles searches for files in MPQ's without listfiles using a list of known filenames
-func (p *Project) searchForMpqFiles(mpq d2interface.Archive, config *hsconfig.Config) ([]string, error) {
-	var files []string
+// GetMPQFileList returns the paths of all known files inside of the mpq. If the mpq doesn't contain a listfile,
+// the names are taken from the external listfile of the config and harvested from the mpq's own files.
+func (p *Project) GetMPQFileList(mpq d2interface.Archive, config *hsconfig.Config) ([]string, error) {
+	return MPQFileList(p.Archives(), mpq, config)
+}
 
-	if config.ExternalListFile != "" {
-		file, err := os.Open(config.ExternalListFile)
-		if err != nil {
-			return files, errors.New("couldn't open listfile")
+// MPQFileList returns the paths of all known files inside of an mpq opened through the given registry,
+// it is used by GetMPQFileList and by tools working on MPQs without a project
+func MPQFileList(archives *hsarchive.Registry, mpq d2interface.Archive, config *hsconfig.Config) ([]string, error) {
+	files, err := archives.Listfile(mpq.Path())
+	if err == nil {
+		return files, nil
+	}
+
+	return archives.Names(mpq.Path(), func() []string {
+		return findMpqFiles(archives, mpq.Path(), config)
+	})
+}
+
+// registryArchive gives the harvester access to an mpq of the registry
+type registryArchive struct {
+	archives *hsarchive.Registry
+	mpqPath  string
+}
+
+func (r registryArchive) Contains(filePath string) bool {
+	return r.archives.Contains(r.mpqPath, filePath)
+}
+
+func (r registryArchive) ReadFile(filePath string) ([]byte, error) {
+	return r.archives.ReadFile(r.mpqPath, filePath)
+}
+
+// findMpqFiles finds the files of an mpq without listfile. The names of the external listfile are combined with
+// the names harvested from the mpq's files, which are cached, since harvesting a large mpq takes a while.
+func findMpqFiles(archives *hsarchive.Registry, mpqPath string, config *hsconfig.Config) []string {
+	archive := registryArchive{archives: archives, mpqPath: mpqPath}
+
+	external, err := readListFile(config.ExternalListFile)
+	if err != nil {
+		log.Printf("couldn't read the external listfile %s: %s", config.ExternalListFile, err)
+	}
+
+	cacheDir := hsconfig.ListfileCacheDir()
+
+	harvested, found := hsharvest.LoadCache(cacheDir, mpqPath)
+	if !found {
+		harvested = hsharvest.Harvest(archive, external)
+
+		if saveErr := hsharvest.SaveCache(cacheDir, mpqPath, harvested); saveErr != nil {
+			log.Printf("couldn't cache the file names of %s: %s", mpqPath, saveErr)
 		}
+	}
 
-		defer func() {
-			err := file.Close()
-			if err != nil {
-				log.Print(err)
-			}
-		}()
+	files := make([]string, 0, len(harvested))
+	known := make(map[string]bool, len(harvested))
 
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			fileName := scanner.Text()
-			if mpq.Contains(fileName) {
-				files = append(files, fileName)
-			}
+	for _, name := range harvested {
+		known[strings.ToLower(name)] = true
+		files = append(files, name)
+	}
+
+	for _, name := range external {
+		if !known[strings.ToLower(name)] && archive.Contains(name) {
+			known[strings.ToLower(name)] = true
+			files = append(files, name)
 		}
+	}
+
+	return files
+}
+
+// readListFile returns the names of a listfile, an empty file name returns no names
+func readListFile(fileName string) ([]string, error) {
+	var names []string
 
-		return files, scanner.Err()
+	if fileName == "" {
+		return names, nil
+	}
+
+	file, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return names, errors.New("couldn't open listfile")
+	}
+
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			log.Print(err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			names = append(names, name)
+		}
 	}
 
-	return files, nil
+	return names, scanner.Err()
 }
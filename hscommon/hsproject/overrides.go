@@ -0,0 +1,151 @@
+package hsproject
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+// OverrideStatus describes how the winning copy of a file relates to the copy it overrides
+type OverrideStatus string
+
+// Override statuses
+const (
+	// OverrideStatusProjectOnly is used for files which only exist in the project
+	OverrideStatusProjectOnly = OverrideStatus("project-only")
+	// OverrideStatusIdentical is used when the winning copy has the same bytes as the copy it overrides
+	OverrideStatusIdentical = OverrideStatus("identical")
+	// OverrideStatusModified is used when the winning copy differs from the copy it overrides
+	OverrideStatusModified = OverrideStatus("modified")
+	// OverrideStatusShadowed is used when the project's copy is hidden by an MPQ which comes first in the load order
+	OverrideStatusShadowed = OverrideStatus("shadowed")
+	// OverrideStatusError is used when one of the copies could not be read
+	OverrideStatusError = OverrideStatus("error")
+)
+
+// OverrideEntry describes a single path which is provided by the project and/or by more than one MPQ
+type OverrideEntry struct {
+	Path    string         `json:"path"`
+	Sources []string       `json:"sources"`
+	Winner  string         `json:"winner"`
+	Status  OverrideStatus `json:"status"`
+	Error   string         `json:"error,omitempty"`
+
+	// WinnerEntry is the path entry of the winning copy, it can be used to open the file in an editor
+	WinnerEntry *hscommon.PathEntry `json:"-"`
+}
+
+// OverrideReport lists every file that the project adds or overrides, as well as files
+// that exist in more than one auxiliary MPQ
+type OverrideReport struct {
+	ProjectName string          `json:"project_name"`
+	LoadOrder   []string        `json:"load_order"`
+	Entries     []OverrideEntry `json:"entries"`
+}
+
+// GenerateOverrideReport compares the project's files with the auxiliary MPQs loaded by ReloadAuxiliaryMPQs.
+// The winner of each path is compared with the copy it overrides (the next one in load order).
+func (p *Project) GenerateOverrideReport(config *hsconfig.Config) *OverrideReport {
+	vfs := p.BuildVirtualFileSystem(config)
+
+	result := &OverrideReport{
+		ProjectName: p.ProjectName,
+		LoadOrder:   p.LoadOrderNames(),
+		Entries:     make([]OverrideEntry, 0),
+	}
+
+	for _, file := range vfs.Files() {
+		winner := file.Winner()
+
+		if len(file.Sources) < 2 && winner.Source != hscommon.PathEntrySourceProject {
+			// only exists in a single mpq, nothing is overridden
+			continue
+		}
+
+		entry := OverrideEntry{
+			Path:        file.Path,
+			Sources:     make([]string, len(file.Sources)),
+			Winner:      winner.GetSourceName(),
+			WinnerEntry: winner,
+		}
+
+		for idx := range file.Sources {
+			entry.Sources[idx] = file.Sources[idx].GetSourceName()
+		}
+
+		if len(file.Sources) < 2 {
+			entry.Status = OverrideStatusProjectOnly
+			result.Entries = append(result.Entries, entry)
+
+			continue
+		}
+
+		if winner.Source != hscommon.PathEntrySourceProject && projectSource(file) != nil {
+			entry.Status = OverrideStatusShadowed
+			result.Entries = append(result.Entries, entry)
+
+			continue
+		}
+
+		same, err := sameFileBytes(winner, file.Sources[1])
+
+		switch {
+		case err != nil:
+			entry.Status = OverrideStatusError
+			entry.Error = err.Error()
+		case same:
+			entry.Status = OverrideStatusIdentical
+		default:
+			entry.Status = OverrideStatusModified
+		}
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result
+}
+
+// projectSource returns the project's copy of a file, or nil if the project doesn't contain the file
+func projectSource(file *VirtualFile) *hscommon.PathEntry {
+	for _, source := range file.Sources {
+		if source.Source == hscommon.PathEntrySourceProject {
+			return source
+		}
+	}
+
+	return nil
+}
+
+func sameFileBytes(a, b *hscommon.PathEntry) (bool, error) {
+	dataA, err := a.GetFileBytes()
+	if err != nil {
+		return false, err
+	}
+
+	dataB, err := b.GetFileBytes()
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(dataA, dataB), nil
+}
+
+// JSON returns the report as indented JSON
+func (r *OverrideReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "   ")
+}
+
+// SaveToFile writes the report as JSON to the given file
+func (r *OverrideReport) SaveToFile(fileName string) error {
+	data, err := r.JSON()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Clean(fileName), data, os.FileMode(newFileMode))
+}
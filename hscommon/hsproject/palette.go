@@ -0,0 +1,82 @@
+package hsproject
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+const (
+	// DefaultPalettePath is the palette indexed images are drawn with until another palette is selected
+	DefaultPalettePath = `data\global\palette\act1\pal.dat`
+
+	gamePaletteFormat = `data\global\palette\%s\pal.dat`
+	paletteExtension  = ".dat"
+)
+
+// gamePalettes returns the names of the palettes of the game, in data\global\palette\<name>\pal.dat
+func gamePalettes() []string {
+	return []string{
+		"act1", "act2", "act3", "act4", "act5", "units", "static", "sky", "endgame", "fechar", "loading",
+		"menu0", "menu1", "menu2", "menu3", "menu4", "trademark",
+	}
+}
+
+// PaletteSource is a palette which indexed images (DC6, DCC, DT1) can be drawn with
+type PaletteSource struct {
+	// Name is shown in the palette selectors
+	Name string
+	// Path is the path of the palette in the game's file system (e.g. data\global\palette\act1\pal.dat)
+	Path string
+}
+
+// Palettes returns the palettes of the game which are found in the load order, followed by the palettes (.dat files)
+// in the project's content. The game palettes are read from the first source of the load order containing them,
+// so a palette overridden by the project is listed once, with the project's colors.
+func (p *Project) Palettes() []PaletteSource {
+	result := make([]PaletteSource, 0)
+	listed := make(map[string]bool)
+
+	for _, name := range gamePalettes() {
+		palettePath := fmt.Sprintf(gamePaletteFormat, name)
+		if p.ResolveFile(palettePath) == nil {
+			continue
+		}
+
+		result = append(result, PaletteSource{Name: name, Path: palettePath})
+		listed[strings.ToLower(palettePath)] = true
+	}
+
+	files, err := p.GetContentFiles()
+	if err != nil {
+		return result
+	}
+
+	for _, relPath := range files {
+		if !strings.EqualFold(path.Ext(relPath), paletteExtension) {
+			continue
+		}
+
+		palettePath := ArchivePathFromContentPath(relPath)
+		if listed[strings.ToLower(palettePath)] {
+			continue
+		}
+
+		result = append(result, PaletteSource{Name: "Project: " + relPath, Path: palettePath})
+	}
+
+	return result
+}
+
+// LoadPalette reads a palette from the first source of the load order containing it
+func (p *Project) LoadPalette(palettePath string) (d2interface.Palette, error) {
+	data, _, err := p.ReadResolvedFile(palettePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return d2dat.Load(data)
+}
@@ -7,17 +7,20 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/OpenDiablo2/dialog"
 
-	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2mpq"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
 
 	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsarchive"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes/hsfont"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hshistory"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hstrash"
 	"github.com/OpenDiablo2/HellSpawner/hsconfig"
 )
 
@@ -33,14 +36,25 @@ const (
 
 // Project represents HellSpawner's project
 type Project struct {
+	// SchemaVersion is the version of the .hsp format the project was saved with
+	SchemaVersion int
 	ProjectName   string
 	Description   string
 	Author        string
 	AuxiliaryMPQs []string
+	// ContentPosition is the position of the content folder among the AuxiliaryMPQs in the load order,
+	// 0 places it before every MPQ, len(AuxiliaryMPQs) after all of them
+	ContentPosition int
 
 	filePath       string
 	pathEntryCache *hscommon.PathEntry
+	// pathEntryError is the error the file structure could not be read with, it is kept until the structure
+	// is invalidated, so that the windows showing it don't read the whole tree again every frame
+	pathEntryError error
 	mpqs           []d2interface.Archive
+	archives       *hsarchive.Registry
+	history        *hshistory.Store
+	trash          *hstrash.Store
 }
 
 // CreateNew creates new project
@@ -53,8 +67,10 @@ func CreateNew(fileName string) (*Project, error) {
 
 	result := &Project{
 		filePath:       fileName,
+		SchemaVersion:  CurrentSchemaVersion,
 		ProjectName:    defaultProjectName,
 		pathEntryCache: nil,
+		archives:       hsarchive.NewRegistry(hsarchive.DefaultCacheSize),
 	}
 
 	if err := result.Save(); err != nil {
@@ -84,6 +100,8 @@ func (p *Project) Save() error {
 
 	var file []byte
 
+	p.SchemaVersion = CurrentSchemaVersion
+
 	if file, err = json.MarshalIndent(p, "", "   "); err != nil {
 		return err
 	}
@@ -101,6 +119,15 @@ func (p *Project) Save() error {
 	return nil
 }
 
+// ApplyProperties copies the properties edited in the project properties dialog from another copy of the project
+func (p *Project) ApplyProperties(other *Project) {
+	p.ProjectName = other.ProjectName
+	p.Description = other.Description
+	p.Author = other.Author
+	p.AuxiliaryMPQs = append([]string(nil), other.AuxiliaryMPQs...)
+	p.ContentPosition = other.ContentPosition
+}
+
 // ValidateAuxiliaryMPQs creates auxiliary mpq's list
 func (p *Project) ValidateAuxiliaryMPQs(config *hsconfig.Config) bool {
 	for idx := range p.AuxiliaryMPQs {
@@ -113,7 +140,9 @@ func (p *Project) ValidateAuxiliaryMPQs(config *hsconfig.Config) bool {
 	return true
 }
 
-// LoadFromFile loads projects file
+// LoadFromFile loads projects file. Projects saved with an older schema version are upgraded,
+// the original file is kept as a backup next to it. Projects saved by a newer version of
+// HellSpawner are rejected with ErrNewerSchemaVersion.
 func LoadFromFile(fileName string) (*Project, error) {
 	var err error
 
@@ -125,16 +154,36 @@ func LoadFromFile(fileName string) (*Project, error) {
 		return nil, err
 	}
 
-	if err := json.Unmarshal(file, &result); err != nil {
+	migrated, fromVersion, err := migrateProject(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(migrated, &result); err != nil {
 		return nil, err
 	}
 
 	result.filePath = fileName
+	result.archives = hsarchive.NewRegistry(hsarchive.DefaultCacheSize)
 
 	if err := result.ensureProjectPaths(); err != nil {
 		return nil, err
 	}
 
+	if fromVersion != CurrentSchemaVersion {
+		backupName, err := backupProjectFile(fileName, file, fromVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("upgraded project %s from format %d to %d, the original was saved as %s",
+			fileName, fromVersion, CurrentSchemaVersion, backupName)
+
+		if err := result.Save(); err != nil {
+			return nil, err
+		}
+	}
+
 	result.InvalidateFileStructure()
 
 	return result, nil
@@ -154,13 +203,19 @@ func (p *Project) ensureProjectPaths() error {
 }
 
 // GetFileStructure returns project's file structure
-func (p *Project) GetFileStructure() *hscommon.PathEntry {
+func (p *Project) GetFileStructure() (*hscommon.PathEntry, error) {
 	if p.pathEntryCache != nil {
-		return p.pathEntryCache
+		return p.pathEntryCache, nil
+	}
+
+	if p.pathEntryError != nil {
+		return nil, p.pathEntryError
 	}
 
 	if err := p.ensureProjectPaths(); err != nil {
-		log.Fatal(err)
+		p.pathEntryError = err
+
+		return nil, err
 	}
 
 	result := &hscommon.PathEntry{
@@ -172,17 +227,22 @@ func (p *Project) GetFileStructure() *hscommon.PathEntry {
 	}
 
 	result.FullPath = filepath.Join(filepath.Dir(p.filePath), "content")
-	p.getFileNodes(result.FullPath, result)
+
+	if err := p.getFileNodes(result.FullPath, result); err != nil {
+		p.pathEntryError = err
+
+		return nil, err
+	}
 
 	p.pathEntryCache = result
 
-	return result
+	return result, nil
 }
 
-func (p *Project) getFileNodes(path string, entry *hscommon.PathEntry) {
+func (p *Project) getFileNodes(path string, entry *hscommon.PathEntry) error {
 	files, err := ioutil.ReadDir(path)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("could not read %s: %w", path, err)
 	}
 
 	for idx := range files {
@@ -199,16 +259,81 @@ func (p *Project) getFileNodes(path string, entry *hscommon.PathEntry) {
 
 		if files[idx].IsDir() {
 			fileNode.IsDirectory = true
-			p.getFileNodes(fileNode.FullPath, fileNode)
+
+			if err := p.getFileNodes(fileNode.FullPath, fileNode); err != nil {
+				return err
+			}
 		}
 
 		entry.Children = append(entry.Children, fileNode)
 	}
+
+	return nil
 }
 
 // InvalidateFileStructure cleans project's files structure
 func (p *Project) InvalidateFileStructure() {
 	p.pathEntryCache = nil
+	p.pathEntryError = nil
+}
+
+// InvalidatePaths re-reads only the directories containing the given (changed) paths,
+// the rest of the cached file structure is kept. It replaces the children of the cached entries in place,
+// so it has to be called by the render loop, which walks the file structure every frame.
+func (p *Project) InvalidatePaths(paths []string) {
+	if p.pathEntryCache == nil {
+		// the structure is read again when it is needed, the change may have fixed what it failed with
+		p.pathEntryError = nil
+
+		return
+	}
+
+	dirs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		dirs = append(dirs, filepath.Dir(path))
+	}
+
+	// parents first, re-reading a directory also re-reads everything below it
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) < len(dirs[j]) })
+
+	refreshed := make([]string, 0)
+
+	for _, dir := range dirs {
+		if isInsideAny(dir, refreshed) {
+			continue
+		}
+
+		entry := p.FindPathEntry(dir)
+		if entry == nil || !entry.IsDirectory {
+			// the directory is new (or gone) as well, so its parent is in the list too
+			continue
+		}
+
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+
+		updated := &hscommon.PathEntry{}
+		if err := p.getFileNodes(dir, updated); err != nil {
+			// the directory may be changing right now, the next change will refresh it again
+			log.Print(err)
+			continue
+		}
+
+		entry.Children = updated.Children
+
+		refreshed = append(refreshed, dir)
+	}
+}
+
+func isInsideAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // RenameFile renames project's file
@@ -280,7 +405,13 @@ func (p *Project) CreateNewFolder(path *hscommon.PathEntry) {
 	}
 
 	p.InvalidateFileStructure()
-	p.GetFileStructure()
+
+	if _, err := p.GetFileStructure(); err != nil {
+		dialog.Message("Could not read the project folder:\n%s", err).Error()
+
+		return
+	}
+
 	p.RenameFile(fileName)
 }
 
@@ -308,40 +439,89 @@ func (p *Project) CreateNewFile(fileType hsfiletypes.FileType, path *hscommon.Pa
 	}
 
 	if fileType == hsfiletypes.FileTypeFont {
-		_, err := hsfont.NewFile(fileName)
-		if err != nil {
-			log.Fatalf("failed to save font: %s", err)
+		if _, err := hsfont.NewFile(fileName); err != nil {
+			dialog.Message("Could not save the font:\n%s", err).Error()
+
+			return
 		}
 	}
 
 	p.InvalidateFileStructure()
 
 	// Force regeneration of file structure so that rename can find the file
-	p.GetFileStructure()
+	if _, err := p.GetFileStructure(); err != nil {
+		dialog.Message("Could not read the project folder:\n%s", err).Error()
+
+		return
+	}
+
 	p.RenameFile(fileName)
 }
 
-// ReloadAuxiliaryMPQs reloads auxiliary MPQs
-func (p *Project) ReloadAuxiliaryMPQs(config *hsconfig.Config) {
-	p.mpqs = make([]d2interface.Archive, len(p.AuxiliaryMPQs))
+// Archives returns the registry through which all of the project's MPQs are read
+func (p *Project) Archives() *hsarchive.Registry {
+	if p.archives == nil {
+		p.archives = hsarchive.NewRegistry(hsarchive.DefaultCacheSize)
+	}
+
+	return p.archives
+}
+
+// History returns the local history of the project's files
+func (p *Project) History() *hshistory.Store {
+	if p.history == nil {
+		p.history = hshistory.New(p.GetProjectFileContentPath())
+	}
+
+	return p.history
+}
+
+// Trash returns the recycle bin holding the project's deleted files and folders
+func (p *Project) Trash() *hstrash.Store {
+	if p.trash == nil {
+		p.trash = hstrash.New(p.GetProjectFileContentPath())
+	}
+
+	return p.trash
+}
+
+// AuxiliaryArchives returns the auxiliary MPQs loaded by ReloadAuxiliaryMPQs, in the same order as AuxiliaryMPQs
+func (p *Project) AuxiliaryArchives() []d2interface.Archive {
+	return p.mpqs
+}
+
+// ReloadAuxiliaryMPQs reloads auxiliary MPQs. Any previously opened archives and cached files are discarded,
+// since the MPQ path in the preferences may have changed. If any of the MPQs can't be opened, none of them are kept.
+func (p *Project) ReloadAuxiliaryMPQs(config *hsconfig.Config) error {
+	archives := p.Archives()
+	archives.Invalidate()
+
+	mpqs := make([]d2interface.Archive, len(p.AuxiliaryMPQs))
+	errs := make([]error, len(p.AuxiliaryMPQs))
 
 	wg := sync.WaitGroup{}
 	wg.Add(len(p.AuxiliaryMPQs))
 
 	for mpqIdx := range p.AuxiliaryMPQs {
 		go func(idx int) {
+			defer wg.Done()
+
 			fileName := filepath.Join(config.AuxiliaryMpqPath, p.AuxiliaryMPQs[idx])
-			data, err := d2mpq.FromFile(fileName)
+			mpqs[idx], errs[idx] = archives.Open(fileName)
+		}(mpqIdx)
+	}
 
-			if err != nil {
-				log.Fatal(err)
-			}
+	wg.Wait()
 
-			p.mpqs[idx] = data
+	for idx, err := range errs {
+		if err != nil {
+			p.mpqs = nil
 
-			wg.Done()
-		}(mpqIdx)
+			return fmt.Errorf("could not open auxiliary MPQ %s: %w", p.AuxiliaryMPQs[idx], err)
+		}
 	}
 
-	wg.Wait()
+	p.mpqs = mpqs
+
+	return nil
 }
@@ -0,0 +1,54 @@
+package hsproject
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsreference"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+// BuildReferenceIndex indexes the references between all files of the virtual file system.
+// Only the winning copy of each file is read, since that is the one the game uses.
+func (p *Project) BuildReferenceIndex(config *hsconfig.Config) (*hsreference.Index, *VirtualFileSystem) {
+	vfs := p.BuildVirtualFileSystem(config)
+	files := vfs.Files()
+
+	paths := make([]string, len(files))
+	for idx := range files {
+		paths[idx] = files[idx].Path
+	}
+
+	read := func(path string) ([]byte, error) {
+		file := vfs.Lookup(path)
+		if file == nil {
+			return nil, errors.New("file not found")
+		}
+
+		return file.Winner().GetFileBytes()
+	}
+
+	return hsreference.NewIndex(paths, read, p.VirtualPathFromFilePath), vfs
+}
+
+// VirtualPathFromFilePath converts a path inside of the project's content directory into the path of the
+// virtual file system. Paths outside of the content directory are assumed to already be virtual paths.
+func (p *Project) VirtualPathFromFilePath(path string) string {
+	relPath, err := filepath.Rel(p.GetProjectFileContentPath(), path)
+	if err != nil || !filepath.IsAbs(path) || strings.HasPrefix(relPath, "..") {
+		return path
+	}
+
+	return ArchivePathFromContentPath(filepath.ToSlash(relPath))
+}
+
+// VirtualPath returns the path of the given project, MPQ or virtual path entry inside of the virtual file system
+func (p *Project) VirtualPath(entry *hscommon.PathEntry) string {
+	if entry.Source == hscommon.PathEntrySourceProject {
+		return p.VirtualPathFromFilePath(entry.FullPath)
+	}
+
+	return strings.ReplaceAll(entry.FullPath, "/", `\`)
+}
@@ -0,0 +1,121 @@
+package hsproject
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	// CurrentSchemaVersion is the version of the .hsp format written by this version of HellSpawner.
+	// It has to be increased, and a migration added to schemaMigrations, whenever the format changes.
+	CurrentSchemaVersion = 2
+
+	// schemaVersionKey is the name of the schema version field in the .hsp file
+	schemaVersionKey = "SchemaVersion"
+
+	maxBackupFiles = 100
+)
+
+// ErrNewerSchemaVersion is returned when a project was saved by a newer version of HellSpawner
+var ErrNewerSchemaVersion = errors.New("the project was saved by a newer version of HellSpawner")
+
+// schemaMigration upgrades a project document from one schema version to the next
+type schemaMigration func(document map[string]interface{}) error
+
+// schemaMigrations returns the migrations, indexed by the version they upgrade from
+func schemaMigrations() []schemaMigration {
+	return []schemaMigration{
+		// version 0 projects were saved before the schema version existed, the format is otherwise unchanged
+		func(document map[string]interface{}) error { return nil },
+		// version 1 projects always placed the content folder before the auxiliary MPQs
+		func(document map[string]interface{}) error {
+			document["ContentPosition"] = 0
+			return nil
+		},
+	}
+}
+
+// schemaVersion returns the schema version of a project document, documents without one are version 0
+func schemaVersion(document map[string]interface{}) (int, error) {
+	value, found := document[schemaVersionKey]
+	if !found {
+		return 0, nil
+	}
+
+	// encoding/json decodes every number as float64
+	version, ok := value.(float64)
+	if !ok || version < 0 || version != float64(int(version)) {
+		return 0, fmt.Errorf("invalid %s %v", schemaVersionKey, value)
+	}
+
+	return int(version), nil
+}
+
+// migrateProject upgrades the project file data to CurrentSchemaVersion.
+// It returns the upgraded data and the version the data had before.
+func migrateProject(data []byte) (migrated []byte, fromVersion int, err error) {
+	var document map[string]interface{}
+
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, 0, err
+	}
+
+	if document == nil {
+		return nil, 0, errors.New("the project file is empty")
+	}
+
+	fromVersion, err = schemaVersion(document)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if fromVersion > CurrentSchemaVersion {
+		return nil, fromVersion, fmt.Errorf("%w (project format %d, supported up to %d)",
+			ErrNewerSchemaVersion, fromVersion, CurrentSchemaVersion)
+	}
+
+	if fromVersion == CurrentSchemaVersion {
+		return data, fromVersion, nil
+	}
+
+	migrations := schemaMigrations()
+
+	for version := fromVersion; version < CurrentSchemaVersion; version++ {
+		if err := migrations[version](document); err != nil {
+			return nil, fromVersion, fmt.Errorf("could not upgrade the project from format %d: %w", version, err)
+		}
+
+		document[schemaVersionKey] = version + 1
+	}
+
+	if migrated, err = json.Marshal(document); err != nil {
+		return nil, fromVersion, err
+	}
+
+	return migrated, fromVersion, nil
+}
+
+// backupProjectFile keeps a copy of a project file before it is upgraded, e.g. project.hsp.v0.bak
+func backupProjectFile(fileName string, data []byte, version int) (string, error) {
+	for i := 0; i < maxBackupFiles; i++ {
+		backupName := fmt.Sprintf("%s.v%d.bak", fileName, version)
+		if i > 0 {
+			backupName = fmt.Sprintf("%s.v%d.%d.bak", fileName, version, i)
+		}
+
+		if _, err := os.Stat(backupName); !os.IsNotExist(err) {
+			continue
+		}
+
+		if err := ioutil.WriteFile(backupName, data, os.FileMode(newFileMode)); err != nil {
+			return "", err
+		}
+
+		return backupName, nil
+	}
+
+	return "", fmt.Errorf("could not create a backup of %s, too many backups exist", fileName)
+}
@@ -0,0 +1,181 @@
+package hsproject
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestProject(t *testing.T, data string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "hsproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	fileName := filepath.Join(dir, "test.hsp")
+
+	if err := ioutil.WriteFile(fileName, []byte(data), os.FileMode(newFileMode)); err != nil {
+		t.Fatal(err)
+	}
+
+	return fileName
+}
+
+func readTestFile(t *testing.T, fileName string) string {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(filepath.Clean(fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestLoadFromFileSchemaVersions(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            string
+		backup          string
+		contentPosition int
+		err             error
+	}{
+		{
+			name:   "version 0, saved before the schema version existed",
+			data:   `{"ProjectName": "Old", "AuxiliaryMPQs": ["d2data.mpq", "d2exp.mpq"]}`,
+			backup: "test.hsp.v0.bak",
+		},
+		{
+			name:   "version 1, content folder before the MPQs",
+			data:   `{"SchemaVersion": 1, "ProjectName": "Old", "AuxiliaryMPQs": ["d2data.mpq", "d2exp.mpq"]}`,
+			backup: "test.hsp.v1.bak",
+		},
+		{
+			name:            "current version",
+			data:            `{"SchemaVersion": 2, "ProjectName": "Old", "AuxiliaryMPQs": ["d2data.mpq", "d2exp.mpq"], "ContentPosition": 1}`,
+			contentPosition: 1,
+		},
+		{
+			name: "newer version",
+			data: `{"SchemaVersion": 3, "ProjectName": "Old", "AuxiliaryMPQs": ["d2data.mpq", "d2exp.mpq"]}`,
+			err:  ErrNewerSchemaVersion,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			fileName := writeTestProject(t, test.data)
+
+			project, err := LoadFromFile(fileName)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected %v, got %v", test.err, err)
+				}
+
+				if saved := readTestFile(t, fileName); saved != test.data {
+					t.Errorf("the rejected project was changed: %s", saved)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if project.SchemaVersion != CurrentSchemaVersion {
+				t.Errorf("schema version %d, expected %d", project.SchemaVersion, CurrentSchemaVersion)
+			}
+
+			if project.ProjectName != "Old" || len(project.AuxiliaryMPQs) != 2 || project.AuxiliaryMPQs[1] != "d2exp.mpq" {
+				t.Errorf("the project's properties were not kept: %+v", project)
+			}
+
+			if project.ContentPosition != test.contentPosition {
+				t.Errorf("content position %d, expected %d", project.ContentPosition, test.contentPosition)
+			}
+
+			backups, err := filepath.Glob(filepath.Join(filepath.Dir(fileName), "*.bak"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.backup == "" {
+				if len(backups) != 0 {
+					t.Errorf("unexpected backups %v", backups)
+				}
+
+				if saved := readTestFile(t, fileName); saved != test.data {
+					t.Errorf("the current project was rewritten: %s", saved)
+				}
+
+				return
+			}
+
+			if len(backups) != 1 || filepath.Base(backups[0]) != test.backup {
+				t.Fatalf("expected the backup %s, got %v", test.backup, backups)
+			}
+
+			if backup := readTestFile(t, backups[0]); backup != test.data {
+				t.Errorf("the backup differs from the original: %s", backup)
+			}
+
+			var saved map[string]interface{}
+			if err := json.Unmarshal([]byte(readTestFile(t, fileName)), &saved); err != nil {
+				t.Fatal(err)
+			}
+
+			if version, err := schemaVersion(saved); err != nil || version != CurrentSchemaVersion {
+				t.Errorf("the upgraded project was saved with version %d (%v)", version, err)
+			}
+		})
+	}
+}
+
+func TestMigrateProjectRejectsInvalidVersions(t *testing.T) {
+	tests := []string{
+		`{"SchemaVersion": "two"}`,
+		`{"SchemaVersion": -1}`,
+		`{"SchemaVersion": 1.5}`,
+		`null`,
+		`[]`,
+	}
+
+	for _, data := range tests {
+		if _, _, err := migrateProject([]byte(data)); err == nil {
+			t.Errorf("%s was accepted", data)
+		}
+	}
+}
+
+func TestBackupProjectFileKeepsExistingBackups(t *testing.T) {
+	fileName := writeTestProject(t, `{}`)
+
+	expected := []string{"test.hsp.v0.bak", "test.hsp.v0.1.bak", "test.hsp.v0.2.bak"}
+
+	for idx, name := range expected {
+		data := []byte{byte(idx)}
+
+		backupName, err := backupProjectFile(fileName, data, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if filepath.Base(backupName) != name {
+			t.Fatalf("backup %d was saved as %s, expected %s", idx, backupName, name)
+		}
+	}
+
+	if first := readTestFile(t, filepath.Join(filepath.Dir(fileName), expected[0])); first != "\x00" {
+		t.Errorf("the first backup was overwritten: %q", first)
+	}
+}
@@ -0,0 +1,292 @@
+package hsproject
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+const (
+	// maxMatchContext is the maximum length of the text shown around a content match
+	maxMatchContext = 80
+)
+
+// SearchMode defines how the search pattern is matched against file paths
+type SearchMode int
+
+// Search modes
+const (
+	// SearchModeSubstring matches paths containing the pattern (case insensitive)
+	SearchModeSubstring SearchMode = iota
+	// SearchModeGlob matches paths using shell globbing (e.g. *.dc6, data\global\ui\*).
+	// Patterns without a path separator are matched against the file name only.
+	SearchModeGlob
+	// SearchModeRegex matches paths using a regular expression (case insensitive)
+	SearchModeRegex
+)
+
+// SearchModeNames returns the names of all search modes, indexed by mode
+func SearchModeNames() []string {
+	return []string{"Substring", "Glob", "Regex"}
+}
+
+// String returns the name of the search mode
+func (m SearchMode) String() string {
+	names := SearchModeNames()
+	if int(m) < 0 || int(m) >= len(names) {
+		return "Unknown"
+	}
+
+	return names[m]
+}
+
+// SearchOptions defines what Project.Search looks for
+type SearchOptions struct {
+	// Pattern is matched against the path each file has inside of the game's file system (data\global\...)
+	Pattern string
+	// Mode defines how Pattern is interpreted
+	Mode SearchMode
+	// Content, when not empty, limits the results to text and table files which contain it (case insensitive)
+	Content string
+	// MaxResults stops the search after this many results, 0 means no limit
+	MaxResults int
+}
+
+// SearchResult is a single file found by Project.Search
+type SearchResult struct {
+	// Path is the path of the file inside of the game's file system
+	Path string
+	// Source is the name of the project or MPQ containing the file
+	Source string
+	// Match is the text around the first content match, it is empty when no content search was done
+	Match string
+	// Entry can be used to open the file in an editor
+	Entry *hscommon.PathEntry
+}
+
+// PathMatcher returns true if the path inside of the game's file system matches a search pattern
+type PathMatcher func(archivePath string) bool
+
+// Search looks for files in the project's content directory and every auxiliary MPQ loaded by ReloadAuxiliaryMPQs.
+// The files are returned in the project's load order (see LoadOrder). The second return value is true
+// when the search was stopped because MaxResults was reached.
+func (p *Project) Search(config *hsconfig.Config, options SearchOptions) (results []SearchResult, truncated bool, err error) {
+	match, err := NewPathMatcher(options.Pattern, options.Mode)
+	if err != nil {
+		return nil, false, err
+	}
+
+	content := asciiLower([]byte(options.Content))
+	results = make([]SearchResult, 0)
+
+	add := func(archivePath string, entry *hscommon.PathEntry) bool {
+		if !match(archivePath) {
+			return true
+		}
+
+		result := SearchResult{
+			Path:   archivePath,
+			Source: entry.GetSourceName(),
+			Entry:  entry,
+		}
+
+		if len(content) > 0 {
+			found, context := searchFileContent(entry, content)
+			if !found {
+				return true
+			}
+
+			result.Match = context
+		}
+
+		if options.MaxResults > 0 && len(results) >= options.MaxResults {
+			truncated = true
+			return false
+		}
+
+		results = append(results, result)
+
+		return true
+	}
+
+	fileStructure, err := p.GetFileStructure()
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, source := range p.LoadOrder() {
+		if source.IsContentFolder() {
+			if !p.searchProjectFiles(fileStructure, add) {
+				return results, truncated, nil
+			}
+
+			continue
+		}
+
+		if source.Archive == nil {
+			continue
+		}
+
+		if !p.searchMPQFiles(source.Archive, config, add) {
+			return results, truncated, nil
+		}
+	}
+
+	return results, truncated, nil
+}
+
+func (p *Project) searchMPQFiles(mpq d2interface.Archive, config *hsconfig.Config,
+	add func(string, *hscommon.PathEntry) bool) bool {
+	files, err := p.GetMPQFileList(mpq, config)
+	if err != nil {
+		log.Printf("failed to list files of %s: %s", mpq.Path(), err)
+		return true
+	}
+
+	for _, fileName := range files {
+		if strings.TrimSpace(fileName) == "" {
+			continue
+		}
+
+		entry := &hscommon.PathEntry{
+			Name:     fileName[strings.LastIndexAny(fileName, `\/`)+1:],
+			FullPath: fileName,
+			Source:   hscommon.PathEntrySourceMPQ,
+			MPQFile:  mpq.Path(),
+		}
+
+		if !add(strings.ReplaceAll(fileName, "/", `\`), entry) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Project) searchProjectFiles(entry *hscommon.PathEntry, add func(string, *hscommon.PathEntry) bool) bool {
+	for _, child := range entry.Children {
+		if child.IsDirectory {
+			if !p.searchProjectFiles(child, add) {
+				return false
+			}
+
+			continue
+		}
+
+		relPath, err := filepath.Rel(p.GetProjectFileContentPath(), child.FullPath)
+		if err != nil {
+			continue
+		}
+
+		if !add(ArchivePathFromContentPath(filepath.ToSlash(relPath)), child) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewPathMatcher returns a matcher for the pattern, interpreted according to the search mode
+func NewPathMatcher(pattern string, mode SearchMode) (PathMatcher, error) {
+	switch mode {
+	case SearchModeSubstring:
+		pattern = strings.ToLower(strings.ReplaceAll(pattern, "/", `\`))
+
+		return func(archivePath string) bool {
+			return strings.Contains(strings.ToLower(archivePath), pattern)
+		}, nil
+	case SearchModeGlob:
+		// path.Match treats backslashes as escapes, so everything is matched with forward slashes
+		pattern = strings.ToLower(strings.ReplaceAll(pattern, `\`, "/"))
+		if pattern == "" {
+			pattern = "*"
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern: %w", err)
+		}
+
+		matchName := !strings.Contains(pattern, "/")
+
+		return func(archivePath string) bool {
+			name := strings.ToLower(strings.ReplaceAll(archivePath, `\`, "/"))
+			if matchName {
+				name = path.Base(name)
+			}
+
+			matched, _ := path.Match(pattern, name)
+
+			return matched
+		}, nil
+	case SearchModeRegex:
+		expression, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+
+		return expression.MatchString, nil
+	}
+
+	return nil, fmt.Errorf("unknown search mode %d", mode)
+}
+
+// searchFileContent looks for content (already lower case) inside of text and table files.
+// It returns the line (or table string) containing the first match.
+func searchFileContent(entry *hscommon.PathEntry, content []byte) (found bool, context string) {
+	ext := strings.ToLower(filepath.Ext(entry.Name))
+	if ext != hsfiletypes.FileTypeText.FileExtension() && ext != hsfiletypes.FileTypeTBL.FileExtension() {
+		return false, ""
+	}
+
+	data, err := entry.GetFileBytes()
+	if err != nil {
+		log.Printf("failed to read %s: %s", entry.FullPath, err)
+		return false, ""
+	}
+
+	idx := bytes.Index(asciiLower(data), content)
+	if idx < 0 {
+		return false, ""
+	}
+
+	// lines in text files, and strings in table files, are delimited by new lines and null bytes
+	isDelimiter := func(b byte) bool { return b == '\n' || b == '\r' || b == 0 }
+
+	start, end := idx, idx+len(content)
+
+	for start > 0 && !isDelimiter(data[start-1]) && idx-start < maxMatchContext {
+		start--
+	}
+
+	for end < len(data) && !isDelimiter(data[end]) && end-start < maxMatchContext {
+		end++
+	}
+
+	return true, strings.TrimSpace(strings.ReplaceAll(string(data[start:end]), "\t", " "))
+}
+
+// asciiLower lower cases ASCII letters only. Game files are not valid UTF-8, and bytes.ToLower
+// would change the length of the data, breaking the offsets of the matches.
+func asciiLower(data []byte) []byte {
+	result := make([]byte, len(data))
+
+	for idx, b := range data {
+		if b >= 'A' && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+
+		result[idx] = b
+	}
+
+	return result
+}
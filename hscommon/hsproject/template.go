@@ -0,0 +1,402 @@
+package hsproject
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes/hsfont"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsutil"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+const (
+	// TemplateFileName is the name of the file describing a user defined template
+	TemplateFileName = "template.json"
+	// templateContentDir is the directory of a user defined template which is copied into the project's content
+	templateContentDir = "content"
+)
+
+// TemplateFont is a font created by a template. All paths are relative to the project's content directory,
+// they are turned into absolute paths when the project is created (the font editor stores absolute paths).
+type TemplateFont struct {
+	Path        string
+	SpriteFile  string
+	TableFile   string
+	PaletteFile string
+}
+
+// Template describes the initial content of a new project
+type Template struct {
+	Name        string
+	Description string
+	// AuxiliaryMPQs are added to the project (highest priority first), if they exist in the auxiliary MPQ path
+	AuxiliaryMPQs []string
+	// CopyFromMPQs are game file paths (data\global\...) copied from the auxiliary MPQs into the project.
+	// The MPQs of the template are searched first, then every MPQ in the auxiliary MPQ path.
+	CopyFromMPQs []string
+	// Directories are created inside of the project's content directory
+	Directories []string
+	// Fonts are created inside of the project's content directory
+	Fonts []TemplateFont
+
+	// dir is the directory a user defined template was loaded from, it is empty for the built in templates
+	dir string
+}
+
+// BuiltinTemplates returns the templates which are always available
+func BuiltinTemplates() []*Template {
+	return []*Template{
+		{
+			Name:        "Empty mod",
+			Description: "An empty project.",
+		},
+		{
+			Name:          "UI / font mod",
+			Description:   "A project containing the game's 16px font and a font file using it.",
+			AuxiliaryMPQs: []string{"patch_d2.mpq", "d2exp.mpq", "d2data.mpq"},
+			CopyFromMPQs: []string{
+				`data\global\ui\FONTS\font16.dc6`,
+				`data\global\ui\FONTS\font16.tbl`,
+				`data\global\palette\Units\Pal.PL2`,
+			},
+			Fonts: []TemplateFont{
+				{
+					Path:        "global/ui/FONTS/font16.hsf",
+					SpriteFile:  "global/ui/FONTS/font16.dc6",
+					TableFile:   "global/ui/FONTS/font16.tbl",
+					PaletteFile: "global/palette/Units/Pal.PL2",
+				},
+			},
+		},
+		{
+			Name:          "Map mod",
+			Description:   "A project with the directory layout of the game's maps (DS1 and DT1 files) and the level tables.",
+			AuxiliaryMPQs: []string{"patch_d2.mpq", "d2exp.mpq", "d2data.mpq"},
+			CopyFromMPQs: []string{
+				`data\global\excel\Levels.txt`,
+				`data\global\excel\LvlPrest.txt`,
+				`data\global\excel\LvlTypes.txt`,
+			},
+			Directories: []string{
+				"global/tiles/ACT1",
+				"global/tiles/ACT2",
+				"global/tiles/ACT3",
+				"global/tiles/ACT4",
+				"global/tiles/ACT5",
+				"global/tiles/Expansion",
+			},
+		},
+	}
+}
+
+// LoadTemplate loads a user defined template from a directory containing a template.json.
+// Besides the entries of template.json, everything in the template's content directory is copied into the project.
+func LoadTemplate(dir string) (*Template, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, TemplateFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Template{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Join(dir, TemplateFileName), err)
+	}
+
+	if strings.TrimSpace(result.Name) == "" {
+		result.Name = filepath.Base(dir)
+	}
+
+	if err := result.checkPaths(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Join(dir, TemplateFileName), err)
+	}
+
+	result.dir = dir
+
+	return result, nil
+}
+
+// checkPaths verifies that every file and directory the template creates is inside of the content directory
+func (t *Template) checkPaths() error {
+	// the paths are resolved against a made up content directory, only whether they stay inside of it matters
+	contentPath := filepath.Join(string(filepath.Separator), templateContentDir)
+
+	paths := make([]string, 0, len(t.Directories)+len(t.CopyFromMPQs)+len(t.Fonts))
+	paths = append(paths, t.Directories...)
+
+	for _, archivePath := range t.CopyFromMPQs {
+		paths = append(paths, ContentPathFromArchivePath(archivePath))
+	}
+
+	for _, font := range t.Fonts {
+		paths = append(paths, font.Path)
+	}
+
+	for _, path := range paths {
+		if !hsutil.IsInsideDir(filepath.Join(contentPath, filepath.FromSlash(path)), contentPath) {
+			return fmt.Errorf("%s is outside of the project's content directory", path)
+		}
+	}
+
+	return nil
+}
+
+// FindTemplates returns the built in templates followed by the templates found in the template directories
+// of the config. Templates which could not be loaded are reported in the returned errors.
+func FindTemplates(config *hsconfig.Config) (templates []*Template, errs []error) {
+	templates = BuiltinTemplates()
+
+	for _, dir := range config.ProjectTemplateDirs {
+		if _, err := os.Stat(filepath.Join(dir, TemplateFileName)); err == nil {
+			template, loadErr := LoadTemplate(dir)
+			if loadErr != nil {
+				errs = append(errs, loadErr)
+				continue
+			}
+
+			templates = append(templates, template)
+
+			continue
+		}
+
+		subDirs, err := ioutil.ReadDir(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, subDir := range subDirs {
+			templateDir := filepath.Join(dir, subDir.Name())
+
+			if !subDir.IsDir() {
+				continue
+			}
+
+			if _, statErr := os.Stat(filepath.Join(templateDir, TemplateFileName)); statErr != nil {
+				continue
+			}
+
+			template, loadErr := LoadTemplate(templateDir)
+			if loadErr != nil {
+				errs = append(errs, loadErr)
+				continue
+			}
+
+			templates = append(templates, template)
+		}
+	}
+
+	return templates, errs
+}
+
+// CreateFromTemplate creates a new project and fills it with the template's content.
+// Missing MPQs and files are not fatal, they are returned as warnings so the project can still be used.
+// If the project can't be created, the project file and the content directory it created are removed again.
+func CreateFromTemplate(fileName string, template *Template, config *hsconfig.Config) (*Project, []string, error) {
+	if template != nil {
+		if err := template.checkPaths(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// CreateNew creates the content directory next to the project file
+	_, statErr := os.Stat(filepath.Join(filepath.Dir(fileName), "content"))
+	contentExisted := statErr == nil
+
+	project, err := CreateNew(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if template == nil {
+		return project, nil, nil
+	}
+
+	warnings, err := project.applyTemplate(template, config)
+	if err == nil {
+		err = project.Save()
+	}
+
+	if err != nil {
+		project.removeCreatedFiles(contentExisted)
+
+		return nil, warnings, err
+	}
+
+	return project, warnings, nil
+}
+
+// removeCreatedFiles removes a project which could not be created. The content directory is only removed
+// if it didn't exist before, the project may have been created in a directory with other files.
+func (p *Project) removeCreatedFiles(contentExisted bool) {
+	if err := os.Remove(p.filePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove %s: %s", p.filePath, err)
+	}
+
+	if contentExisted {
+		return
+	}
+
+	if err := os.RemoveAll(p.GetProjectFileContentPath()); err != nil {
+		log.Printf("failed to remove %s: %s", p.GetProjectFileContentPath(), err)
+	}
+}
+
+func (p *Project) applyTemplate(template *Template, config *hsconfig.Config) ([]string, error) {
+	contentPath := p.GetProjectFileContentPath()
+	warnings := make([]string, 0)
+
+	for _, mpq := range template.AuxiliaryMPQs {
+		if _, err := os.Stat(filepath.Join(config.AuxiliaryMpqPath, mpq)); err != nil {
+			warnings = append(warnings, fmt.Sprintf("auxiliary MPQ %s not found", mpq))
+			continue
+		}
+
+		p.AuxiliaryMPQs = append(p.AuxiliaryMPQs, mpq)
+	}
+
+	if template.dir != "" {
+		if err := copyDirectory(filepath.Join(template.dir, templateContentDir), contentPath); err != nil {
+			return warnings, err
+		}
+	}
+
+	for _, dir := range template.Directories {
+		if err := os.MkdirAll(filepath.Join(contentPath, filepath.FromSlash(dir)), os.FileMode(newDirMode)); err != nil {
+			return warnings, err
+		}
+	}
+
+	warnings = append(warnings, p.copyTemplateFiles(template, config)...)
+
+	for _, font := range template.Fonts {
+		if err := p.createTemplateFont(font); err != nil {
+			return warnings, err
+		}
+	}
+
+	return warnings, nil
+}
+
+// copyTemplateFiles copies the template's files out of the MPQs, the template's own MPQs are searched first
+func (p *Project) copyTemplateFiles(template *Template, config *hsconfig.Config) (warnings []string) {
+	mpqs := make([]string, 0)
+
+	for _, mpq := range p.AuxiliaryMPQs {
+		mpqs = append(mpqs, filepath.Join(config.AuxiliaryMpqPath, mpq))
+	}
+
+	others := config.GetAuxMPQs()
+	sort.Strings(others)
+
+	mpqs = append(mpqs, others...)
+
+	for _, archivePath := range template.CopyFromMPQs {
+		var data []byte
+
+		for _, mpq := range mpqs {
+			if !p.Archives().Contains(mpq, archivePath) {
+				continue
+			}
+
+			if fileData, err := p.Archives().ReadFile(mpq, archivePath); err == nil {
+				data = fileData
+				break
+			}
+		}
+
+		if data == nil {
+			warnings = append(warnings, fmt.Sprintf("%s not found in the auxiliary MPQs", archivePath))
+			continue
+		}
+
+		target := filepath.Join(p.GetProjectFileContentPath(), filepath.FromSlash(ContentPathFromArchivePath(archivePath)))
+
+		if err := os.MkdirAll(filepath.Dir(target), os.FileMode(newDirMode)); err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not copy %s: %s", archivePath, err))
+			continue
+		}
+
+		if err := ioutil.WriteFile(target, data, os.FileMode(newFileMode)); err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not copy %s: %s", archivePath, err))
+		}
+	}
+
+	return warnings
+}
+
+func (p *Project) createTemplateFont(font TemplateFont) error {
+	contentPath := p.GetProjectFileContentPath()
+
+	absPath := func(relPath string) string {
+		if relPath == "" {
+			return ""
+		}
+
+		return filepath.Join(contentPath, filepath.FromSlash(relPath))
+	}
+
+	fontPath := absPath(font.Path)
+	if err := os.MkdirAll(filepath.Dir(fontPath), os.FileMode(newDirMode)); err != nil {
+		return err
+	}
+
+	result, err := hsfont.NewFile(fontPath)
+	if err != nil {
+		return err
+	}
+
+	result.SpriteFile = absPath(font.SpriteFile)
+	result.TableFile = absPath(font.TableFile)
+	result.PaletteFile = absPath(font.PaletteFile)
+
+	return result.SaveToFile()
+}
+
+// copyDirectory copies every file of the source directory into the target directory, hidden files are skipped
+func copyDirectory(source, target string) error {
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == source {
+			return nil
+		}
+
+		if info.Name()[0] == '.' {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		relPath, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+
+		targetPath := filepath.Join(target, relPath)
+
+		if info.IsDir() {
+			return os.MkdirAll(targetPath, os.FileMode(newDirMode))
+		}
+
+		data, err := ioutil.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(targetPath, data, os.FileMode(newFileMode))
+	})
+}
@@ -0,0 +1,201 @@
+package hsproject
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2cof"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dcc"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2ds1"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dt1"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2pl2"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2tbl"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes/hsfont"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+const (
+	wavHeaderSize      = 12
+	wavFormatOffset    = 8
+	fontTableSignature = "Woo!"
+)
+
+// Severity describes how serious a validation problem is
+type Severity string
+
+// Problem severities
+const (
+	SeverityError   = Severity("error")
+	SeverityWarning = Severity("warning")
+)
+
+// Problem is a single issue found by Project.Validate
+type Problem struct {
+	Severity Severity `json:"severity"`
+	// Path is the path of the file inside of the game's file system (data\global\...)
+	Path    string `json:"path"`
+	Message string `json:"message"`
+
+	// Entry can be used to open the file in an editor
+	Entry *hscommon.PathEntry `json:"-"`
+}
+
+// ValidationReport lists every problem found in a project
+type ValidationReport struct {
+	ProjectName string    `json:"project_name"`
+	FileCount   int       `json:"file_count"`
+	Problems    []Problem `json:"problems"`
+}
+
+// HasErrors returns true if the report contains at least one error
+func (r *ValidationReport) HasErrors() bool {
+	for idx := range r.Problems {
+		if r.Problems[idx].Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JSON returns the report as indented JSON
+func (r *ValidationReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "   ")
+}
+
+// Validate decodes every file of the project's content directory with the decoder of its file type,
+// and checks that the files referenced by project files exist in the project or in the auxiliary MPQs.
+// The auxiliary MPQs have to be loaded with ReloadAuxiliaryMPQs first.
+func (p *Project) Validate(config *hsconfig.Config) *ValidationReport {
+	result := &ValidationReport{
+		ProjectName: p.ProjectName,
+		Problems:    make([]Problem, 0),
+	}
+
+	index, vfs := p.BuildReferenceIndex(config)
+
+	for _, file := range vfs.Files() {
+		entry := file.Winner()
+		if entry.Source != hscommon.PathEntrySourceProject {
+			if shadowed := projectSource(file); shadowed != nil {
+				result.FileCount++
+				result.Problems = append(result.Problems, Problem{
+					Severity: SeverityWarning,
+					Path:     file.Path,
+					Message:  fmt.Sprintf("hidden by %s, which comes first in the load order", entry.GetSourceName()),
+					Entry:    shadowed,
+				})
+			}
+
+			continue
+		}
+
+		result.FileCount++
+
+		addProblem := func(severity Severity, format string, args ...interface{}) {
+			result.Problems = append(result.Problems, Problem{
+				Severity: severity,
+				Path:     file.Path,
+				Message:  fmt.Sprintf(format, args...),
+				Entry:    entry,
+			})
+		}
+
+		data, err := entry.GetFileBytes()
+		if err != nil {
+			addProblem(SeverityError, "could not read file: %s", err)
+			continue
+		}
+
+		fileType, known, decodeErr := decodeFile(entry.Name, data)
+
+		switch {
+		case !known:
+			addProblem(SeverityWarning, "unknown file type")
+			continue
+		case decodeErr != nil:
+			addProblem(SeverityError, "could not decode %s file: %s", fileType, decodeErr)
+			continue
+		}
+
+		for _, reference := range index.References(file.Path) {
+			if !reference.Exists {
+				addProblem(SeverityError, "%s %s not found in the project or the auxiliary MPQs",
+					reference.Kind, reference.Target)
+			}
+		}
+
+		if fileType == hsfiletypes.FileTypeFont {
+			if font, fontErr := hsfont.LoadFromJSON(data); fontErr == nil && font.SpriteFile == "" {
+				addProblem(SeverityWarning, "font has no sprite")
+			}
+		}
+	}
+
+	sort.SliceStable(result.Problems, func(i, j int) bool {
+		return strings.ToLower(result.Problems[i].Path) < strings.ToLower(result.Problems[j].Path)
+	})
+
+	return result
+}
+
+// decodeFile decodes the data with the decoder for the file type implied by the file name.
+// known is false if there is no file type for the file's extension. Some of the decoders
+// panic on malformed data, so panics are reported as errors.
+func decodeFile(fileName string, data []byte) (fileType hsfiletypes.FileType, known bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			// only the decoders of known file types can panic
+			known = true
+			err = fmt.Errorf("decoder failed: %v", r)
+		}
+	}()
+
+	fileType, err = hsfiletypes.GetFileTypeFromExtension(filepath.Ext(fileName), &data)
+	if err != nil {
+		return fileType, false, nil
+	}
+
+	known = true
+
+	switch fileType {
+	case hsfiletypes.FileTypeFont:
+		_, err = hsfont.LoadFromJSON(data)
+	case hsfiletypes.FileTypePalette:
+		_, err = d2dat.Load(data)
+	case hsfiletypes.FileTypePL2:
+		_, err = d2pl2.Load(data)
+	case hsfiletypes.FileTypeAudio:
+		if len(data) < wavHeaderSize || !bytes.HasPrefix(data, []byte("RIFF")) ||
+			!bytes.Equal(data[wavFormatOffset:wavHeaderSize], []byte("WAVE")) {
+			err = fmt.Errorf("not a RIFF/WAVE file")
+		}
+	case hsfiletypes.FileTypeDCC:
+		_, err = d2dcc.Load(data)
+	case hsfiletypes.FileTypeDC6:
+		_, err = d2dc6.Load(data)
+	case hsfiletypes.FileTypeCOF:
+		_, err = d2cof.Load(data)
+	case hsfiletypes.FileTypeDT1:
+		_, err = d2dt1.LoadDT1(data)
+	case hsfiletypes.FileTypeDS1:
+		_, err = d2ds1.LoadDS1(data)
+	case hsfiletypes.FileTypeTBLStringTable:
+		_, err = d2tbl.LoadTextDictionary(data)
+	case hsfiletypes.FileTypeTBLFontTable:
+		if !bytes.HasPrefix(data, []byte(fontTableSignature)) {
+			err = fmt.Errorf("missing %s signature", fontTableSignature)
+		}
+	}
+
+	return fileType, known, err
+}
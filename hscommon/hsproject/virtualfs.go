@@ -0,0 +1,194 @@
+package hsproject
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+)
+
+// VirtualFile is a single file of the virtual file system, along with every source that contains it
+type VirtualFile struct {
+	// Path is the path of the file inside of the game's file system (e.g. data\global\ui\cursor.dc6)
+	Path string
+
+	// Sources lists every project/MPQ path entry that contains this file, ordered by priority.
+	// The first entry is the one the game sees.
+	Sources []*hscommon.PathEntry
+}
+
+// Winner returns the source that provides the file's data
+func (v *VirtualFile) Winner() *hscommon.PathEntry {
+	return v.Sources[0]
+}
+
+// VirtualFileSystem is the composite view of the project directory and all auxiliary MPQs.
+// The sources are layered in the project's load order (see LoadOrder).
+type VirtualFileSystem struct {
+	files map[string]*VirtualFile
+	root  *hscommon.PathEntry
+}
+
+// BuildVirtualFileSystem overlays the project's content directory and its auxiliary MPQs in load order.
+// The MPQs have to be loaded with ReloadAuxiliaryMPQs first.
+func (p *Project) BuildVirtualFileSystem(config *hsconfig.Config) *VirtualFileSystem {
+	result := &VirtualFileSystem{
+		files: make(map[string]*VirtualFile),
+	}
+
+	for _, source := range p.LoadOrder() {
+		if source.IsContentFolder() {
+			result.addProjectFiles(p)
+			continue
+		}
+
+		if source.Archive == nil {
+			continue
+		}
+
+		result.addMPQFiles(p, source.Archive, config)
+	}
+
+	result.root = result.buildTree(p.ProjectName)
+
+	return result
+}
+
+func (v *VirtualFileSystem) addProjectFiles(p *Project) {
+	contentPath := p.GetProjectFileContentPath()
+
+	projectFiles, err := p.GetContentFiles()
+	if err != nil {
+		log.Printf("failed to list project files: %s", err)
+	}
+
+	for _, relPath := range projectFiles {
+		v.addSource(ArchivePathFromContentPath(relPath), &hscommon.PathEntry{
+			Name:     filepath.Base(relPath),
+			FullPath: filepath.Join(contentPath, filepath.FromSlash(relPath)),
+			Source:   hscommon.PathEntrySourceProject,
+		})
+	}
+}
+
+func (v *VirtualFileSystem) addMPQFiles(p *Project, mpq d2interface.Archive, config *hsconfig.Config) {
+	files, err := p.GetMPQFileList(mpq, config)
+	if err != nil {
+		log.Printf("failed to list files of %s: %s", mpq.Path(), err)
+		return
+	}
+
+	for _, fileName := range files {
+		if strings.TrimSpace(fileName) == "" {
+			continue
+		}
+
+		v.addSource(fileName, &hscommon.PathEntry{
+			Name:     fileName[strings.LastIndexAny(fileName, `\/`)+1:],
+			FullPath: fileName,
+			Source:   hscommon.PathEntrySourceMPQ,
+			MPQFile:  mpq.Path(),
+		})
+	}
+}
+
+func (v *VirtualFileSystem) addSource(path string, source *hscommon.PathEntry) {
+	path = strings.ReplaceAll(path, "/", `\`)
+	key := strings.ToLower(path)
+
+	file, found := v.files[key]
+	if !found {
+		file = &VirtualFile{Path: path}
+		v.files[key] = file
+	}
+
+	for _, existing := range file.Sources {
+		if existing.Source == source.Source && existing.MPQFile == source.MPQFile {
+			// listfiles sometimes contain the same file more than once
+			return
+		}
+	}
+
+	file.Sources = append(file.Sources, source)
+}
+
+func (v *VirtualFileSystem) buildTree(name string) *hscommon.PathEntry {
+	root := &hscommon.PathEntry{
+		Name:        name,
+		IsDirectory: true,
+		IsRoot:      true,
+		Source:      hscommon.PathEntryVirtual,
+	}
+
+	pathNodes := make(map[string]*hscommon.PathEntry)
+	pathNodes[""] = root
+
+	for _, file := range v.Files() {
+		elements := strings.FieldsFunc(file.Path, func(r rune) bool { return r == '\\' })
+
+		path := ""
+
+		for elemIdx := range elements {
+			oldPath := path
+
+			path += elements[elemIdx]
+			if elemIdx < len(elements)-1 {
+				path += `\`
+			}
+
+			if pathNodes[strings.ToLower(path)] != nil {
+				continue
+			}
+
+			node := &hscommon.PathEntry{
+				Name:        elements[elemIdx],
+				FullPath:    path,
+				Source:      hscommon.PathEntryVirtual,
+				IsDirectory: elemIdx < len(elements)-1,
+			}
+
+			if !node.IsDirectory {
+				node.Resolved = file.Winner()
+			}
+
+			pathNodes[strings.ToLower(path)] = node
+			pathNodes[strings.ToLower(oldPath)].Children = append(pathNodes[strings.ToLower(oldPath)].Children, node)
+		}
+	}
+
+	for _, node := range pathNodes {
+		hscommon.SortPaths(node)
+	}
+
+	return root
+}
+
+// Root returns the root of the virtual file tree
+func (v *VirtualFileSystem) Root() *hscommon.PathEntry {
+	return v.root
+}
+
+// Lookup returns the virtual file at the given path, or nil if no layer contains it
+func (v *VirtualFileSystem) Lookup(path string) *VirtualFile {
+	return v.files[strings.ToLower(strings.ReplaceAll(path, "/", `\`))]
+}
+
+// Files returns every file of the virtual file system, sorted by path
+func (v *VirtualFileSystem) Files() []*VirtualFile {
+	result := make([]*VirtualFile, 0, len(v.files))
+
+	for _, file := range v.files {
+		result = append(result, file)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Path) < strings.ToLower(result[j].Path)
+	})
+
+	return result
+}
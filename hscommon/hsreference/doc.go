@@ -0,0 +1,3 @@
+// Package hsreference finds the files that assets reference (fonts, DS1 tile lists and COF layers),
+// and the assets that use a given file.
+package hsreference
@@ -0,0 +1,177 @@
+package hsreference
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2cof"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2ds1"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes/hsfont"
+)
+
+const (
+	fontExtension = ".hsf"
+	ds1Extension  = ".ds1"
+	cofExtension  = ".cof"
+	dt1Extension  = ".dt1"
+	dccExtension  = ".dcc"
+
+	// ds1PathPrefix is the directory of the original level editor, DS1 files store their tile paths relative to it
+	ds1PathPrefix = `d2\`
+
+	// a COF file is named <token><mode><weapon class>.cof, for example ZMNUHTH.cof
+	cofModeLength        = 2
+	cofWeaponClassLength = 3
+
+	// a DCC file is named <token><layer><armor><mode><weapon class>.dcc, for example ZMTRLITNUHTH.dcc
+	dccArmorLength = 3
+)
+
+func parseFont(path string, data []byte, resolve PathResolver) ([]Reference, error) {
+	font, err := hsfont.LoadFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Reference, 0)
+
+	for _, target := range []struct {
+		path string
+		kind Kind
+	}{
+		{font.SpriteFile, KindFontSprite},
+		{font.TableFile, KindFontTable},
+		{font.PaletteFile, KindFontPalette},
+	} {
+		if target.path == "" {
+			continue
+		}
+
+		result = append(result, Reference{Source: path, Target: resolve(target.path), Kind: target.kind})
+	}
+
+	return result, nil
+}
+
+func parseDS1(path string, data []byte, _ PathResolver) ([]Reference, error) {
+	ds1, err := d2ds1.LoadDS1(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Reference, 0, len(ds1.Files))
+
+	for idx, file := range ds1.Files {
+		if file == "" {
+			continue
+		}
+
+		result = append(result, Reference{
+			Source: path,
+			Target: DS1TilePath(file),
+			Kind:   KindTile,
+			Detail: fmt.Sprintf("file %d", idx),
+		})
+	}
+
+	return result, nil
+}
+
+// DS1TilePath converts a tile path stored in a DS1 file (e.g. \d2\data\global\tiles\act1\town\floor.tg1)
+// into the path of the DT1 file in the MPQs (data\global\tiles\act1\town\floor.dt1)
+func DS1TilePath(file string) string {
+	path := normalizePath(file)
+
+	if len(path) > len(ds1PathPrefix) && strings.EqualFold(path[:len(ds1PathPrefix)], ds1PathPrefix) {
+		path = path[len(ds1PathPrefix):]
+	}
+
+	return strings.TrimSuffix(path, filepath.Ext(path)) + dt1Extension
+}
+
+// RewriteDS1TilePath replaces the DT1 file a tile path stored in a DS1 file points to, keeping the prefix and the
+// extension of the stored path. It is the reverse of DS1TilePath.
+func RewriteDS1TilePath(file, target string) string {
+	path := normalizePath(file)
+	prefix := file[:len(file)-len(path)]
+
+	if len(path) > len(ds1PathPrefix) && strings.EqualFold(path[:len(ds1PathPrefix)], ds1PathPrefix) {
+		prefix += path[:len(ds1PathPrefix)]
+	}
+
+	target = normalizePath(target)
+
+	return prefix + strings.TrimSuffix(target, filepath.Ext(target)) + filepath.Ext(path)
+}
+
+// parseCOF resolves the layers of a COF file to DCC files. COF files are stored in
+// <token directory>\cof\, and the DCC files of each layer in <token directory>\<layer>\. The armor type
+// of a DCC is chosen by the game at runtime, so every armor type found in the index is a reference.
+func (i *Index) parseCOF(path string, data []byte, _ PathResolver) ([]Reference, error) {
+	cof, err := d2cof.Load(data)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(strings.ReplaceAll(path, `\`, "/")), filepath.Ext(path))
+	if len(name) <= cofModeLength+cofWeaponClassLength {
+		return nil, fmt.Errorf("unexpected COF file name %s", name)
+	}
+
+	token := name[:len(name)-cofModeLength-cofWeaponClassLength]
+	mode := name[len(token) : len(token)+cofModeLength]
+	tokenDir := dirName(dirName(path))
+
+	result := make([]Reference, 0)
+
+	for idx := range cof.CofLayers {
+		layer := cof.CofLayers[idx]
+		layerName := layer.Type.String()
+		prefix := strings.ToLower(token + layerName)
+		suffix := strings.ToLower(mode + layer.WeaponClass.String() + dccExtension)
+		detail := fmt.Sprintf("layer %s (%s)", layerName, layer.WeaponClass.String())
+
+		layerDir := tokenDir + `\` + layerName
+		found := false
+
+		for _, candidate := range i.dirs[strings.ToLower(layerDir)] {
+			fileName := strings.ToLower(candidate[len(layerDir)+1:])
+
+			if len(fileName) == len(prefix)+dccArmorLength+len(suffix) &&
+				strings.HasPrefix(fileName, prefix) && strings.HasSuffix(fileName, suffix) {
+				result = append(result, Reference{Source: path, Target: candidate, Kind: KindLayer, Detail: detail})
+				found = true
+			}
+		}
+
+		if !found {
+			// report the layer as missing, the armor type is unknown
+			target := layerDir + `\` + token + layerName + "*" + mode + strings.ToUpper(layer.WeaponClass.String()) + dccExtension
+			result = append(result, Reference{Source: path, Target: target, Kind: KindLayer, Detail: detail})
+		}
+	}
+
+	return result, nil
+}
+
+// CanBeReferenced returns true if files with the given name can be referenced by other files
+func CanBeReferenced(fileName string) bool {
+	ext := filepath.Ext(fileName)
+
+	for _, fileType := range []hsfiletypes.FileType{
+		hsfiletypes.FileTypeDC6,
+		hsfiletypes.FileTypeTBL,
+		hsfiletypes.FileTypePL2,
+		hsfiletypes.FileTypeDT1,
+		hsfiletypes.FileTypeDCC,
+	} {
+		if strings.EqualFold(ext, fileType.FileExtension()) {
+			return true
+		}
+	}
+
+	return false
+}
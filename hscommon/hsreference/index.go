@@ -0,0 +1,175 @@
+package hsreference
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kind describes how one file references another
+type Kind string
+
+// Reference kinds
+const (
+	KindFontSprite  = Kind("font sprite")
+	KindFontTable   = Kind("font table")
+	KindFontPalette = Kind("font palette")
+	KindTile        = Kind("tile")
+	KindLayer       = Kind("layer")
+)
+
+// Reference is a single reference from one file to another. Paths are paths inside of the
+// game's file system (data\global\...), as used by the virtual file system.
+type Reference struct {
+	Source string
+	Target string
+	Kind   Kind
+	// Detail tells which part of the source contains the reference (e.g. the COF layer)
+	Detail string
+	// Exists is false when the target can't be found in the project or the auxiliary MPQs
+	Exists bool
+}
+
+// FileReader returns the contents of a file of the virtual file system
+type FileReader func(path string) ([]byte, error)
+
+// PathResolver converts a path stored inside of a file (for example the file system path of a font's sprite)
+// into a path of the virtual file system
+type PathResolver func(path string) string
+
+// Index holds every reference between the files it was built from
+type Index struct {
+	files      map[string]string
+	dirs       map[string][]string
+	references map[string][]Reference
+	usages     map[string][]Reference
+}
+
+// NewIndex reads every file which can reference other files, and indexes their references.
+// paths should contain every file of the virtual file system, so that missing targets can be detected.
+func NewIndex(paths []string, read FileReader, resolve PathResolver) *Index {
+	result := &Index{
+		files:      make(map[string]string),
+		dirs:       make(map[string][]string),
+		references: make(map[string][]Reference),
+		usages:     make(map[string][]Reference),
+	}
+
+	for _, path := range paths {
+		path = normalizePath(path)
+		key := strings.ToLower(path)
+
+		result.files[key] = path
+		result.dirs[strings.ToLower(dirName(path))] = append(result.dirs[strings.ToLower(dirName(path))], path)
+	}
+
+	for _, path := range paths {
+		path = normalizePath(path)
+
+		var parse func(path string, data []byte, resolve PathResolver) ([]Reference, error)
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case fontExtension:
+			parse = parseFont
+		case ds1Extension:
+			parse = parseDS1
+		case cofExtension:
+			parse = result.parseCOF
+		default:
+			continue
+		}
+
+		data, err := read(path)
+		if err != nil {
+			log.Printf("failed to read %s: %s", path, err)
+			continue
+		}
+
+		references, err := parse(path, data, resolve)
+		if err != nil {
+			log.Printf("failed to find references of %s: %s", path, err)
+			continue
+		}
+
+		for idx := range references {
+			result.add(references[idx])
+		}
+	}
+
+	return result
+}
+
+func (i *Index) add(reference Reference) {
+	reference.Target = normalizePath(reference.Target)
+
+	if path, found := i.files[strings.ToLower(reference.Target)]; found {
+		reference.Target = path
+		reference.Exists = true
+	}
+
+	sourceKey, targetKey := strings.ToLower(reference.Source), strings.ToLower(reference.Target)
+
+	i.references[sourceKey] = append(i.references[sourceKey], reference)
+	i.usages[targetKey] = append(i.usages[targetKey], reference)
+}
+
+// References returns the files referenced by the file at the given path
+func (i *Index) References(path string) []Reference {
+	return sortedCopy(i.references[strings.ToLower(normalizePath(path))], func(r Reference) string { return r.Target })
+}
+
+// Usages returns the references to the file at the given path
+func (i *Index) Usages(path string) []Reference {
+	return sortedCopy(i.usages[strings.ToLower(normalizePath(path))], func(r Reference) string { return r.Source })
+}
+
+// Missing returns every reference whose target doesn't exist
+func (i *Index) Missing() []Reference {
+	result := make([]Reference, 0)
+
+	for _, references := range i.references {
+		for idx := range references {
+			if !references[idx].Exists {
+				result = append(result, references[idx])
+			}
+		}
+	}
+
+	return sortedCopy(result, func(r Reference) string { return r.Source + "|" + r.Target })
+}
+
+// CanReference returns true if files with the given name can reference other files
+func CanReference(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case fontExtension, ds1Extension, cofExtension:
+		return true
+	}
+
+	return false
+}
+
+func sortedCopy(references []Reference, key func(Reference) string) []Reference {
+	result := make([]Reference, len(references))
+	copy(result, references)
+
+	sort.SliceStable(result, func(a, b int) bool {
+		return strings.ToLower(key(result[a])) < strings.ToLower(key(result[b]))
+	})
+
+	return result
+}
+
+// normalizePath converts a path to the form used by MPQs (backslash separated, no leading separator)
+func normalizePath(path string) string {
+	return strings.TrimLeft(strings.ReplaceAll(path, "/", `\`), `\`)
+}
+
+func dirName(path string) string {
+	idx := strings.LastIndex(path, `\`)
+	if idx < 0 {
+		return ""
+	}
+
+	return path[:idx]
+}
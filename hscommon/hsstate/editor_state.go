@@ -4,4 +4,6 @@ package hsstate
 type EditorState struct {
 	WindowState
 	Path []byte `json:"path"` // this gets exported as raw JSON to prevent an import loop
+	// Palette is the path of the palette the editor draws indexed images with, if it has a palette selector
+	Palette string `json:"palette,omitempty"`
 }
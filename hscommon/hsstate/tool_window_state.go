@@ -8,6 +8,15 @@ const (
 	ToolWindowTypeMPQExplorer     = ToolWindowType("MPQ Explorer")
 	ToolWindowTypeProjectExplorer = ToolWindowType("Project Explorer")
 	ToolWindowTypeConsole         = ToolWindowType("Console")
+	ToolWindowTypeVirtualExplorer = ToolWindowType("Virtual File System")
+	ToolWindowTypeOverrideReport  = ToolWindowType("Override Report")
+	ToolWindowTypeSearch          = ToolWindowType("Search")
+	ToolWindowTypeReferences      = ToolWindowType("References")
+	ToolWindowTypeProblems        = ToolWindowType("Problems")
+	ToolWindowTypeLocalHistory    = ToolWindowType("Local History")
+	ToolWindowTypeMPQInspector    = ToolWindowType("MPQ Inspector")
+	ToolWindowTypeDiff            = ToolWindowType("Archive Diff")
+	ToolWindowTypeRecycleBin      = ToolWindowType("Recycle Bin")
 )
 
 // ToolWindowState holds information about tool windows (e.g. MPQ Explorer)
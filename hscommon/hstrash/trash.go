@@ -0,0 +1,312 @@
+// Package hstrash keeps deleted project files and folders, so that they can be restored
+package hstrash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DirName is the name of the directory inside of the project's content directory which contains the trash.
+	// It starts with a dot, so it is hidden from the project explorer and never exported.
+	DirName = ".hstrash"
+
+	indexFileName = "items.json"
+	itemIDFormat  = "20060102T150405.000000000"
+
+	newFileMode = 0644
+	newDirMode  = 0755
+)
+
+// Item describes a file or folder in the trash
+type Item struct {
+	// ID identifies the item, it is derived from the time the item was deleted
+	ID string
+	// Path is the slash separated path the item had, relative to the root of the store
+	Path        string
+	IsDirectory bool
+	// Time is the time the item was deleted
+	Time time.Time
+	// Size is the total size of the item's files
+	Size int64
+}
+
+// Name returns the name of the deleted file or folder
+func (i *Item) Name() string {
+	return filepath.Base(filepath.FromSlash(i.Path))
+}
+
+// Store keeps the files and folders deleted from a directory. A deleted file content/global/ui/cursor.dc6
+// is moved to content/.hstrash/<item ID>/cursor.dc6, and an index describes where it came from.
+type Store struct {
+	root  string
+	trash string
+
+	mutex sync.Mutex
+	// lastDeleted is the ID of the item deleted last, if it is still in the trash
+	lastDeleted string
+}
+
+// New creates a store for the files inside of root, the trash is kept in root/.hstrash
+func New(root string) *Store {
+	return &Store{
+		root:  root,
+		trash: filepath.Join(root, DirName),
+	}
+}
+
+// Delete moves a file or folder inside of the root into the trash
+func (s *Store) Delete(path string) (*Item, error) {
+	relPath, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is not inside of %s", path, s.root)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	items, err := s.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	// items deleted at once (or by a clock with a coarse resolution) still need different IDs
+	for s.find(items, now.Format(itemIDFormat)) >= 0 {
+		now = now.Add(time.Microsecond)
+	}
+
+	item := Item{
+		ID:          now.Format(itemIDFormat),
+		Path:        filepath.ToSlash(relPath),
+		IsDirectory: info.IsDir(),
+		Time:        now,
+		Size:        sizeOf(path),
+	}
+
+	dir := filepath.Join(s.trash, item.ID)
+	if err := os.MkdirAll(dir, newDirMode); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(path, filepath.Join(dir, item.Name())); err != nil {
+		s.removeItemDir(item.ID)
+		return nil, err
+	}
+
+	if err := s.writeIndex(append([]Item{item}, items...)); err != nil {
+		// without the index the item could never be restored
+		if restoreErr := os.Rename(filepath.Join(dir, item.Name()), path); restoreErr != nil {
+			log.Printf("failed to move %s back out of the trash: %s", path, restoreErr)
+		}
+
+		return nil, err
+	}
+
+	s.lastDeleted = item.ID
+
+	return &item, nil
+}
+
+// Items returns the items in the trash, the most recently deleted first
+func (s *Store) Items() ([]Item, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.readIndex()
+}
+
+// Restore moves an item out of the trash, back to where it was deleted from, and returns its path.
+// Nothing is overwritten if a file with the same name was created in the meantime.
+func (s *Store) Restore(id string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	items, err := s.readIndex()
+	if err != nil {
+		return "", err
+	}
+
+	idx := s.find(items, id)
+	if idx < 0 {
+		return "", fmt.Errorf("%s is not in the trash", id)
+	}
+
+	item := items[idx]
+	target := filepath.Join(s.root, filepath.FromSlash(item.Path))
+
+	if _, statErr := os.Lstat(target); statErr == nil {
+		return "", fmt.Errorf("%s already exists", item.Path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), newDirMode); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(filepath.Join(s.trash, item.ID, item.Name()), target); err != nil {
+		return "", err
+	}
+
+	s.removeItemDir(item.ID)
+
+	if err := s.writeIndex(append(items[:idx:idx], items[idx+1:]...)); err != nil {
+		return "", err
+	}
+
+	if s.lastDeleted == id {
+		s.lastDeleted = ""
+	}
+
+	return target, nil
+}
+
+// UndoDelete restores the item deleted last by this store. It returns nil if there is nothing to undo,
+// because nothing was deleted or the item was already restored or purged.
+func (s *Store) UndoDelete() (*Item, error) {
+	s.mutex.Lock()
+	id := s.lastDeleted
+	s.mutex.Unlock()
+
+	if id == "" {
+		return nil, nil
+	}
+
+	items, err := s.Items()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := s.find(items, id)
+	if idx < 0 {
+		return nil, nil
+	}
+
+	if _, err := s.Restore(id); err != nil {
+		return nil, err
+	}
+
+	return &items[idx], nil
+}
+
+// Purge permanently removes an item from the trash
+func (s *Store) Purge(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	items, err := s.readIndex()
+	if err != nil {
+		return err
+	}
+
+	idx := s.find(items, id)
+	if idx < 0 {
+		return fmt.Errorf("%s is not in the trash", id)
+	}
+
+	if err := os.RemoveAll(filepath.Join(s.trash, id)); err != nil {
+		return err
+	}
+
+	if s.lastDeleted == id {
+		s.lastDeleted = ""
+	}
+
+	return s.writeIndex(append(items[:idx:idx], items[idx+1:]...))
+}
+
+// Empty permanently removes every item from the trash
+func (s *Store) Empty() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastDeleted = ""
+
+	return os.RemoveAll(s.trash)
+}
+
+func (s *Store) find(items []Item, id string) int {
+	for idx := range items {
+		if items[idx].ID == id {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func (s *Store) removeItemDir(id string) {
+	if err := os.RemoveAll(filepath.Join(s.trash, id)); err != nil {
+		log.Printf("failed to remove %s from the trash: %s", id, err)
+	}
+}
+
+func (s *Store) readIndex() ([]Item, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.trash, indexFileName))
+	if os.IsNotExist(err) {
+		return []Item{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid trash index in %s: %w", s.trash, err)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time.After(items[j].Time) })
+
+	return items, nil
+}
+
+func (s *Store) writeIndex(items []Item) error {
+	data, err := json.MarshalIndent(items, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.trash, newDirMode); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(s.trash, indexFileName), data, newFileMode)
+}
+
+// sizeOf returns the size of a file, or the total size of a folder's files
+func sizeOf(path string) int64 {
+	var size int64
+
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Printf("failed to get the size of %s: %s", path, err)
+	}
+
+	return size
+}
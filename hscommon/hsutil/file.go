@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const (
@@ -44,3 +45,10 @@ func CreateFileAtPath(pathToFile string, data []byte) bool {
 
 	return true
 }
+
+// IsInsideDir returns false if the path (e.g. made of a malicious archive path containing ..) is not inside of the directory
+func IsInsideDir(path, dir string) bool {
+	relPath, err := filepath.Rel(dir, path)
+
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
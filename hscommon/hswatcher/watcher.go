@@ -0,0 +1,231 @@
+// Package hswatcher watches a directory tree for changes made outside of HellSpawner
+package hswatcher
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultInterval is the default time between two scans of the watched directory
+	DefaultInterval = time.Second
+	// DefaultDebounce is the default time the directory has to stay unchanged before changes are reported
+	DefaultDebounce = 500 * time.Millisecond
+)
+
+// ChangeType describes what happened to a file
+type ChangeType int
+
+// Change types
+const (
+	ChangeCreated ChangeType = iota
+	ChangeModified
+	ChangeRemoved
+)
+
+// String returns the name of the change type
+func (c ChangeType) String() string {
+	switch c {
+	case ChangeCreated:
+		return "created"
+	case ChangeModified:
+		return "modified"
+	case ChangeRemoved:
+		return "removed"
+	}
+
+	return "unknown"
+}
+
+// Change is a single changed file or directory
+type Change struct {
+	Path  string
+	Type  ChangeType
+	IsDir bool
+}
+
+// Callback is called with all changes that happened since the last call.
+// It is called from the watcher's goroutine.
+type Callback func(changes []Change)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	isDir   bool
+}
+
+// Watcher periodically scans a directory tree and reports the files which were created,
+// modified or removed. Bursts of changes (e.g. a git checkout) are reported in a single batch,
+// once the directory stopped changing for the debounce duration.
+//
+// Polling is used instead of OS notifications (fsnotify): those are not recursive, so every directory
+// of the content tree would need its own watch, which quickly exceeds the inotify watch limit of
+// large mods; they are not delivered for network drives and shared folders on every platform;
+// and the atomic saves of most editors (write a temporary file, rename it) arrive as
+// remove/create pairs which have to be reconciled against the tree anyway.
+// A scan of the content directory every second is cheap compared to that.
+type Watcher struct {
+	root     string
+	interval time.Duration
+	debounce time.Duration
+	callback Callback
+
+	files      map[string]fileState
+	pending    map[string]Change
+	lastChange time.Time
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// New creates a new watcher for the given directory, using the default interval and debounce duration
+func New(root string, callback Callback) *Watcher {
+	return &Watcher{
+		root:     root,
+		interval: DefaultInterval,
+		debounce: DefaultDebounce,
+		callback: callback,
+		pending:  make(map[string]Change),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start takes the initial snapshot of the directory and starts watching it in the background
+func (w *Watcher) Start() {
+	w.files = w.scan()
+
+	go w.run()
+}
+
+// Stop stops the watcher, pending changes are discarded
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		<-w.done
+	})
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+func (w *Watcher) poll() {
+	current := w.scan()
+
+	for path, state := range current {
+		old, found := w.files[path]
+
+		switch {
+		case !found:
+			w.addChange(Change{Path: path, Type: ChangeCreated, IsDir: state.isDir})
+		case state.isDir != old.isDir:
+			w.addChange(Change{Path: path, Type: ChangeRemoved, IsDir: old.isDir})
+			w.addChange(Change{Path: path, Type: ChangeCreated, IsDir: state.isDir})
+		case !state.isDir && (!state.modTime.Equal(old.modTime) || state.size != old.size):
+			w.addChange(Change{Path: path, Type: ChangeModified})
+		}
+	}
+
+	for path, old := range w.files {
+		if _, found := current[path]; !found {
+			w.addChange(Change{Path: path, Type: ChangeRemoved, IsDir: old.isDir})
+		}
+	}
+
+	w.files = current
+
+	if len(w.pending) == 0 || time.Since(w.lastChange) < w.debounce {
+		return
+	}
+
+	changes := make([]Change, 0, len(w.pending))
+	for _, change := range w.pending {
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	w.pending = make(map[string]Change)
+
+	w.callback(changes)
+}
+
+// addChange merges the change with a pending change of the same path
+func (w *Watcher) addChange(change Change) {
+	w.lastChange = time.Now()
+
+	old, found := w.pending[change.Path]
+	if !found {
+		w.pending[change.Path] = change
+		return
+	}
+
+	switch {
+	case old.Type == ChangeCreated && change.Type == ChangeRemoved:
+		// the file only existed for a moment, nobody has seen it
+		delete(w.pending, change.Path)
+	case old.Type == ChangeCreated:
+		// still a new file, no matter how often it was modified
+		w.pending[change.Path] = Change{Path: change.Path, Type: ChangeCreated, IsDir: change.IsDir}
+	case old.Type == ChangeRemoved && change.Type == ChangeCreated && old.IsDir == change.IsDir:
+		w.pending[change.Path] = Change{Path: change.Path, Type: ChangeModified, IsDir: change.IsDir}
+	default:
+		w.pending[change.Path] = change
+	}
+}
+
+// scan returns the state of every file and directory below the root, hidden (dot-prefixed) entries are skipped
+func (w *Watcher) scan() map[string]fileState {
+	result := make(map[string]fileState)
+
+	err := filepath.Walk(w.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files can disappear while walking, they will be reported as removed on the next scan
+			return nil
+		}
+
+		if path == w.root {
+			return nil
+		}
+
+		if info.Name()[0] == '.' {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		result[path] = fileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+			isDir:   info.IsDir(),
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("failed to scan %s: %s", w.root, err)
+	}
+
+	return result
+}
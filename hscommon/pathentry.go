@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2mpq"
 )
@@ -53,6 +54,9 @@ type PathEntry struct {
 
 	// MPQFile represents the full path to the MPQ that contains this file (if this is an MPQ path).
 	MPQFile string `json:"mpq_file"`
+
+	// Resolved is the project or MPQ path entry that provides the data of a virtual path entry.
+	Resolved *PathEntry `json:"resolved,omitempty"`
 }
 
 // GetUniqueID returns path's ID
@@ -60,8 +64,33 @@ func (p *PathEntry) GetUniqueID() string {
 	return fmt.Sprintf("%d_%s_%s", p.Source, p.MPQFile, p.FullPath)
 }
 
+// GetSourceName returns a human readable name of the place the file comes from
+// (the project, or the file name of the MPQ).
+func (p *PathEntry) GetSourceName() string {
+	switch p.Source {
+	case PathEntrySourceProject:
+		return "Project"
+	case PathEntrySourceMPQ:
+		return filepath.Base(p.MPQFile)
+	case PathEntryVirtual:
+		if p.Resolved != nil {
+			return p.Resolved.GetSourceName()
+		}
+	}
+
+	return ""
+}
+
 // GetFileBytes reads the file and returns the contents
 func (p *PathEntry) GetFileBytes() ([]byte, error) {
+	if p.Source == PathEntryVirtual {
+		if p.Resolved == nil {
+			return nil, errors.New("virtual path does not resolve to any file")
+		}
+
+		return p.Resolved.GetFileBytes()
+	}
+
 	if p.Source == PathEntrySourceProject {
 		if _, err := os.Stat(p.FullPath); os.IsNotExist(err) {
 			return nil, err
@@ -70,6 +99,10 @@ func (p *PathEntry) GetFileBytes() ([]byte, error) {
 		return ioutil.ReadFile(p.FullPath)
 	}
 
+	if reader := getArchiveReader(); reader != nil {
+		return reader.ReadFile(p.MPQFile, p.FullPath)
+	}
+
 	mpq, err := d2mpq.FromFile(p.MPQFile)
 	if err != nil {
 		return nil, err
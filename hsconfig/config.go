@@ -2,12 +2,15 @@ package hsconfig
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hshistory"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
 
 	"github.com/kirsle/configdir"
@@ -17,8 +20,14 @@ const (
 	newFileMode = 0644
 )
 
+const (
+	// LoadOrderArgument is replaced by the project's load order in AbyssEngineArguments
+	LoadOrderArgument = "{LoadOrder}"
+)
+
 const (
 	maxRecentOpenedProjectsCount = 5
+	day                          = 24 * time.Hour
 )
 
 // Config represents HellSpawner's config
@@ -29,6 +38,17 @@ type Config struct {
 	ExternalListFile        string
 	OpenMostRecentOnStartup bool
 	ProjectStates           map[string]hsstate.AppState
+	// AbyssEngineArguments are the command line arguments the engine is launched with, separated by spaces.
+	// LoadOrderArgument is replaced by the paths of the project's content folder and auxiliary MPQs,
+	// one argument each, highest priority first.
+	AbyssEngineArguments string
+	// ProjectTemplateDirs are directories containing user defined project templates.
+	// Each one is either a template itself (it contains a template.json) or contains templates in its subdirectories.
+	ProjectTemplateDirs []string
+	// LocalHistoryMaxRevisions is the number of revisions the local history keeps per file, 0 keeps every revision
+	LocalHistoryMaxRevisions int
+	// LocalHistoryMaxDays is the number of days the local history keeps revisions, 0 keeps them forever
+	LocalHistoryMaxDays int
 }
 
 func getConfigPath() string {
@@ -37,15 +57,31 @@ func getConfigPath() string {
 		log.Fatal(err)
 	}
 
-	return filepath.Join(configPath, "environment.json")
+	return configFilePath()
 }
 
-func generateDefaultConfig() *Config {
-	result := &Config{
-		RecentProjects:          []string{},
-		OpenMostRecentOnStartup: true,
-		ProjectStates:           make(map[string]hsstate.AppState),
+// configFilePath returns the path of the config file, without creating its directory
+func configFilePath() string {
+	return filepath.Join(configdir.LocalConfig("hellspawner"), "environment.json")
+}
+
+// ListfileCacheDir returns the directory containing the file names harvested from MPQs without a listfile
+func ListfileCacheDir() string {
+	return filepath.Join(configdir.LocalConfig("hellspawner"), "listfiles")
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		RecentProjects:           []string{},
+		OpenMostRecentOnStartup:  true,
+		ProjectStates:            make(map[string]hsstate.AppState),
+		LocalHistoryMaxRevisions: hshistory.DefaultMaxRevisions,
+		LocalHistoryMaxDays:      int(hshistory.DefaultMaxAge / day),
 	}
+}
+
+func generateDefaultConfig() *Config {
+	result := defaultConfig()
 
 	err := result.Save()
 	if err != nil {
@@ -55,28 +91,37 @@ func generateDefaultConfig() *Config {
 	return result
 }
 
-// Load loads config
+// Load loads config. A default config is saved if there is none or it can't be read.
 func Load() *Config {
-	configFile := getConfigPath()
+	if _, err := os.Stat(configFilePath()); os.IsNotExist(err) {
+		return generateDefaultConfig()
+	}
 
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+	result, err := LoadReadOnly()
+	if err != nil {
 		return generateDefaultConfig()
 	}
 
-	var err error
+	return result
+}
 
-	var data []byte
+// LoadReadOnly loads the config without ever writing it, for the tools which only read the preferences
+// (e.g. the command line interface). The defaults are returned if there is no config yet.
+func LoadReadOnly() (*Config, error) {
+	result := defaultConfig()
 
-	if data, err = ioutil.ReadFile(filepath.Clean(configFile)); err != nil {
-		return generateDefaultConfig()
+	data, err := ioutil.ReadFile(filepath.Clean(configFilePath()))
+	if os.IsNotExist(err) {
+		return result, nil
+	} else if err != nil {
+		return nil, err
 	}
 
-	result := generateDefaultConfig()
-	if err = json.Unmarshal(data, &result); err != nil {
-		return generateDefaultConfig()
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("could not read the preferences: %w", err)
 	}
 
-	return result
+	return result, nil
 }
 
 // Save saves a new config
@@ -132,6 +177,14 @@ func (c *Config) AddToRecentProjects(filePath string) {
 	}
 }
 
+// LocalHistoryRetention returns the retention policy of the local history
+func (c *Config) LocalHistoryRetention() hshistory.RetentionPolicy {
+	return hshistory.RetentionPolicy{
+		MaxRevisions: c.LocalHistoryMaxRevisions,
+		MaxAge:       time.Duration(c.LocalHistoryMaxDays) * day,
+	}
+}
+
 // GetAuxMPQs returns paths to auxiliary mpq's
 func (c *Config) GetAuxMPQs() []string {
 	if c.AuxiliaryMpqPath == "" {
@@ -10,13 +10,15 @@ import (
 	"github.com/ianling/imgui-go"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
 
 	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsimage"
 )
 
 const (
-	// nolint:gomnd // it was constant
-	maxAlpha = uint8(255)
+	// originCrosshairSize is the length of the crosshair's arms, in pixels of the frame
+	originCrosshairSize = 8
 )
 
 // DC6ViewerState represents dc6 viewer's state
@@ -32,6 +34,13 @@ type DC6ViewerState struct {
 	framesPerDirection uint32
 	texture            *g.Texture
 	rgb                []*image2.RGBA
+	palette            d2interface.Palette
+	dc6                *d2dc6.DC6
+
+	// the position of the mouse when the frame started to be dragged, and the distance applied since then
+	dragging    bool
+	dragStart   image2.Point
+	dragApplied image2.Point
 }
 
 // Dispose cleans state content
@@ -44,21 +53,65 @@ type DC6ViewerWidget struct {
 	id            string
 	dc6           *d2dc6.DC6
 	textureLoader *hscommon.TextureLoader
+	palette       d2interface.Palette
+	onOriginMove  func(dx, dy int32)
 }
 
 // DC6Viewer creates new DC6ViewerWidget
 func DC6Viewer(textureLoader *hscommon.TextureLoader, id string, dc6 *d2dc6.DC6) *DC6ViewerWidget {
 	result := &DC6ViewerWidget{
-		id:  id,
-		dc6: dc6,
+		id:            id,
+		dc6:           dc6,
+		textureLoader: textureLoader,
 	}
 
 	return result
 }
 
+// Palette sets the palette the frames are drawn with, without a palette they are drawn in shades of gray
+func (p *DC6ViewerWidget) Palette(palette d2interface.Palette) *DC6ViewerWidget {
+	p.palette = palette
+	return p
+}
+
+// Origin shows the frame at its offset from the origin of the sprite, which is marked by a crosshair.
+// Dragging the frame calls onMove with the distance it was dragged, in pixels of the frame.
+func (p *DC6ViewerWidget) Origin(onMove func(dx, dy int32)) *DC6ViewerWidget {
+	p.onOriginMove = onMove
+	return p
+}
+
+// CurrentFrame returns the direction and the frame (in the direction) which are shown
+func (p *DC6ViewerWidget) CurrentFrame() (direction, frame int32) {
+	state := g.Context.GetState(p.getStateID())
+	if state == nil {
+		return 0, 0
+	}
+
+	viewerState := state.(*DC6ViewerState)
+
+	return viewerState.controls.direction, viewerState.controls.frame
+}
+
+// SetCurrentFrame shows a frame of a direction
+func (p *DC6ViewerWidget) SetCurrentFrame(direction, frame int32) {
+	state := g.Context.GetState(p.getStateID())
+	if state == nil {
+		return
+	}
+
+	viewerState := state.(*DC6ViewerState)
+	viewerState.controls.direction = direction
+	viewerState.controls.frame = frame
+}
+
+func (p *DC6ViewerWidget) getStateID() string {
+	return fmt.Sprintf("DC6ViewerWidget_%s", p.id)
+}
+
 // Build builds a widget
 func (p *DC6ViewerWidget) Build() {
-	stateID := fmt.Sprintf("DC6ViewerWidget_%s", p.id)
+	stateID := p.getStateID()
 
 	state := g.Context.GetState(stateID)
 	if state == nil {
@@ -66,6 +119,17 @@ func (p *DC6ViewerWidget) Build() {
 	} else {
 		viewerState := state.(*DC6ViewerState)
 
+		if viewerState.palette != p.palette || viewerState.dc6 != p.dc6 {
+			// the frames have to be drawn again, with the colors of the new palette or because the DC6 was replaced
+			viewerState.palette = p.palette
+			viewerState.dc6 = p.dc6
+			viewerState.framesPerDirection = p.dc6.FramesPerDirection
+			viewerState.controls.direction = clampInt32(viewerState.controls.direction, 0, int32(p.dc6.Directions)-1)
+			viewerState.controls.frame = clampInt32(viewerState.controls.frame, 0, int32(p.dc6.FramesPerDirection)-1)
+			viewerState.rgb = hsimage.DC6Images(p.dc6, p.palette)
+			viewerState.lastFrame = -1
+		}
+
 		vs := (viewerState.lastDirection != viewerState.controls.direction || viewerState.lastFrame != viewerState.controls.frame)
 		if !viewerState.loadingTexture && vs {
 			// Control values have changed, need to regenerate the texture
@@ -100,12 +164,16 @@ func (p *DC6ViewerWidget) Build() {
 			log.Print(err)
 		}
 
-		var widget *g.ImageWidget
+		var widget g.Widget
 		w := float32(p.dc6.Frames[curFrameIndex].Width * imageScale)
 		h := float32(p.dc6.Frames[curFrameIndex].Height * imageScale)
-		if viewerState.texture == nil {
+
+		switch {
+		case p.onOriginMove != nil:
+			widget = p.makeOriginCanvas(viewerState, p.dc6.Frames[curFrameIndex], int(imageScale))
+		case viewerState.texture == nil:
 			widget = g.Image(nil).Size(w, h)
-		} else {
+		default:
 			widget = g.Image(viewerState.texture).Size(w, h)
 		}
 
@@ -145,35 +213,88 @@ func (p *DC6ViewerWidget) buildNew(stateID string) {
 		lastFrame:          -1,
 		lastDirection:      -1,
 		framesPerDirection: p.dc6.FramesPerDirection,
+		palette:            p.palette,
+		dc6:                p.dc6,
 	}
 
 	sw := float32(p.dc6.Frames[0].Width)
 	sh := float32(p.dc6.Frames[0].Height)
 	widget = g.Image(nil).Size(sw, sh)
 
-	newState.rgb = make([]*image2.RGBA, p.dc6.Directions*p.dc6.FramesPerDirection)
+	newState.rgb = hsimage.DC6Images(p.dc6, p.palette)
 
-	for frameIndex := 0; frameIndex < int(p.dc6.Directions*p.dc6.FramesPerDirection); frameIndex++ {
-		newState.rgb[frameIndex] = image2.NewRGBA(image2.Rect(0, 0, int(p.dc6.Frames[frameIndex].Width), int(p.dc6.Frames[frameIndex].Height)))
-		decodedFrame := p.dc6.DecodeFrame(frameIndex)
+	g.Context.SetState(stateID, newState)
 
-		for y := 0; y < int(p.dc6.Frames[frameIndex].Height); y++ {
-			for x := 0; x < int(p.dc6.Frames[frameIndex].Width); x++ {
-				idx := x + (y * int(p.dc6.Frames[frameIndex].Width))
-				val := decodedFrame[idx]
+	widget.Build()
+}
 
-				alpha := maxAlpha
+// makeOriginCanvas draws the frame at its offset, around a crosshair marking the origin
+func (p *DC6ViewerWidget) makeOriginCanvas(state *DC6ViewerState, frame *d2dc6.DC6Frame, scale int) g.Widget {
+	return g.Custom(func() {
+		frameRect := image2.Rect(0, 0, int(frame.Width), int(frame.Height)).Add(image2.Pt(int(frame.OffsetX), int(frame.OffsetY)))
+		crosshair := image2.Rect(-originCrosshairSize, -originCrosshairSize, originCrosshairSize+1, originCrosshairSize+1)
+		bounds := frameRect.Union(crosshair)
 
-				if val == 0 {
-					alpha = 0
-				}
+		pos := g.GetCursorScreenPos()
+		toScreen := func(pt image2.Point) image2.Point {
+			return pos.Add(pt.Sub(bounds.Min).Mul(scale))
+		}
+
+		canvas := g.GetCanvas()
+
+		// nolint:gomnd // const
+		frameColor, crosshairColor := color.RGBA{R: 128, G: 128, B: 128, A: 255}, color.RGBA{R: 255, G: 64, B: 64, A: 255}
 
-				newState.rgb[frameIndex].Set(x, y, color.RGBA{R: val, G: val, B: val, A: alpha})
-			}
+		if state.texture != nil {
+			canvas.AddImage(state.texture, toScreen(frameRect.Min), toScreen(frameRect.Max))
 		}
+
+		canvas.AddRect(toScreen(frameRect.Min), toScreen(frameRect.Max), frameColor, 0, 0, 1)
+
+		origin := toScreen(image2.Point{})
+		length := originCrosshairSize * scale
+		canvas.AddLine(origin.Sub(image2.Pt(length, 0)), origin.Add(image2.Pt(length, 0)), crosshairColor, 1)
+		canvas.AddLine(origin.Sub(image2.Pt(0, length)), origin.Add(image2.Pt(0, length)), crosshairColor, 1)
+
+		// the button takes the space of the canvas and makes the frame draggable
+		size := bounds.Size().Mul(scale)
+		g.InvisibleButton(fmt.Sprintf("##DC6ViewerOrigin_%s", p.id)).Size(float32(size.X), float32(size.Y)).Build()
+		p.dragFrame(state, scale)
+	})
+}
+
+// dragFrame moves the frame while the origin canvas is dragged, it has to be called after the canvas' button
+func (p *DC6ViewerWidget) dragFrame(state *DC6ViewerState, scale int) {
+	if !g.IsItemActive() {
+		state.dragging = false
+		return
 	}
 
-	g.Context.SetState(stateID, newState)
+	mouse := g.GetMousePos()
 
-	widget.Build()
+	if !state.dragging {
+		state.dragging = true
+		state.dragStart = mouse
+		state.dragApplied = image2.Point{}
+
+		return
+	}
+
+	moved := mouse.Sub(state.dragStart).Div(scale)
+
+	if step := moved.Sub(state.dragApplied); step != (image2.Point{}) {
+		state.dragApplied = moved
+		p.onOriginMove(int32(step.X), int32(step.Y))
+	}
+}
+
+func clampInt32(value, min, max int32) int32 {
+	switch {
+	case value < min:
+		return min
+	case value > max:
+		return max
+	default:
+		return value
+	}
 }
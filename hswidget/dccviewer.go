@@ -3,13 +3,15 @@ package hswidget
 import (
 	"fmt"
 	image2 "image"
-	"image/color"
 	"log"
 
 	"github.com/ianling/giu"
 	"github.com/ianling/imgui-go"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dcc"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsimage"
 )
 
 const (
@@ -25,6 +27,8 @@ type DCCViewerState struct {
 	}
 
 	textures []*giu.Texture
+	palette  d2interface.Palette
+	dcc      *d2dcc.DCC
 }
 
 // Dispose cleans viewers state
@@ -34,8 +38,9 @@ func (is *DCCViewerState) Dispose() {
 
 // DCCViewerWidget creates a new dcc widget
 type DCCViewerWidget struct {
-	id  string
-	dcc *d2dcc.DCC
+	id      string
+	dcc     *d2dcc.DCC
+	palette d2interface.Palette
 }
 
 // DCCViewer creates a new dcc viewers widget
@@ -48,6 +53,12 @@ func DCCViewer(id string, dcc *d2dcc.DCC) *DCCViewerWidget {
 	return result
 }
 
+// Palette sets the palette the frames are drawn with, without a palette they are drawn in shades of gray
+func (p *DCCViewerWidget) Palette(palette d2interface.Palette) *DCCViewerWidget {
+	p.palette = palette
+	return p
+}
+
 // Build build a widget
 // nolint:funlen // no need to change
 func (p *DCCViewerWidget) Build() {
@@ -59,6 +70,20 @@ func (p *DCCViewerWidget) Build() {
 	} else {
 		viewerState := state.(*DCCViewerState)
 
+		if viewerState.dcc != p.dcc {
+			// another DCC was imported, it may have fewer directions and frames
+			viewerState.controls.direction = clampInt32(viewerState.controls.direction, 0, int32(p.dcc.NumberOfDirections-1))
+			viewerState.controls.frame = clampInt32(viewerState.controls.frame, 0, int32(p.dcc.FramesPerDirection-1))
+		}
+
+		if viewerState.palette != p.palette || viewerState.dcc != p.dcc {
+			// the frames have to be drawn again, with the colors of the new palette or from the new DCC
+			viewerState.palette = p.palette
+			viewerState.dcc = p.dcc
+			viewerState.textures = nil
+			p.makeTextures(stateID)
+		}
+
 		imageScale := uint32(viewerState.controls.scale)
 		dirIdx := int(viewerState.controls.direction)
 		frameIdx := viewerState.controls.frame
@@ -116,8 +141,20 @@ func (p *DCCViewerWidget) Build() {
 
 func (p *DCCViewerWidget) buildNew(stateID string) {
 	// Prevent multiple invocation to LoadImage.
-	giu.Context.SetState(stateID, &DCCViewerState{})
+	giu.Context.SetState(stateID, &DCCViewerState{palette: p.palette, dcc: p.dcc})
+
+	p.makeTextures(stateID)
+
+	// display a temporary dummy image until the real one ready
+	firstFrame := p.dcc.Directions[0].Frames[0]
+	sw := float32(firstFrame.Width)
+	sh := float32(firstFrame.Height)
+	widget := giu.Image(nil).Size(sw, sh)
+	widget.Build()
+}
 
+// makeTextures draws every frame with the viewer's palette, the textures are added to the state once they are created
+func (p *DCCViewerWidget) makeTextures(stateID string) {
 	totalFrames := p.dcc.NumberOfDirections * p.dcc.FramesPerDirection
 	images := make([]*image2.RGBA, totalFrames)
 
@@ -140,22 +177,14 @@ func (p *DCCViewerWidget) buildNew(stateID string) {
 						continue
 					}
 
-					val := pixels[idx]
-
-					alpha := maxAlpha
-
-					if val == 0 {
-						alpha = 0
-					}
-
-					RGBAcolor := color.RGBA{R: val, G: val, B: val, A: alpha}
-
-					images[absoluteFrameIdx].Set(x, y, RGBAcolor)
+					images[absoluteFrameIdx].Set(x, y, hsimage.Color(p.palette, pixels[idx]))
 				}
 			}
 		}
 	}
 
+	palette, dcc := p.palette, p.dcc
+
 	go func() {
 		textures := make([]*giu.Texture, totalFrames)
 
@@ -167,13 +196,13 @@ func (p *DCCViewerWidget) buildNew(stateID string) {
 				log.Fatal(err)
 			}
 		}
-		giu.Context.SetState(stateID, &DCCViewerState{textures: textures})
-	}()
 
-	// display a temporary dummy image until the real one ready
-	firstFrame := p.dcc.Directions[0].Frames[0]
-	sw := float32(firstFrame.Width)
-	sh := float32(firstFrame.Height)
-	widget := giu.Image(nil).Size(sw, sh)
-	widget.Build()
+		viewerState, ok := giu.Context.GetState(stateID).(*DCCViewerState)
+		if !ok || viewerState.palette != palette || viewerState.dcc != dcc {
+			// the viewer was closed, or another palette or DCC was selected in the meantime
+			return
+		}
+
+		viewerState.textures = textures
+	}()
 }
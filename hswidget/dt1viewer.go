@@ -9,10 +9,12 @@ import (
 	"github.com/ianling/giu"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dt1"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math"
 
 	"github.com/OpenDiablo2/HellSpawner/hscommon"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsenum"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsimage"
 )
 
 const (
@@ -44,6 +46,7 @@ type DT1ViewerState struct {
 	*dt1Controls
 
 	lastTileGroup int32
+	palette       d2interface.Palette
 
 	tileGroups [][]*d2dt1.Tile
 	textures   [][]map[string]*giu.Texture
@@ -66,6 +69,7 @@ type DT1ViewerWidget struct {
 	id            string
 	dt1           *d2dt1.DT1
 	textureLoader *hscommon.TextureLoader
+	palette       d2interface.Palette
 }
 
 // DT1Viewer creates a new dt1 viewers widget
@@ -81,6 +85,12 @@ func DT1Viewer(textureLoader *hscommon.TextureLoader, id string, dt1 *d2dt1.DT1)
 	return result
 }
 
+// Palette sets the palette the tiles are drawn with, without a palette the floors and walls get false colors
+func (p *DT1ViewerWidget) Palette(palette d2interface.Palette) *DT1ViewerWidget {
+	p.palette = palette
+	return p
+}
+
 func (p *DT1ViewerWidget) registerKeyboardShortcuts() {
 	// noop
 }
@@ -117,6 +127,7 @@ func (p *DT1ViewerWidget) initState() {
 			showWall:  true,
 		},
 		tileGroups: p.groupTilesByIdentity(),
+		palette:    p.palette,
 	}
 
 	p.setState(state)
@@ -126,6 +137,12 @@ func (p *DT1ViewerWidget) initState() {
 func (p *DT1ViewerWidget) Build() {
 	state := p.getState()
 
+	if state.palette != p.palette {
+		// the tiles have to be drawn again, with the colors of the new palette
+		state.palette = p.palette
+		p.makeTileTextures()
+	}
+
 	if state.lastTileGroup != state.dt1Controls.tileGroup {
 		state.lastTileGroup = state.dt1Controls.tileGroup
 		state.dt1Controls.tileVariant = 0
@@ -244,10 +261,29 @@ func (p *DT1ViewerWidget) makePixelBuffer(tile *d2dt1.Tile) (floorBuf, wallBuf [
 	decodeTileGfxData(tile.Blocks, &floor, &wall, tileYOffset, tile.Width)
 
 	// nolint:gomnd // constant
-	floorBuf = make([]byte, tw*th*4) // rgba, fake palette values
+	floorBuf = make([]byte, tw*th*4) // rgba
 	// nolint:gomnd // constant
-	wallBuf = make([]byte, tw*th*4) // rgba, fake palette values
+	wallBuf = make([]byte, tw*th*4) // rgba
+
+	if p.palette == nil {
+		makeFalseColors(floor, wall, floorBuf, wallBuf)
+		return floorBuf, wallBuf
+	}
+
+	for idx := range floor {
+		floorColor, wallColor := hsimage.Color(p.palette, floor[idx]), hsimage.Color(p.palette, wall[idx])
 
+		// nolint:gomnd // constant
+		copy(floorBuf[idx*4:], []byte{floorColor.R, floorColor.G, floorColor.B, floorColor.A})
+		// nolint:gomnd // constant
+		copy(wallBuf[idx*4:], []byte{wallColor.R, wallColor.G, wallColor.B, wallColor.A})
+	}
+
+	return floorBuf, wallBuf
+}
+
+// makeFalseColors draws the palette indices of the floor and the wall in different colors
+func makeFalseColors(floor, wall, floorBuf, wallBuf []byte) {
 	for idx := range floor {
 		var alpha byte
 
@@ -282,8 +318,6 @@ func (p *DT1ViewerWidget) makePixelBuffer(tile *d2dt1.Tile) (floorBuf, wallBuf [
 
 		wallBuf[a] = alpha
 	}
-
-	return floorBuf, wallBuf
 }
 
 func (p *DT1ViewerWidget) makeTileSelector() giu.Layout {
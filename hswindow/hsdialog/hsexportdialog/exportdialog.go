@@ -0,0 +1,70 @@
+// Package hsexportdialog contains the dialog choosing how a project is exported as loose files or a zip archive
+package hsexportdialog
+
+import (
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog"
+)
+
+const (
+	mainWindowW, mainWindowH = 350, 130
+	comboW                   = 250
+)
+
+// ExportDialog represents the export distribution dialog
+type ExportDialog struct {
+	*hsdialog.Dialog
+
+	format             int32
+	lowercase          bool
+	dataPrefix         bool
+	includeEditorFiles bool
+	onExport           func(options hsproject.DistributionOptions)
+}
+
+// Create creates a new export dialog. onExport is called with the selected options when the user confirms the dialog.
+func Create(onExport func(options hsproject.DistributionOptions)) *ExportDialog {
+	result := &ExportDialog{
+		Dialog:     hsdialog.New("Export Distribution"),
+		dataPrefix: true,
+		onExport:   onExport,
+	}
+
+	return result
+}
+
+// Build builds the export dialog
+func (e *ExportDialog) Build() {
+	formats := []string{"Loose files (directory)", "Zip archive"}
+
+	e.IsOpen(&e.Visible).Layout(g.Layout{
+		g.Child("ExportDialogLayout").Size(mainWindowW, mainWindowH).Layout(g.Layout{
+			g.Label("Format:"),
+			g.Combo("##ExportDialogFormat", formats[e.format], formats, &e.format).Size(comboW),
+			g.Checkbox("Place files under data/##ExportDialogDataPrefix", &e.dataPrefix),
+			g.Checkbox("Convert paths to lower case##ExportDialogLowercase", &e.lowercase),
+			g.Checkbox("Include editor-only files (.hsf)##ExportDialogEditorFiles", &e.includeEditorFiles),
+		}),
+		g.Line(
+			g.Button("Export...##ExportDialogExport").OnClick(e.onExportClicked),
+			g.Button("Cancel##ExportDialogCancel").OnClick(e.onCancelClicked),
+		),
+	})
+}
+
+func (e *ExportDialog) onExportClicked() {
+	e.Visible = false
+
+	e.onExport(hsproject.DistributionOptions{
+		Format:             hsproject.DistributionFormat(e.format),
+		Lowercase:          e.lowercase,
+		DataPrefix:         e.dataPrefix,
+		IncludeEditorFiles: e.includeEditorFiles,
+	})
+}
+
+func (e *ExportDialog) onCancelClicked() {
+	e.Visible = false
+}
@@ -0,0 +1,134 @@
+// Package hsmpqexportdialog contains the dialog choosing how a project is exported as an MPQ archive,
+// it shows the progress of the export
+package hsmpqexportdialog
+
+import (
+	"sync"
+
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog"
+)
+
+const (
+	mainWindowW, mainWindowH = 350, 60
+	comboW                   = 250
+)
+
+// MPQExportDialog represents the export MPQ dialog
+type MPQExportDialog struct {
+	*hsdialog.Dialog
+
+	compression int32
+	onExport    func(compression hsproject.CompressionSelector)
+
+	// the export runs in the background, it reports its progress to the dialog
+	mutex     sync.Mutex
+	exporting bool
+	finished  bool
+	cancel    bool
+	progress  float32
+}
+
+// Create creates a new export MPQ dialog. onExport is called with the selected compression when the user
+// confirms the dialog.
+func Create(onExport func(compression hsproject.CompressionSelector)) *MPQExportDialog {
+	result := &MPQExportDialog{
+		Dialog:   hsdialog.New("Export MPQ"),
+		onExport: onExport,
+	}
+
+	return result
+}
+
+// Build builds the export MPQ dialog
+func (e *MPQExportDialog) Build() {
+	e.mutex.Lock()
+	exporting := e.exporting
+	progress := e.progress
+
+	if e.finished {
+		e.finished = false
+		e.Visible = false
+	}
+	e.mutex.Unlock()
+
+	if exporting {
+		e.IsOpen(&e.Visible).Layout(g.Layout{
+			g.Child("MPQExportDialogLayout").Size(mainWindowW, mainWindowH).Layout(g.Layout{
+				g.Label("Exporting..."),
+				g.ProgressBar(progress).Size(-1, 0),
+			}),
+			g.Button("Cancel##MPQExportDialogCancelExport").OnClick(e.onCancelExportClicked),
+		})
+
+		return
+	}
+
+	modes := hsproject.MPQCompressionModes()
+	names := make([]string, len(modes))
+
+	for idx := range modes {
+		names[idx] = modes[idx].Name
+	}
+
+	e.IsOpen(&e.Visible).Layout(g.Layout{
+		g.Child("MPQExportDialogLayout").Size(mainWindowW, mainWindowH).Layout(g.Layout{
+			g.Label("Compression:"),
+			g.Combo("##MPQExportDialogCompression", names[e.compression], names, &e.compression).Size(comboW),
+		}),
+		g.Line(
+			g.Button("Export...##MPQExportDialogExport").OnClick(e.onExportClicked),
+			g.Button("Cancel##MPQExportDialogCancel").OnClick(e.onCancelClicked),
+		),
+	})
+}
+
+// Start shows the progress of an export, which was started by onExport
+func (e *MPQExportDialog) Start() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.exporting = true
+	e.finished = false
+	e.cancel = false
+	e.progress = 0
+	e.Visible = true
+}
+
+// Progress updates the progress of the export, it returns false once the user canceled the export
+func (e *MPQExportDialog) Progress(done, total int) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.progress = float32(done) / float32(total)
+
+	return !e.cancel
+}
+
+// Finish closes the dialog after the export has ended
+func (e *MPQExportDialog) Finish() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.exporting = false
+	e.finished = true
+}
+
+func (e *MPQExportDialog) onExportClicked() {
+	e.Visible = false
+
+	e.onExport(hsproject.MPQCompressionModes()[e.compression].Select)
+}
+
+func (e *MPQExportDialog) onCancelClicked() {
+	e.Visible = false
+}
+
+func (e *MPQExportDialog) onCancelExportClicked() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.cancel = true
+}
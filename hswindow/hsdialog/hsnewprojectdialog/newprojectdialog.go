@@ -0,0 +1,98 @@
+// Package hsnewprojectdialog contains the dialog choosing the template of a new project
+package hsnewprojectdialog
+
+import (
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hsdialog"
+)
+
+const (
+	mainWindowW, mainWindowH = 400, 150
+	comboW                   = 300
+)
+
+// NewProjectDialog represents the new project dialog
+type NewProjectDialog struct {
+	*hsdialog.Dialog
+
+	templates []*hsproject.Template
+	names     []string
+	errors    []string
+	selected  int32
+	onCreate  func(template *hsproject.Template)
+}
+
+// Create creates a new project dialog. onCreate is called with the selected template
+// when the user confirms the dialog.
+func Create(onCreate func(template *hsproject.Template)) *NewProjectDialog {
+	result := &NewProjectDialog{
+		Dialog:   hsdialog.New("New Project"),
+		onCreate: onCreate,
+	}
+
+	return result
+}
+
+// Show shows the dialog, the templates are reloaded from the template directories of the config
+func (p *NewProjectDialog) Show(config *hsconfig.Config) {
+	var errs []error
+
+	p.templates, errs = hsproject.FindTemplates(config)
+	p.names = make([]string, len(p.templates))
+	p.errors = make([]string, len(errs))
+	p.selected = 0
+
+	for idx := range p.templates {
+		p.names[idx] = p.templates[idx].Name
+	}
+
+	for idx := range errs {
+		p.errors[idx] = errs[idx].Error()
+	}
+
+	p.Dialog.Show()
+}
+
+// Build builds the new project dialog
+func (p *NewProjectDialog) Build() {
+	if len(p.templates) == 0 {
+		return
+	}
+
+	template := p.templates[p.selected]
+
+	content := g.Layout{
+		g.Label("Template:"),
+		g.Combo("##NewProjectDialogTemplate", template.Name, p.names, &p.selected).Size(comboW),
+		g.Label(template.Description).Wrapped(true),
+	}
+
+	if len(p.errors) > 0 {
+		content = append(content, g.Separator(), g.Label("Some templates could not be loaded:"))
+
+		for idx := range p.errors {
+			content = append(content, g.Label(p.errors[idx]).Wrapped(true))
+		}
+	}
+
+	p.IsOpen(&p.Visible).Layout(g.Layout{
+		g.Child("NewProjectDialogLayout").Size(mainWindowW, mainWindowH).Layout(content),
+		g.Line(
+			g.Button("Create...##NewProjectDialogCreate").OnClick(p.onCreateClicked),
+			g.Button("Cancel##NewProjectDialogCancel").OnClick(p.onCancelClicked),
+		),
+	})
+}
+
+func (p *NewProjectDialog) onCreateClicked() {
+	p.Visible = false
+
+	p.onCreate(p.templates[p.selected])
+}
+
+func (p *NewProjectDialog) onCancelClicked() {
+	p.Visible = false
+}
@@ -10,7 +10,9 @@ import (
 )
 
 const (
-	mainWindowW, mainWindowH = 300, 200
+	mainWindowW, mainWindowH = 300, 360
+	templateListH            = 60
+	historyInputW            = 100
 	textboxSize              = 245
 	btnW, btnH               = 30, 0
 )
@@ -21,6 +23,10 @@ type PreferencesDialog struct {
 
 	config          *hsconfig.Config
 	onConfigChanged func(config *hsconfig.Config)
+
+	selectedTemplateDir int
+	historyMaxRevisions int32
+	historyMaxDays      int32
 }
 
 // Create creates a new preferences dialog
@@ -55,8 +61,29 @@ func (p *PreferencesDialog) Build() {
 				g.InputText("##AppPreferencesAbyssEnginePath", &p.config.AbyssEnginePath).Size(textboxSize).Flags(g.InputTextFlagsReadOnly),
 				g.Button("...##AppPreferencesAbyssEnginePathBrowse").Size(btnW, btnH).OnClick(p.onBrowseAbyssEngineClicked),
 			),
+			g.Label("Abyss Engine Arguments (" + hsconfig.LoadOrderArgument + " is replaced by the project's load order)"),
+			g.InputText("##AppPreferencesAbyssEngineArguments", &p.config.AbyssEngineArguments).Size(textboxSize + btnW),
 			g.Separator(),
 			g.Checkbox("Open most recent project on start-up", &p.config.OpenMostRecentOnStartup),
+			g.Separator(),
+			g.Label("Project template directories"),
+			g.ListBox("##AppPreferencesTemplateDirs", p.config.ProjectTemplateDirs).Size(0, templateListH).OnChange(func(selectedIndex int) {
+				p.selectedTemplateDir = selectedIndex
+			}),
+			g.Line(
+				g.Button("Add...##AppPreferencesTemplateDirsAdd").OnClick(p.onAddTemplateDirClicked),
+				g.Button("Remove##AppPreferencesTemplateDirsRemove").OnClick(p.onRemoveTemplateDirClicked),
+			),
+			g.Separator(),
+			g.Label("Local history (0 = no limit)"),
+			g.Line(
+				g.InputInt("Revisions per file##AppPreferencesHistoryRevisions", &p.historyMaxRevisions).Size(historyInputW).
+					OnChange(p.onLocalHistoryChanged),
+			),
+			g.Line(
+				g.InputInt("Days##AppPreferencesHistoryDays", &p.historyMaxDays).Size(historyInputW).
+					OnChange(p.onLocalHistoryChanged),
+			),
 		}),
 		g.Line(
 			g.Button("Save##AppPreferencesSave").OnClick(p.onSaveClicked),
@@ -70,6 +97,8 @@ func (p *PreferencesDialog) Show(config *hsconfig.Config) {
 	p.Dialog.Show()
 
 	p.config = config
+	p.historyMaxRevisions = int32(config.LocalHistoryMaxRevisions)
+	p.historyMaxDays = int32(config.LocalHistoryMaxDays)
 }
 
 func (p *PreferencesDialog) onBrowseAuxMpqPathClicked() {
@@ -93,6 +122,44 @@ func (p *PreferencesDialog) onBrowseExternalListfileClicked() {
 	p.config.ExternalListFile = filePath
 }
 
+func (p *PreferencesDialog) onLocalHistoryChanged() {
+	if p.historyMaxRevisions < 0 {
+		p.historyMaxRevisions = 0
+	}
+
+	if p.historyMaxDays < 0 {
+		p.historyMaxDays = 0
+	}
+
+	p.config.LocalHistoryMaxRevisions = int(p.historyMaxRevisions)
+	p.config.LocalHistoryMaxDays = int(p.historyMaxDays)
+}
+
+func (p *PreferencesDialog) onAddTemplateDirClicked() {
+	path, err := dialog.Directory().Browse()
+	if err != nil || path == "" {
+		return
+	}
+
+	for _, dir := range p.config.ProjectTemplateDirs {
+		if dir == path {
+			return
+		}
+	}
+
+	p.config.ProjectTemplateDirs = append(p.config.ProjectTemplateDirs, path)
+}
+
+func (p *PreferencesDialog) onRemoveTemplateDirClicked() {
+	dirs := p.config.ProjectTemplateDirs
+	if p.selectedTemplateDir < 0 || p.selectedTemplateDir >= len(dirs) {
+		return
+	}
+
+	p.config.ProjectTemplateDirs = append(dirs[:p.selectedTemplateDir:p.selectedTemplateDir], dirs[p.selectedTemplateDir+1:]...)
+	p.selectedTemplateDir = 0
+}
+
 func (p *PreferencesDialog) onSaveClicked() {
 	p.onConfigChanged(p.config)
 	p.Visible = false
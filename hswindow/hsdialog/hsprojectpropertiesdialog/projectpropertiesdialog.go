@@ -33,6 +33,12 @@ const (
 	downItemButtonPath   = "3rdparty/iconpack-obsidian/Obsidian/actions/16/stock_down.png"
 )
 
+const (
+	// contentFolderEntry stands for the project's content folder in the load order, MPQ paths are never empty
+	contentFolderEntry = ""
+	contentFolderLabel = "(project content folder)"
+)
+
 // ProjectPropertiesDialog represent project properties' dialog
 type ProjectPropertiesDialog struct {
 	*hsdialog.Dialog
@@ -44,6 +50,8 @@ type ProjectPropertiesDialog struct {
 	config                     *hsconfig.Config
 	onProjectPropertiesChanged func(project *hsproject.Project)
 	auxMPQs, auxMPQNames       []string
+	// loadOrder contains the auxiliary MPQs and the content folder by priority
+	loadOrder []string
 
 	mpqSelectDlgIndex      int
 	mpqSelectDialogVisible bool
@@ -77,6 +85,17 @@ func (p *ProjectPropertiesDialog) Show(project *hsproject.Project, config *hscon
 	p.config = config
 	p.project = *project
 	p.auxMPQs = config.GetAuxMPQs()
+	p.loadOrder = make([]string, 0, len(project.AuxiliaryMPQs)+1)
+
+	for _, source := range project.LoadOrder() {
+		if source.IsContentFolder() {
+			p.loadOrder = append(p.loadOrder, contentFolderEntry)
+			continue
+		}
+
+		p.loadOrder = append(p.loadOrder, source.Name)
+	}
+
 	p.auxMPQNames = make([]string, len(p.auxMPQs))
 
 	for idx := range p.auxMPQNames {
@@ -129,52 +148,18 @@ func (p *ProjectPropertiesDialog) Build() {
 					g.InputText("##ProjectPropertiesDialogAuthor", &p.project.Author).Size(inputTextSize),
 				}),
 				g.Child("ProjectPropertiesLayout2").Size(mpqSelectW, mpqSelectH).Layout(g.Layout{
-					g.Label("Auxiliary MPQs:"),
+					g.Label("Load order (first wins):"),
 					g.Child("ProjectPropertiesAuxMpqLayoutGroup").Border(false).Size(mpqGroupW, mpqGroupH).Layout(g.Layout{
 						g.Custom(func() {
 							imgui.PushStyleColor(imgui.StyleColorButton, imgui.Vec4{})
 							imgui.PushStyleColor(imgui.StyleColorBorder, imgui.Vec4{})
 							imgui.PushStyleVarVec2(imgui.StyleVarItemSpacing, imgui.Vec2{})
-							for idx := range p.project.AuxiliaryMPQs {
-								currentIdx := idx
-
-								if idx >= len(p.project.AuxiliaryMPQs) {
+							for idx := range p.loadOrder {
+								if idx >= len(p.loadOrder) {
 									break
 								}
 
-								g.Line(
-									g.Custom(func() {
-										imgui.PushID(fmt.Sprintf("ProjectPropertiesAddAuxMpqRemove_%d", currentIdx))
-									}),
-
-									g.ImageButton(p.removeIconTexture).Size(imgBtnW, imgBtnH).OnClick(func() {
-										copy(p.project.AuxiliaryMPQs[currentIdx:], p.project.AuxiliaryMPQs[currentIdx+1:])
-										p.project.AuxiliaryMPQs = p.project.AuxiliaryMPQs[:len(p.project.AuxiliaryMPQs)-1]
-									}),
-									g.Custom(func() {
-										imgui.PopID()
-										imgui.PushID(fmt.Sprintf("ProjectPropertiesAddAuxMpqDown_%d", currentIdx))
-									}),
-									g.ImageButton(p.downIconTexture).Size(imgBtnW, imgBtnH).OnClick(func() {
-										if currentIdx < len(p.project.AuxiliaryMPQs)-1 {
-											p.project.AuxiliaryMPQs[currentIdx] = p.project.AuxiliaryMPQs[currentIdx+1]
-											p.project.AuxiliaryMPQs[currentIdx+1] = p.project.AuxiliaryMPQs[currentIdx]
-										}
-									}),
-									g.Custom(func() {
-										imgui.PopID()
-										imgui.PushID(fmt.Sprintf("ProjectPropertiesAddAuxMpqUp_%d", currentIdx))
-									}),
-									g.ImageButton(p.upIconTexture).Size(imgBtnW, imgBtnH).OnClick(func() {
-										if currentIdx > 0 {
-											p.project.AuxiliaryMPQs[currentIdx-1] = p.project.AuxiliaryMPQs[currentIdx]
-											p.project.AuxiliaryMPQs[currentIdx] = p.project.AuxiliaryMPQs[currentIdx-1]
-										}
-									}),
-									g.Custom(func() { imgui.PopID() }),
-									g.Dummy(dummyW, dummyH),
-									g.Label(p.project.AuxiliaryMPQs[idx]),
-								).Build()
+								p.loadOrderItem(idx).Build()
 							}
 							imgui.PopStyleVar()
 							// nolint:gomnd // const
@@ -203,11 +188,69 @@ func (p *ProjectPropertiesDialog) Build() {
 	}
 }
 
+// loadOrderItem returns the row of an entry of the load order, with buttons to remove it and to move it up and down
+func (p *ProjectPropertiesDialog) loadOrderItem(idx int) *g.LineWidget {
+	label := p.loadOrder[idx]
+
+	var removeButton g.Widget = g.ImageButton(p.removeIconTexture).Size(imgBtnW, imgBtnH).OnClick(func() {
+		p.loadOrder = append(p.loadOrder[:idx], p.loadOrder[idx+1:]...)
+	})
+
+	if label == contentFolderEntry {
+		// the content folder can only be moved
+		label = contentFolderLabel
+		removeButton = g.Dummy(imgBtnW, imgBtnH)
+	}
+
+	return g.Line(
+		g.Custom(func() {
+			imgui.PushID(fmt.Sprintf("ProjectPropertiesAddAuxMpqRemove_%d", idx))
+		}),
+		removeButton,
+		g.Custom(func() {
+			imgui.PopID()
+			imgui.PushID(fmt.Sprintf("ProjectPropertiesAddAuxMpqDown_%d", idx))
+		}),
+		g.ImageButton(p.downIconTexture).Size(imgBtnW, imgBtnH).OnClick(func() {
+			if idx < len(p.loadOrder)-1 {
+				p.loadOrder[idx], p.loadOrder[idx+1] = p.loadOrder[idx+1], p.loadOrder[idx]
+			}
+		}),
+		g.Custom(func() {
+			imgui.PopID()
+			imgui.PushID(fmt.Sprintf("ProjectPropertiesAddAuxMpqUp_%d", idx))
+		}),
+		g.ImageButton(p.upIconTexture).Size(imgBtnW, imgBtnH).OnClick(func() {
+			if idx > 0 {
+				p.loadOrder[idx-1], p.loadOrder[idx] = p.loadOrder[idx], p.loadOrder[idx-1]
+			}
+		}),
+		g.Custom(func() { imgui.PopID() }),
+		g.Dummy(dummyW, dummyH),
+		g.Label(label),
+	)
+}
+
+// applyLoadOrder stores the edited load order in the project's AuxiliaryMPQs and ContentPosition
+func (p *ProjectPropertiesDialog) applyLoadOrder() {
+	p.project.AuxiliaryMPQs = make([]string, 0, len(p.loadOrder))
+
+	for _, entry := range p.loadOrder {
+		if entry == contentFolderEntry {
+			p.project.ContentPosition = len(p.project.AuxiliaryMPQs)
+			continue
+		}
+
+		p.project.AuxiliaryMPQs = append(p.project.AuxiliaryMPQs, entry)
+	}
+}
+
 func (p *ProjectPropertiesDialog) onSaveClicked() {
 	if strings.TrimSpace(p.project.ProjectName) == "" {
 		return
 	}
 
+	p.applyLoadOrder()
 	p.onProjectPropertiesChanged(&p.project)
 	p.Visible = false
 }
@@ -226,11 +269,12 @@ func (p *ProjectPropertiesDialog) addAuxMpq(mpqPath string) {
 		log.Fatal(err)
 	}
 
-	for idx := range p.project.AuxiliaryMPQs {
-		if p.project.AuxiliaryMPQs[idx] == relPath {
+	for idx := range p.loadOrder {
+		if p.loadOrder[idx] == relPath {
 			return
 		}
 	}
 
-	p.project.AuxiliaryMPQs = append(p.project.AuxiliaryMPQs, relPath)
+	p.loadOrder = append(p.loadOrder, relPath)
+	p.applyLoadOrder()
 }
@@ -5,6 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ianling/giu"
 
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
 
@@ -18,15 +23,25 @@ type Editor struct {
 	*hswindow.Window
 	Path    *hscommon.PathEntry
 	Project *hsproject.Project
+	// Palettes is set by the editors of indexed images, it chooses the palette they are drawn with
+	Palettes *PaletteSelector
+
+	// fileModTime is the modification time of the file when it was loaded or last saved
+	fileModTime      time.Time
+	changesDiscarded bool
 }
 
 // New creates a new editor
 func New(path *hscommon.PathEntry, x, y float32, project *hsproject.Project) *Editor {
-	return &Editor{
+	result := &Editor{
 		Window:  hswindow.New(generateWindowTitle(path), x, y),
 		Path:    path,
 		Project: project,
 	}
+
+	result.fileModTime = result.getFileModTime()
+
+	return result
 }
 
 // State returns editors state
@@ -36,10 +51,23 @@ func (e *Editor) State() hsstate.EditorState {
 		log.Print("failed to marshal editor path to JSON: ", err)
 	}
 
-	return hsstate.EditorState{
+	state := hsstate.EditorState{
 		WindowState: e.Window.State(),
 		Path:        path,
 	}
+
+	if e.Palettes != nil {
+		state.Palette = e.Palettes.Path()
+	}
+
+	return state
+}
+
+// RestoreState selects the palette of a saved state
+func (e *Editor) RestoreState(state hsstate.EditorState) {
+	if e.Palettes != nil && state.Palette != "" {
+		e.Palettes.Select(state.Palette)
+	}
 }
 
 // GetWindowTitle returns window title
@@ -76,11 +104,21 @@ func (e *Editor) Save(editor Saveable) {
 			return
 		}
 
+		if e.Project != nil {
+			// keep the version we are about to overwrite, a failing history must not prevent saving
+			if historyErr := e.Project.History().Record(e.Path.FullPath, "save"); historyErr != nil {
+				log.Print("failed to record local history: ", historyErr)
+			}
+		}
+
 		err = e.Path.WriteFile(saveData)
 		if err != nil {
 			fmt.Println("failed to save file: ", err)
 			return
 		}
+
+		// our own changes shouldn't be reported by CheckExternalChanges
+		e.fileModTime = e.getFileModTime()
 	} else {
 		return
 	}
@@ -93,6 +131,10 @@ func (e *Editor) HasChanges(editor Saveable) bool {
 		return false
 	}
 
+	if e.changesDiscarded {
+		return false
+	}
+
 	if _, isSaveable := editor.(Saveable); isSaveable {
 		newData := editor.GenerateSaveData()
 		if newData != nil {
@@ -107,6 +149,53 @@ func (e *Editor) HasChanges(editor Saveable) bool {
 	return false
 }
 
+// CheckExternalChanges returns true if the file was changed by another program since it was loaded
+// or saved by this editor. Each change is only reported once. Like Save, it has to be called by the render loop.
+func (e *Editor) CheckExternalChanges() bool {
+	modTime := e.getFileModTime()
+	if modTime.IsZero() || modTime.Equal(e.fileModTime) {
+		return false
+	}
+
+	e.fileModTime = modTime
+
+	return true
+}
+
+// DiscardChanges makes the editor forget its unsaved changes, so it can be closed without asking to save them
+func (e *Editor) DiscardChanges() {
+	e.changesDiscarded = true
+}
+
+// Move points the editor at the new path of its file. The window keeps its position and the unsaved changes.
+func (e *Editor) Move(filePath string) {
+	x, y := e.CurrentPosition()
+
+	// the path entry may belong to the project explorer's tree, which is read again after the move
+	path := *e.Path
+	path.FullPath = filePath
+	path.Name = filepath.Base(filePath)
+	e.Path = &path
+
+	// the title is the ID of the window, so the window has to be replaced
+	e.Window.WindowWidget = giu.Window(generateWindowTitle(e.Path)).Pos(x, y)
+	e.fileModTime = e.getFileModTime()
+}
+
+func (e *Editor) getFileModTime() time.Time {
+	if e.Path.Source != hscommon.PathEntrySourceProject {
+		// MPQs can't be changed from here
+		return time.Time{}
+	}
+
+	info, err := os.Stat(e.Path.FullPath)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
+
 // Cleanup cides an editor
 func (e *Editor) Cleanup() {
 	e.Window.Cleanup()
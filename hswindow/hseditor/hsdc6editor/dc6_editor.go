@@ -2,9 +2,14 @@
 package hsdc6editor
 
 import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
 	"github.com/OpenDiablo2/dialog"
 	g "github.com/ianling/giu"
 
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsimage"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
 
 	"github.com/OpenDiablo2/HellSpawner/hscommon"
@@ -20,6 +25,12 @@ type DC6Editor struct {
 	*hseditor.Editor
 	dc6           *d2dc6.DC6
 	textureLoader *hscommon.TextureLoader
+	importPanel   *hseditor.ImportPanel
+	exportPanel   *exportPanel
+	edit          editControls
+
+	// loadedData is the loaded DC6 written by MarshalDC6, as long as the DC6 is the same the file needn't be saved
+	loadedData []byte
 }
 
 // Create creates a new dc6 editor
@@ -35,16 +46,77 @@ func Create(textureLoader *hscommon.TextureLoader,
 		Editor:        hseditor.New(pathEntry, x, y, project),
 		dc6:           dc6,
 		textureLoader: textureLoader,
+		importPanel:   hseditor.NewImportPanel(pathEntry.GetUniqueID()),
+		exportPanel:   newExportPanel(pathEntry.GetUniqueID()),
+		loadedData:    hsimage.MarshalDC6(dc6),
 	}
 
+	result.Palettes = hseditor.NewPaletteSelector(pathEntry.GetUniqueID(), project)
+	result.setDC6(dc6)
+
 	return result, nil
 }
 
 // Build builds a new dc6 editor
 func (e *DC6Editor) Build() {
-	e.IsOpen(&e.Visible).Flags(g.WindowFlagsAlwaysAutoResize).Layout(g.Layout{
-		hswidget.DC6Viewer(e.textureLoader, e.Path.GetUniqueID(), e.dc6),
-	})
+	layout := g.Layout{}
+
+	if e.importPanel.Visible {
+		layout = append(layout, e.importPanel.Layout(e.onImportClicked, e.onImportCancelClicked)...)
+	}
+
+	if e.exportPanel.visible {
+		layout = append(layout, e.exportPanel.layout(e.onExportClicked, e.onExportCancelClicked)...)
+	}
+
+	viewer := hswidget.DC6Viewer(e.textureLoader, e.Path.GetUniqueID(), e.dc6).Palette(e.Palettes.Palette())
+	if e.edit.showOrigin {
+		viewer.Origin(e.onOriginMove(viewer))
+	}
+
+	layout = append(layout, e.Palettes, viewer)
+	layout = append(layout, e.makeEditLayout(viewer)...)
+
+	e.IsOpen(&e.Visible).Flags(g.WindowFlagsAlwaysAutoResize).Layout(layout)
+}
+
+func (e *DC6Editor) onImportClicked() {
+	images, options, err := e.importPanel.Load()
+	if err != nil {
+		dialog.Message("Could not import the images:\n%s", err).Error()
+		return
+	}
+
+	dc6, err := hsimage.ImportDC6(images, e.Palettes.Palette(), options)
+	if err != nil {
+		dialog.Message("Could not import the images:\n%s", err).Error()
+		return
+	}
+
+	e.setDC6(dc6)
+	e.importPanel.Visible = false
+}
+
+func (e *DC6Editor) onImportCancelClicked() {
+	e.importPanel.Visible = false
+}
+
+func (e *DC6Editor) onExportClicked() {
+	name := strings.TrimSuffix(e.Path.Name, filepath.Ext(e.Path.Name))
+
+	files, err := e.exportPanel.export(e.dc6, e.Palettes.Palette(), name)
+	if err != nil {
+		dialog.Message("Could not export the frames:\n%s", err).Error()
+		return
+	}
+
+	e.exportPanel.visible = false
+
+	dialog.Message("%d files were written to %s.", len(files), e.exportPanel.dir).Title("Export").Info()
+}
+
+func (e *DC6Editor) onExportCancelClicked() {
+	e.exportPanel.visible = false
 }
 
 // UpdateMainMenuLayout updates main menu to it contain DC6's editor menu
@@ -53,8 +125,12 @@ func (e *DC6Editor) UpdateMainMenuLayout(l *g.Layout) {
 		g.MenuItem("Add to project").OnClick(func() {}),
 		g.MenuItem("Remove from project").OnClick(func() {}),
 		g.Separator(),
-		g.MenuItem("Import from file...").OnClick(func() {}),
-		g.MenuItem("Export to file...").OnClick(func() {}),
+		g.MenuItem("Import from file...").OnClick(func() {
+			e.importPanel.Visible = true
+		}),
+		g.MenuItem("Export to file...").OnClick(func() {
+			e.exportPanel.visible = true
+		}),
 		g.Separator(),
 		g.MenuItem("Close").OnClick(func() {
 			e.Cleanup()
@@ -66,8 +142,12 @@ func (e *DC6Editor) UpdateMainMenuLayout(l *g.Layout) {
 
 // GenerateSaveData generates save data
 func (e *DC6Editor) GenerateSaveData() []byte {
-	// https://github.com/OpenDiablo2/HellSpawner/issues/181
-	data, _ := e.Path.GetFileBytes()
+	data := hsimage.MarshalDC6(e.dc6)
+
+	if bytes.Equal(data, e.loadedData) {
+		// the file may be laid out differently than MarshalDC6 would write it, it is kept until the DC6 is changed
+		data, _ = e.Path.GetFileBytes()
+	}
 
 	return data
 }
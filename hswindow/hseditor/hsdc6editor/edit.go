@@ -0,0 +1,135 @@
+package hsdc6editor
+
+import (
+	"github.com/OpenDiablo2/dialog"
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsimage"
+	"github.com/OpenDiablo2/HellSpawner/hswidget"
+)
+
+const (
+	editInputW = 80
+)
+
+// editControls holds the values of the controls changing the DC6
+type editControls struct {
+	showOrigin         bool
+	offsetX            int32
+	offsetY            int32
+	directions         int32
+	framesPerDirection int32
+}
+
+// setDC6 replaces the edited DC6, the viewer draws the frames again
+func (e *DC6Editor) setDC6(dc6 *d2dc6.DC6) {
+	e.dc6 = dc6
+	e.edit.directions = int32(dc6.Directions)
+	e.edit.framesPerDirection = int32(dc6.FramesPerDirection)
+}
+
+// currentFrame returns the frame shown by the viewer. Right after the DC6 was replaced, the viewer may still
+// point at a frame which doesn't exist anymore.
+func (e *DC6Editor) currentFrame(viewer *hswidget.DC6ViewerWidget) (direction, frame int32, current *d2dc6.DC6Frame) {
+	direction, frame = viewer.CurrentFrame()
+
+	if direction >= int32(e.dc6.Directions) {
+		direction = int32(e.dc6.Directions) - 1
+	}
+
+	if frame >= int32(e.dc6.FramesPerDirection) {
+		frame = int32(e.dc6.FramesPerDirection) - 1
+	}
+
+	return direction, frame, e.dc6.Frames[direction*int32(e.dc6.FramesPerDirection)+frame]
+}
+
+// makeEditLayout returns the controls changing the offsets and the order of the frames shown by the viewer
+func (e *DC6Editor) makeEditLayout(viewer *hswidget.DC6ViewerWidget) g.Layout {
+	id := e.Path.GetUniqueID()
+	direction, frame, current := e.currentFrame(viewer)
+	lastFrame := int32(e.dc6.FramesPerDirection) - 1
+
+	e.edit.offsetX, e.edit.offsetY = current.OffsetX, current.OffsetY
+
+	insert := func() (*d2dc6.DC6, error) { return hsimage.InsertDC6Frame(e.dc6, int(frame)) }
+	duplicate := func() (*d2dc6.DC6, error) { return hsimage.DuplicateDC6Frame(e.dc6, int(frame)) }
+	remove := func() (*d2dc6.DC6, error) { return hsimage.DeleteDC6Frame(e.dc6, int(frame)) }
+	moveEarlier := func() (*d2dc6.DC6, error) { return hsimage.MoveDC6Frame(e.dc6, int(frame), int(frame)-1) }
+	moveLater := func() (*d2dc6.DC6, error) { return hsimage.MoveDC6Frame(e.dc6, int(frame), int(frame)+1) }
+
+	return g.Layout{
+		g.Separator(),
+		g.Checkbox("Show origin (drag the frame to move it)##"+id+"ShowOrigin", &e.edit.showOrigin),
+		g.Line(
+			g.InputInt("Offset X##"+id+"OffsetX", &e.edit.offsetX).Size(editInputW).OnChange(func() {
+				current.OffsetX = e.edit.offsetX
+			}),
+			g.InputInt("Offset Y##"+id+"OffsetY", &e.edit.offsetY).Size(editInputW).OnChange(func() {
+				current.OffsetY = e.edit.offsetY
+			}),
+		),
+		g.Label("Frame (in every direction):"),
+		g.Line(
+			g.Button("Insert##"+id+"InsertFrame").OnClick(func() { e.applyEdit(viewer, direction, frame, insert) }),
+			g.Button("Duplicate##"+id+"DuplicateFrame").OnClick(func() { e.applyEdit(viewer, direction, frame+1, duplicate) }),
+			g.Button("Delete##"+id+"DeleteFrame").OnClick(func() { e.applyEdit(viewer, direction, frame, remove) }),
+			g.Custom(func() {
+				if frame > 0 {
+					g.Button("Move Earlier##" + id + "MoveFrameEarlier").OnClick(func() {
+						e.applyEdit(viewer, direction, frame-1, moveEarlier)
+					}).Build()
+				}
+			}),
+			g.Custom(func() {
+				if frame < lastFrame {
+					g.Button("Move Later##" + id + "MoveFrameLater").OnClick(func() {
+						e.applyEdit(viewer, direction, frame+1, moveLater)
+					}).Build()
+				}
+			}),
+		),
+		g.Line(
+			g.InputInt("Directions##"+id+"Directions", &e.edit.directions).Size(editInputW),
+			g.InputInt("Frames per Direction##"+id+"FramesPerDirection", &e.edit.framesPerDirection).Size(editInputW),
+			g.Button("Apply##"+id+"ApplyLayout").OnClick(func() { e.onApplyLayoutClicked(viewer) }),
+		),
+	}
+}
+
+// onOriginMove returns the callback moving the shown frame, while it is dragged on the origin canvas
+func (e *DC6Editor) onOriginMove(viewer *hswidget.DC6ViewerWidget) func(dx, dy int32) {
+	return func(dx, dy int32) {
+		_, _, current := e.currentFrame(viewer)
+
+		current.OffsetX += dx
+		current.OffsetY += dy
+	}
+}
+
+// applyEdit replaces the DC6 by the result of edit and shows the frame in the direction afterwards
+func (e *DC6Editor) applyEdit(viewer *hswidget.DC6ViewerWidget, direction, frame int32, edit func() (*d2dc6.DC6, error)) {
+	dc6, err := edit()
+	if err != nil {
+		dialog.Message("Could not change the frames:\n%s", err).Error()
+		return
+	}
+
+	e.setDC6(dc6)
+	viewer.SetCurrentFrame(direction, frame)
+}
+
+func (e *DC6Editor) onApplyLayoutClicked(viewer *hswidget.DC6ViewerWidget) {
+	directions, framesPerDirection := int(e.edit.directions), int(e.edit.framesPerDirection)
+
+	if removed := len(e.dc6.Frames) - directions*framesPerDirection; removed > 0 {
+		if !dialog.Message("%d frames at the end don't fit into %d directions of %d frames, remove them?",
+			removed, directions, framesPerDirection).Title("Remove Frames").YesNo() {
+			return
+		}
+	}
+
+	e.applyEdit(viewer, 0, 0, func() (*d2dc6.DC6, error) { return hsimage.ResizeDC6(e.dc6, directions, framesPerDirection) })
+}
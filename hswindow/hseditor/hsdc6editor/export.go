@@ -0,0 +1,72 @@
+package hsdc6editor
+
+import (
+	"errors"
+
+	"github.com/OpenDiablo2/dialog"
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dc6"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsimage"
+)
+
+const (
+	exportPathW   = 250
+	exportBrowseW = 30
+	exportBrowseH = 0
+	exportButtonW = 80
+	exportButtonH = 0
+)
+
+// exportPanel holds the options of the "Export to file..." panel, which writes the frames as PNG or GIF images
+type exportPanel struct {
+	id      string
+	visible bool
+
+	format int32
+	dir    string
+}
+
+func newExportPanel(id string) *exportPanel {
+	return &exportPanel{
+		id: id,
+	}
+}
+
+func (p *exportPanel) layout(onExport, onCancel func()) g.Layout {
+	formats := hsimage.ExportFormats()
+
+	return g.Layout{
+		g.Label("Export with the selected palette"),
+		g.Combo("Format##"+p.id+"ExportFormat", formats[p.format], formats, &p.format).Size(exportPathW),
+		g.Line(
+			g.InputText("##"+p.id+"ExportDir", &p.dir).Size(exportPathW),
+			g.Button("...##"+p.id+"ExportBrowse").Size(exportBrowseW, exportBrowseH).OnClick(p.onBrowseClicked),
+		),
+		g.Line(
+			g.Button("Export##"+p.id+"Export").Size(exportButtonW, exportButtonH).OnClick(onExport),
+			g.Button("Cancel##"+p.id+"ExportCancel").Size(exportButtonW, exportButtonH).OnClick(onCancel),
+		),
+		g.Separator(),
+	}
+}
+
+func (p *exportPanel) onBrowseClicked() {
+	dir, err := dialog.Directory().Title("Export to").Browse()
+	if err != nil || dir == "" {
+		return
+	}
+
+	p.dir = dir
+}
+
+// export writes the frames of the DC6 into the selected directory, the files are named after name
+func (p *exportPanel) export(dc6 *d2dc6.DC6, palette d2interface.Palette, name string) ([]string, error) {
+	if p.dir == "" {
+		return nil, errors.New("no directory was selected")
+	}
+
+	return hsimage.ExportDC6(dc6, palette, hsimage.ExportFormat(p.format), p.dir, name)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dcc"
 
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsimage"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
 
 	"github.com/OpenDiablo2/HellSpawner/hscommon"
@@ -19,7 +20,11 @@ import (
 // DCCEditor represents a new dcc editor
 type DCCEditor struct {
 	*hseditor.Editor
-	dcc *d2dcc.DCC
+	dcc         *d2dcc.DCC
+	importPanel *hseditor.ImportPanel
+
+	// encoded is the imported DCC, the loaded file is saved as it is until images are imported
+	encoded []byte
 }
 
 // Create creates a new dcc editor
@@ -32,18 +37,58 @@ func Create(_ *hscommon.TextureLoader,
 	}
 
 	result := &DCCEditor{
-		Editor: hseditor.New(pathEntry, x, y, project),
-		dcc:    dcc,
+		Editor:      hseditor.New(pathEntry, x, y, project),
+		dcc:         dcc,
+		importPanel: hseditor.NewImportPanel(pathEntry.GetUniqueID()),
 	}
 
+	result.Palettes = hseditor.NewPaletteSelector(pathEntry.GetUniqueID(), project)
+
 	return result, nil
 }
 
 // Build builds a dcc editor
 func (e *DCCEditor) Build() {
-	e.IsOpen(&e.Visible).Flags(g.WindowFlagsAlwaysAutoResize).Layout(g.Layout{
-		hswidget.DCCViewer(e.Path.GetUniqueID(), e.dcc),
-	})
+	layout := g.Layout{}
+
+	if e.importPanel.Visible {
+		layout = append(layout, e.importPanel.Layout(e.onImportClicked, e.onImportCancelClicked)...)
+	}
+
+	layout = append(layout,
+		e.Palettes,
+		hswidget.DCCViewer(e.Path.GetUniqueID(), e.dcc).Palette(e.Palettes.Palette()),
+	)
+
+	e.IsOpen(&e.Visible).Flags(g.WindowFlagsAlwaysAutoResize).Layout(layout)
+}
+
+func (e *DCCEditor) onImportClicked() {
+	images, options, err := e.importPanel.Load()
+	if err != nil {
+		dialog.Message("Could not import the images:\n%s", err).Error()
+		return
+	}
+
+	data, err := hsimage.ImportDCC(images, e.Palettes.Palette(), options)
+	if err != nil {
+		dialog.Message("Could not import the images:\n%s", err).Error()
+		return
+	}
+
+	dcc, err := d2dcc.Load(data)
+	if err != nil {
+		dialog.Message("Could not decode the imported DCC:\n%s", err).Error()
+		return
+	}
+
+	e.dcc = dcc
+	e.encoded = data
+	e.importPanel.Visible = false
+}
+
+func (e *DCCEditor) onImportCancelClicked() {
+	e.importPanel.Visible = false
 }
 
 // UpdateMainMenuLayout updates main menu to it contain editor's options
@@ -52,7 +97,9 @@ func (e *DCCEditor) UpdateMainMenuLayout(l *g.Layout) {
 		g.MenuItem("Add to project").OnClick(func() {}),
 		g.MenuItem("Remove from project").OnClick(func() {}),
 		g.Separator(),
-		g.MenuItem("Import from file...").OnClick(func() {}),
+		g.MenuItem("Import from file...").OnClick(func() {
+			e.importPanel.Visible = true
+		}),
 		g.MenuItem("Export to file...").OnClick(func() {}),
 		g.Separator(),
 		g.MenuItem("Close").OnClick(func() {
@@ -65,7 +112,10 @@ func (e *DCCEditor) UpdateMainMenuLayout(l *g.Layout) {
 
 // GenerateSaveData generates data to save
 func (e *DCCEditor) GenerateSaveData() []byte {
-	// https://github.com/OpenDiablo2/HellSpawner/issues/181
+	if e.encoded != nil {
+		return e.encoded
+	}
+
 	data, _ := e.Path.GetFileBytes()
 
 	return data
@@ -37,6 +37,8 @@ func Create(textureLoader *hscommon.TextureLoader,
 		dt1Viewer: hswidget.DT1Viewer(textureLoader, pathEntry.GetUniqueID(), dt1),
 	}
 
+	result.Palettes = hseditor.NewPaletteSelector(pathEntry.GetUniqueID(), project)
+
 	return result, nil
 }
 
@@ -45,7 +47,8 @@ func (e *DT1Editor) Build() {
 	e.IsOpen(&e.Visible).
 		Flags(g.WindowFlagsAlwaysAutoResize).
 		Layout(g.Layout{
-			e.dt1Viewer,
+			e.Palettes,
+			e.dt1Viewer.Palette(e.Palettes.Palette()),
 		})
 }
 
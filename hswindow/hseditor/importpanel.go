@@ -0,0 +1,163 @@
+package hseditor
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/OpenDiablo2/dialog"
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsimage"
+)
+
+const (
+	importSourceFrames = iota
+	importSourceSpriteSheet
+)
+
+const (
+	importPathW   = 250
+	importInputW  = 80
+	importBrowseW = 30
+	importBrowseH = 0
+	importButtonW = 80
+	importButtonH = 0
+)
+
+// ImportPanel holds the options of the "Import from file..." panel of the sprite editors (DC6, DCC),
+// which build their sprites from PNG images
+type ImportPanel struct {
+	id string
+	// Visible is set by the editor's menu, the panel is shown until an import succeeds or is canceled
+	Visible bool
+
+	source      int32
+	path        string
+	columns     int32
+	rows        int32
+	directions  int32
+	offsetX     int32
+	offsetY     int32
+	offsetsPath string
+	dither      bool
+}
+
+// NewImportPanel creates an import panel, which reads a folder of PNG frames of a single direction
+func NewImportPanel(id string) *ImportPanel {
+	return &ImportPanel{
+		id:         id,
+		columns:    1,
+		rows:       1,
+		directions: 1,
+	}
+}
+
+// Layout returns the controls of the panel, onImport is called when the Import button is clicked
+func (p *ImportPanel) Layout(onImport, onCancel func()) g.Layout {
+	sources := []string{"Folder of PNG frames", "PNG sprite sheet"}
+
+	layout := g.Layout{
+		g.Label("Import from PNG"),
+		g.Combo("Source##"+p.id+"ImportSource", sources[p.source], sources, &p.source).Size(importPathW),
+		g.Line(
+			g.InputText("##"+p.id+"ImportPath", &p.path).Size(importPathW),
+			g.Button("...##"+p.id+"ImportBrowse").Size(importBrowseW, importBrowseH).OnClick(p.onBrowseClicked),
+		),
+	}
+
+	if p.source == importSourceSpriteSheet {
+		layout = append(layout, g.Line(
+			g.InputInt("Columns##"+p.id+"ImportColumns", &p.columns).Size(importInputW),
+			g.InputInt("Rows##"+p.id+"ImportRows", &p.rows).Size(importInputW),
+		))
+	}
+
+	return append(layout,
+		g.InputInt("Directions##"+p.id+"ImportDirections", &p.directions).Size(importInputW),
+		g.Line(
+			g.InputInt("Offset X##"+p.id+"ImportOffsetX", &p.offsetX).Size(importInputW),
+			g.InputInt("Offset Y##"+p.id+"ImportOffsetY", &p.offsetY).Size(importInputW),
+		),
+		g.Label("Offsets of the frames (optional, one x,y line per frame)"),
+		g.Line(
+			g.InputText("##"+p.id+"ImportOffsetsPath", &p.offsetsPath).Size(importPathW),
+			g.Button("...##"+p.id+"ImportOffsetsBrowse").Size(importBrowseW, importBrowseH).OnClick(p.onBrowseOffsetsClicked),
+		),
+		g.Checkbox("Dither##"+p.id+"ImportDither", &p.dither),
+		g.Line(
+			g.Button("Import##"+p.id+"Import").Size(importButtonW, importButtonH).OnClick(onImport),
+			g.Button("Cancel##"+p.id+"ImportCancel").Size(importButtonW, importButtonH).OnClick(onCancel),
+		),
+		g.Separator(),
+	)
+}
+
+func (p *ImportPanel) onBrowseClicked() {
+	var path string
+
+	var err error
+
+	if p.source == importSourceFrames {
+		path, err = dialog.Directory().Title("Import PNG Frames").Browse()
+	} else {
+		path, err = dialog.File().Filter("PNG Image", "png").Title("Import Sprite Sheet").Load()
+	}
+
+	if err != nil || path == "" {
+		return
+	}
+
+	p.path = path
+}
+
+func (p *ImportPanel) onBrowseOffsetsClicked() {
+	path, err := dialog.File().Filter("Text File", "txt").Title("Frame Offsets").Load()
+	if err != nil || path == "" {
+		return
+	}
+
+	p.offsetsPath = path
+}
+
+// Load reads the selected images and returns them with the options they are converted with
+func (p *ImportPanel) Load() ([]image.Image, hsimage.ImportOptions, error) {
+	options := hsimage.ImportOptions{
+		Directions: int(p.directions),
+		Dither:     p.dither,
+		OffsetX:    p.offsetX,
+		OffsetY:    p.offsetY,
+	}
+
+	if p.path == "" {
+		return nil, options, errors.New("no images were selected")
+	}
+
+	var images []image.Image
+
+	var err error
+
+	if p.source == importSourceSpriteSheet {
+		var sheet image.Image
+
+		if sheet, err = hsimage.LoadPNG(p.path); err != nil {
+			return nil, options, err
+		}
+
+		images, err = hsimage.SplitSpriteSheet(sheet, hsimage.Grid{Columns: int(p.columns), Rows: int(p.rows)})
+	} else {
+		images, err = hsimage.LoadPNGFrames(p.path)
+	}
+
+	if err != nil {
+		return nil, options, err
+	}
+
+	if p.offsetsPath != "" {
+		if options.Offsets, err = hsimage.LoadOffsets(p.offsetsPath); err != nil {
+			return nil, options, fmt.Errorf("could not read the offsets: %w", err)
+		}
+	}
+
+	return images, options, nil
+}
@@ -0,0 +1,114 @@
+package hseditor
+
+import (
+	"log"
+	"sync"
+
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+)
+
+const (
+	paletteComboW = 250
+)
+
+// PaletteSelector chooses the palette the indexed images (DC6, DCC, DT1) of an editor are drawn with.
+// The path of the selected palette is a part of the editor's state.
+type PaletteSelector struct {
+	id      string
+	project *hsproject.Project
+	sources []hsproject.PaletteSource
+
+	mutex   sync.RWMutex
+	path    string
+	palette d2interface.Palette
+	current int32
+}
+
+// NewPaletteSelector creates a palette selector, which selects the default palette
+func NewPaletteSelector(id string, project *hsproject.Project) *PaletteSelector {
+	result := &PaletteSelector{
+		id:      id,
+		project: project,
+	}
+
+	if project != nil {
+		result.sources = project.Palettes()
+	}
+
+	result.Select(hsproject.DefaultPalettePath)
+
+	return result
+}
+
+// Select loads the palette with the given path. If it can't be loaded, the previous palette stays selected.
+func (s *PaletteSelector) Select(palettePath string) {
+	if s.project == nil {
+		return
+	}
+
+	palette, err := s.project.LoadPalette(palettePath)
+	if err != nil {
+		log.Printf("failed to load palette %s: %s", palettePath, err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.path = palettePath
+	s.palette = palette
+	s.current = -1
+
+	for idx := range s.sources {
+		if s.sources[idx].Path == palettePath {
+			s.current = int32(idx)
+			break
+		}
+	}
+}
+
+// Path returns the path of the selected palette, or an empty string if no palette could be loaded
+func (s *PaletteSelector) Path() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.path
+}
+
+// Palette returns the selected palette, or nil if no palette could be loaded
+func (s *PaletteSelector) Palette() d2interface.Palette {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.palette
+}
+
+// Build builds the palette combo box
+func (s *PaletteSelector) Build() {
+	if len(s.sources) == 0 {
+		g.Label("Palette: none found, drawing palette indices").Build()
+		return
+	}
+
+	names := make([]string, len(s.sources))
+	for idx := range s.sources {
+		names[idx] = s.sources[idx].Name
+	}
+
+	s.mutex.RLock()
+	current := s.current
+	preview := s.path
+	s.mutex.RUnlock()
+
+	if current >= 0 {
+		preview = names[current]
+	}
+
+	g.Combo("Palette##"+s.id+"PaletteSelector", preview, names, &current).Size(paletteComboW).OnChange(func() {
+		s.Select(s.sources[current].Path)
+	}).Build()
+}
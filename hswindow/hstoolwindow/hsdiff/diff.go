@@ -0,0 +1,343 @@
+// Package hsdiff contains the tool window comparing two archives or archive sets
+package hsdiff
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/OpenDiablo2/dialog"
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
+)
+
+const (
+	mainWindowW, mainWindowH = 800, 450
+	sourceComboW             = 250
+)
+
+// DiffFileSelectedCallback represents file selected callback
+type DiffFileSelectedCallback func(path *hscommon.PathEntry)
+
+// source kinds offered in the source combos
+const (
+	sourceProject = iota
+	sourceAuxiliary
+	sourceMPQ
+)
+
+// source is an entry of the source combos
+type source struct {
+	name    string
+	kind    int
+	mpqPath string
+}
+
+// Diff is a tool window listing the files which were added, removed or changed between two sources
+type Diff struct {
+	*hstoolwindow.ToolWindow
+	config               *hsconfig.Config
+	project              *hsproject.Project
+	fileSelectedCallback DiffFileSelectedCallback
+
+	sources      []source
+	browsed      []string
+	sourceA      int32
+	sourceB      int32
+	showChanged  bool
+	showAdded    bool
+	showRemoved  bool
+	filterString string
+
+	mutex     sync.Mutex
+	report    *hsproject.DiffReport
+	rows      g.Rows
+	status    string
+	comparing bool
+	cancel    bool
+	progress  float32
+}
+
+// Create creates a new diff window
+func Create(fileSelectedCallback DiffFileSelectedCallback, config *hsconfig.Config, x, y float32) (*Diff, error) {
+	result := &Diff{
+		ToolWindow:           hstoolwindow.New("Archive Diff", hsstate.ToolWindowTypeDiff, x, y),
+		fileSelectedCallback: fileSelectedCallback,
+		config:               config,
+		showChanged:          true,
+		showAdded:            true,
+		showRemoved:          true,
+	}
+
+	return result, nil
+}
+
+// SetProject sets the project whose archives are compared
+func (d *Diff) SetProject(project *hsproject.Project) {
+	d.project = project
+	d.browsed = nil
+	d.Reset()
+}
+
+// Reset discards the current diff, it has to be called when the auxiliary MPQs change
+func (d *Diff) Reset() {
+	d.updateSources()
+
+	// compare the project with the game's files by default
+	d.sourceA, d.sourceB = sourceAuxiliary, sourceProject
+
+	d.mutex.Lock()
+	d.cancel = true
+	d.report = nil
+	d.rows = nil
+	d.status = ""
+	d.mutex.Unlock()
+}
+
+func (d *Diff) updateSources() {
+	d.sources = []source{
+		{name: "Project (in load order)", kind: sourceProject},
+		{name: "Auxiliary MPQs", kind: sourceAuxiliary},
+	}
+
+	if d.project != nil {
+		for _, mpqName := range d.project.AuxiliaryMPQs {
+			d.sources = append(d.sources, source{
+				name:    mpqName,
+				kind:    sourceMPQ,
+				mpqPath: filepath.Join(d.config.AuxiliaryMpqPath, mpqName),
+			})
+		}
+	}
+
+	for _, mpqPath := range d.browsed {
+		d.sources = append(d.sources, source{name: mpqPath, kind: sourceMPQ, mpqPath: mpqPath})
+	}
+}
+
+func (d *Diff) sourceNames() []string {
+	result := make([]string, len(d.sources))
+	for idx := range d.sources {
+		result[idx] = d.sources[idx].name
+	}
+
+	return result
+}
+
+// Build builds the diff window
+func (d *Diff) Build() {
+	if d.project == nil {
+		return
+	}
+
+	d.mutex.Lock()
+	comparing := d.comparing
+	progress := d.progress
+	status := d.status
+	rows := d.rows
+	d.mutex.Unlock()
+
+	names := d.sourceNames()
+
+	compareButton := g.Button("Compare##DiffCompare").OnClick(d.compare)
+	if comparing {
+		compareButton = g.Button("Cancel##DiffCancel").OnClick(func() {
+			d.mutex.Lock()
+			d.cancel = true
+			d.mutex.Unlock()
+		})
+	}
+
+	layout := g.Layout{
+		g.Line(
+			g.Label("Old:"),
+			g.Combo("##DiffSourceA", names[d.sourceA], names, &d.sourceA).Size(sourceComboW),
+			g.Label("New:"),
+			g.Combo("##DiffSourceB", names[d.sourceB], names, &d.sourceB).Size(sourceComboW),
+			g.Button("Add MPQ...##DiffBrowse").OnClick(d.onBrowseClicked),
+		),
+		g.Line(
+			compareButton,
+			g.Checkbox("Changed##DiffShowChanged", &d.showChanged).OnChange(d.updateRows),
+			g.Checkbox("Added##DiffShowAdded", &d.showAdded).OnChange(d.updateRows),
+			g.Checkbox("Removed##DiffShowRemoved", &d.showRemoved).OnChange(d.updateRows),
+			g.InputText("Filter##DiffFilter", &d.filterString).Size(sourceComboW).OnChange(d.updateRows),
+		),
+	}
+
+	if comparing {
+		layout = append(layout, g.ProgressBar(progress).Size(-1, 0))
+	}
+
+	layout = append(layout, g.Label(status).Wrapped(true), g.Separator())
+
+	if rows != nil {
+		layout = append(layout, g.Child("DiffContent").
+			Border(false).
+			Flags(g.WindowFlagsHorizontalScrollbar).
+			Layout(g.Layout{
+				g.FastTable("").Border(true).Rows(rows),
+			}))
+	}
+
+	d.IsOpen(&d.Visible).
+		Size(mainWindowW, mainWindowH).
+		Layout(layout)
+}
+
+func (d *Diff) onBrowseClicked() {
+	file, err := dialog.File().Filter("MPQ Archive", "mpq").Title("Add MPQ").Load()
+	if err != nil || file == "" {
+		return
+	}
+
+	d.browsed = append(d.browsed, file)
+	d.updateSources()
+}
+
+// diffSource creates the diff source for an entry of the source combos
+func (d *Diff) diffSource(src source) (*hsproject.DiffSource, error) {
+	switch src.kind {
+	case sourceProject:
+		return d.project.ProjectDiffSource(d.config), nil
+	case sourceAuxiliary:
+		return d.project.AuxiliaryDiffSource(d.config)
+	default:
+		return hsproject.NewMPQDiffSource(filepath.Base(src.mpqPath), d.project.Archives(), []string{src.mpqPath}, d.config)
+	}
+}
+
+func (d *Diff) compare() {
+	if d.sourceA == d.sourceB {
+		dialog.Message("Select two different sources to compare.").Title("Archive Diff").Info()
+		return
+	}
+
+	d.mutex.Lock()
+	if d.comparing {
+		d.mutex.Unlock()
+		return
+	}
+
+	d.comparing = true
+	d.cancel = false
+	d.progress = 0
+	d.status = "Listing files..."
+	d.mutex.Unlock()
+
+	srcA, srcB := d.sources[d.sourceA], d.sources[d.sourceB]
+
+	go func() {
+		report, err := d.runDiff(srcA, srcB)
+
+		d.mutex.Lock()
+		d.comparing = false
+
+		if err != nil {
+			d.status = fmt.Sprintf("Could not compare the files: %s", err)
+			d.report = nil
+		} else {
+			d.report = report
+			d.status = fmt.Sprintf("%s -> %s: %d changed, %d added, %d removed, %d identical.",
+				report.A, report.B, report.Count(hsproject.DiffStatusChanged), report.Count(hsproject.DiffStatusAdded),
+				report.Count(hsproject.DiffStatusRemoved), report.Identical)
+
+			if unreadable := report.Count(hsproject.DiffStatusError); unreadable > 0 {
+				d.status += fmt.Sprintf(" %d files could not be read.", unreadable)
+			}
+
+			if report.Canceled {
+				d.status = "Canceled. " + d.status
+			}
+		}
+		d.mutex.Unlock()
+
+		d.updateRows()
+	}()
+}
+
+func (d *Diff) runDiff(srcA, srcB source) (*hsproject.DiffReport, error) {
+	a, err := d.diffSource(srcA)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := d.diffSource(srcB)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	d.status = "Comparing files..."
+	d.mutex.Unlock()
+
+	return hsproject.Diff(a, b, func(done, total int) bool {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+
+		d.progress = float32(done) / float32(total)
+
+		return !d.cancel
+	}), nil
+}
+
+func (d *Diff) updateRows() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.report == nil {
+		d.rows = nil
+		return
+	}
+
+	rows := g.Rows{
+		g.Row(g.Label("Status"), g.Label("Path"), g.Label("Type"), g.Label("Size"), g.Label("Summary")),
+	}
+
+	filter := strings.ToLower(d.filterString)
+
+	for idx := range d.report.Entries {
+		entry := d.report.Entries[idx]
+
+		switch {
+		case entry.Status == hsproject.DiffStatusChanged && !d.showChanged,
+			entry.Status == hsproject.DiffStatusAdded && !d.showAdded,
+			entry.Status == hsproject.DiffStatusRemoved && !d.showRemoved,
+			filter != "" && !strings.Contains(strings.ToLower(entry.Path), filter):
+			continue
+		}
+
+		// open the new copy of the file, unless it was removed
+		pathEntry := entry.EntryB
+		if pathEntry == nil {
+			pathEntry = entry.EntryA
+		}
+
+		size := fmt.Sprintf("%d -> %d", entry.SizeA, entry.SizeB)
+
+		switch entry.Status {
+		case hsproject.DiffStatusAdded:
+			size = fmt.Sprintf("%d", entry.SizeB)
+		case hsproject.DiffStatusRemoved:
+			size = fmt.Sprintf("%d", entry.SizeA)
+		}
+
+		rows = append(rows, g.Row(
+			g.Label(string(entry.Status)),
+			g.Selectable(entry.Path+"##DiffRow_"+entry.Path).OnClick(func() {
+				go d.fileSelectedCallback(pathEntry)
+			}),
+			g.Label(entry.Type),
+			g.Label(size),
+			g.Label(entry.Summary),
+		))
+	}
+
+	d.rows = rows
+}
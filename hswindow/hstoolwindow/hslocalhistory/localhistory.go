@@ -0,0 +1,286 @@
+// Package hslocalhistory contains the tool window listing the local history of a project file
+package hslocalhistory
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hshistory"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
+)
+
+const (
+	mainWindowW, mainWindowH = 650, 350
+	comboW                   = 220
+	timeFormat               = "2006-01-02 15:04:05"
+	shortHashLength          = 8
+)
+
+// RestoreCallback is called when the user wants to restore a revision
+type RestoreCallback func(path *hscommon.PathEntry, revision hshistory.Revision)
+
+// LocalHistory is a tool window listing the revisions of a file kept by the local history
+type LocalHistory struct {
+	*hstoolwindow.ToolWindow
+	project         *hsproject.Project
+	restoreCallback RestoreCallback
+
+	mutex       sync.Mutex
+	entry       *hscommon.PathEntry
+	revisions   []hshistory.Revision
+	currentHash string
+	status      string
+	rows        g.Rows
+
+	// compareNames contains the current file followed by every revision
+	compareNames   []string
+	compareA       int32
+	compareB       int32
+	comparison     string
+	comparedA      int32
+	comparedB      int32
+	comparisonDone bool
+}
+
+// Create creates a new local history window
+func Create(restoreCallback RestoreCallback, x, y float32) (*LocalHistory, error) {
+	result := &LocalHistory{
+		ToolWindow:      hstoolwindow.New("Local History", hsstate.ToolWindowTypeLocalHistory, x, y),
+		restoreCallback: restoreCallback,
+	}
+
+	return result, nil
+}
+
+// SetProject sets the project whose history is shown
+func (l *LocalHistory) SetProject(project *hsproject.Project) {
+	l.project = project
+
+	l.mutex.Lock()
+	l.entry = nil
+	l.revisions = nil
+	l.rows = nil
+	l.status = ""
+	l.compareNames = nil
+	l.mutex.Unlock()
+}
+
+// ShowFile shows the history of the given project file
+func (l *LocalHistory) ShowFile(entry *hscommon.PathEntry) {
+	l.mutex.Lock()
+	l.entry = entry
+	l.compareA, l.compareB = 0, 1
+	l.comparisonDone = false
+	l.mutex.Unlock()
+
+	l.Refresh()
+	l.Show()
+}
+
+// Refresh reloads the history of the shown file
+func (l *LocalHistory) Refresh() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.entry == nil || l.project == nil {
+		return
+	}
+
+	l.rows = nil
+	l.revisions = nil
+	l.comparisonDone = false
+
+	if l.entry.Source != hscommon.PathEntrySourceProject {
+		l.status = "Only project files have a local history."
+		return
+	}
+
+	revisions, err := l.project.History().Revisions(l.entry.FullPath)
+	if err != nil {
+		l.status = fmt.Sprintf("Could not read the history of %s: %s", l.entry.Name, err)
+		return
+	}
+
+	l.revisions = revisions
+	l.currentHash, _ = hshistory.HashFile(l.entry.FullPath)
+	l.status = fmt.Sprintf("%s: %d revisions", l.entry.Name, len(revisions))
+
+	l.compareNames = []string{"Current file"}
+	for idx := range revisions {
+		l.compareNames = append(l.compareNames, revisions[idx].Time.Local().Format(timeFormat))
+	}
+
+	if int(l.compareB) >= len(l.compareNames) {
+		l.compareB = 0
+	}
+
+	l.updateRows()
+}
+
+// Build builds the local history window
+func (l *LocalHistory) Build() {
+	l.mutex.Lock()
+	status := l.status
+	rows := l.rows
+	canCompare := len(l.compareNames) > 1
+
+	if canCompare && (!l.comparisonDone || l.comparedA != l.compareA || l.comparedB != l.compareB) {
+		l.comparison = l.compare(l.compareA, l.compareB)
+		l.comparedA, l.comparedB = l.compareA, l.compareB
+		l.comparisonDone = true
+	}
+
+	comparison := l.comparison
+	l.mutex.Unlock()
+
+	layout := g.Layout{
+		g.Line(
+			g.Button("Refresh##LocalHistoryRefresh").OnClick(l.Refresh),
+			g.Label(status),
+		),
+		g.Separator(),
+	}
+
+	if canCompare {
+		layout = append(layout,
+			g.Line(
+				g.Label("Compare"),
+				g.Combo("##LocalHistoryCompareA", l.compareNames[l.compareA], l.compareNames, &l.compareA).Size(comboW),
+				g.Label("with"),
+				g.Combo("##LocalHistoryCompareB", l.compareNames[l.compareB], l.compareNames, &l.compareB).Size(comboW),
+			),
+			g.Label(comparison).Wrapped(true),
+			g.Separator(),
+		)
+	}
+
+	if len(rows) > 1 {
+		layout = append(layout, g.Child("LocalHistoryContent").
+			Border(false).
+			Flags(g.WindowFlagsHorizontalScrollbar).
+			Layout(g.Layout{
+				g.FastTable("").Border(true).Rows(rows),
+			}))
+	}
+
+	l.IsOpen(&l.Visible).
+		Size(mainWindowW, mainWindowH).
+		Layout(layout)
+}
+
+func (l *LocalHistory) updateRows() {
+	rows := g.Rows{g.Row(
+		g.Label("Recorded"),
+		g.Label("Reason"),
+		g.Label("Size"),
+		g.Label("Change"),
+		g.Label("Hash"),
+		g.Label(""),
+	)}
+
+	for idx := range l.revisions {
+		revision := l.revisions[idx]
+		entry := l.entry
+
+		content := "differs from the current file"
+		if revision.Hash == l.currentHash {
+			content = "same as the current file"
+		}
+
+		hash := revision.Hash
+		if len(hash) > shortHashLength {
+			hash = hash[:shortHashLength]
+		}
+
+		rows = append(rows, g.Row(
+			g.Label(revision.Time.Local().Format(timeFormat)),
+			g.Label(revision.Reason),
+			g.Label(fmt.Sprintf("%d bytes", revision.Size)),
+			g.Label(content),
+			g.Label(hash),
+			g.Button(fmt.Sprintf("Restore##LocalHistoryRestore_%d", idx)).OnClick(func() {
+				go l.restoreCallback(entry, revision)
+			}),
+		))
+	}
+
+	l.rows = rows
+}
+
+// compare describes the differences between two versions, index 0 is the current file
+func (l *LocalHistory) compare(a, b int32) string {
+	if a == b {
+		return "Select two different versions."
+	}
+
+	dataA, err := l.read(a)
+	if err != nil {
+		return err.Error()
+	}
+
+	dataB, err := l.read(b)
+	if err != nil {
+		return err.Error()
+	}
+
+	nameA, nameB := l.compareNames[a], l.compareNames[b]
+
+	if bytes.Equal(dataA, dataB) {
+		return fmt.Sprintf("%s and %s are identical (%d bytes).", nameA, nameB, len(dataA))
+	}
+
+	result := fmt.Sprintf("%s: %d bytes, %s: %d bytes (%+d).", nameA, len(dataA), nameB, len(dataB), len(dataB)-len(dataA))
+
+	if isText(dataA) && isText(dataB) {
+		added, removed := diffLines(dataA, dataB)
+		result += fmt.Sprintf(" %d lines added, %d lines removed.", added, removed)
+	}
+
+	return result
+}
+
+func (l *LocalHistory) read(idx int32) ([]byte, error) {
+	if idx == 0 {
+		return ioutil.ReadFile(filepath.Clean(l.entry.FullPath))
+	}
+
+	return l.project.History().Read(l.entry.FullPath, l.revisions[idx-1].ID)
+}
+
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// diffLines counts the lines which only exist in one of the versions, the order of the lines is ignored
+func diffLines(a, b []byte) (added, removed int) {
+	counts := make(map[string]int)
+
+	for _, line := range bytes.Split(a, []byte("\n")) {
+		counts[string(bytes.TrimRight(line, "\r"))]++
+	}
+
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		key := string(bytes.TrimRight(line, "\r"))
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+
+		added++
+	}
+
+	for _, count := range counts {
+		removed += count
+	}
+
+	return added, removed
+}
@@ -0,0 +1,297 @@
+package hsmpqexplorer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/OpenDiablo2/dialog"
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsutil"
+)
+
+// overwritePolicy decides what happens to files which already exist at the target of an extraction
+type overwritePolicy int32
+
+const (
+	// overwriteAsk asks once per batch, the answer is applied to every existing file of the batch
+	overwriteAsk overwritePolicy = iota
+	overwriteSkip
+	overwriteAll
+)
+
+func overwritePolicyNames() []string {
+	return []string{"Ask once", "Skip existing files", "Overwrite existing files"}
+}
+
+// extractedFile is a single file of a batch
+type extractedFile struct {
+	entry  *hscommon.PathEntry
+	target string
+	exists bool
+	// outside is set when the archive path leads out of the target directory (e.g. ..\..\file), it isn't extracted
+	outside bool
+}
+
+// batch copies a set of files out of an archive in the background
+type batch struct {
+	mutex sync.Mutex
+
+	title    string
+	files    []extractedFile
+	existing int
+	policy   overwritePolicy
+
+	// answer receives the user's decision when the policy is overwriteAsk and files exist
+	answer   chan overwritePolicy
+	asking   bool
+	done     int
+	current  string
+	canceled bool
+	finished bool
+
+	copied, skipped, failed int
+}
+
+// collectFiles returns the files inside of a path entry (or the entry itself, if it is a file)
+func collectFiles(entry *hscommon.PathEntry) []*hscommon.PathEntry {
+	if !entry.IsDirectory {
+		return []*hscommon.PathEntry{entry}
+	}
+
+	result := make([]*hscommon.PathEntry, 0)
+	for _, child := range entry.Children {
+		result = append(result, collectFiles(child)...)
+	}
+
+	return result
+}
+
+// copyToProject copies a file or a folder into the project's content directory, it blocks until the copy has finished
+func (m *MPQExplorer) copyToProject(entry *hscommon.PathEntry) {
+	contentPath := m.project.GetProjectFileContentPath()
+
+	m.startBatch("Copying to project", entry, contentPath, true, func(file *hscommon.PathEntry) string {
+		// strip "data" from the beginning of the path if it exists
+		return filepath.Join(contentPath, filepath.FromSlash(hsproject.ContentPathFromArchivePath(file.FullPath)))
+	})
+}
+
+// extractToDisk extracts a file, a folder or a whole archive into a directory chosen by the user.
+// The files keep their path inside of the archive. It blocks until the extraction has finished.
+func (m *MPQExplorer) extractToDisk(entry *hscommon.PathEntry) {
+	dir, err := dialog.Directory().Title("Extract to").Browse()
+	if err != nil || dir == "" {
+		return
+	}
+
+	m.startBatch("Extracting to "+dir, entry, dir, false, func(file *hscommon.PathEntry) string {
+		return filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(file.FullPath, `\`, "/")))
+	})
+}
+
+func (m *MPQExplorer) startBatch(title string, entry *hscommon.PathEntry, targetDir string, toProject bool,
+	targetPath func(file *hscommon.PathEntry) string) {
+	current := &batch{
+		title:  title,
+		policy: overwritePolicy(m.overwritePolicy),
+		answer: make(chan overwritePolicy, 1),
+	}
+
+	for _, file := range collectFiles(entry) {
+		target := targetPath(file)
+
+		if !hsutil.IsInsideDir(target, targetDir) {
+			current.files = append(current.files, extractedFile{entry: file, target: target, outside: true})
+			continue
+		}
+
+		_, err := os.Stat(target)
+		exists := err == nil
+
+		if exists {
+			current.existing++
+		}
+
+		current.files = append(current.files, extractedFile{entry: file, target: target, exists: exists})
+	}
+
+	m.batchMutex.Lock()
+
+	if m.batch != nil && !m.batch.isFinished() {
+		m.batchMutex.Unlock()
+		dialog.Message("Wait until the current extraction has finished or cancel it.").Title("Extraction in progress").Info()
+
+		return
+	}
+
+	m.batch = current
+	m.batchMutex.Unlock()
+
+	current.run()
+
+	if toProject && current.copied > 0 {
+		m.project.InvalidateFileStructure()
+	}
+}
+
+func (b *batch) isFinished() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.finished
+}
+
+func (b *batch) cancel() {
+	b.mutex.Lock()
+	b.canceled = true
+	b.mutex.Unlock()
+
+	// stop waiting for an answer
+	select {
+	case b.answer <- overwriteSkip:
+	default:
+	}
+}
+
+// resolve applies the user's answer to the existing files
+func (b *batch) resolve(policy overwritePolicy) {
+	select {
+	case b.answer <- policy:
+	default:
+	}
+}
+
+func (b *batch) run() {
+	defer func() {
+		b.mutex.Lock()
+		b.finished = true
+		b.current = ""
+		b.mutex.Unlock()
+	}()
+
+	policy := b.policy
+	if policy == overwriteAsk && b.existing > 0 {
+		b.mutex.Lock()
+		b.asking = true
+		b.mutex.Unlock()
+
+		policy = <-b.answer
+
+		b.mutex.Lock()
+		b.asking = false
+		b.mutex.Unlock()
+	}
+
+	for idx := range b.files {
+		file := &b.files[idx]
+
+		b.mutex.Lock()
+		canceled := b.canceled
+		b.current = file.entry.FullPath
+		b.mutex.Unlock()
+
+		if canceled {
+			return
+		}
+
+		result := b.extract(file, policy)
+
+		b.mutex.Lock()
+		b.done++
+
+		switch result {
+		case extractCopied:
+			b.copied++
+		case extractSkipped:
+			b.skipped++
+		default:
+			b.failed++
+		}
+		b.mutex.Unlock()
+	}
+}
+
+type extractResult int
+
+const (
+	extractCopied extractResult = iota
+	extractSkipped
+	extractFailed
+)
+
+func (b *batch) extract(file *extractedFile, policy overwritePolicy) extractResult {
+	if file.outside {
+		log.Printf("not extracting %s, its path leaves the target directory", file.entry.FullPath)
+		return extractFailed
+	}
+
+	if file.exists && policy != overwriteAll {
+		return extractSkipped
+	}
+
+	data, err := file.entry.GetFileBytes()
+	if err != nil {
+		log.Printf("failed to read file %s when extracting it: %s", file.entry.FullPath, err)
+		return extractFailed
+	}
+
+	if !hsutil.CreateFileAtPath(file.target, data) {
+		return extractFailed
+	}
+
+	return extractCopied
+}
+
+// layout returns the progress of the batch, or the overwrite prompt while the batch is waiting for an answer
+func (b *batch) layout(dismiss func()) g.Layout {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	total := len(b.files)
+
+	if b.finished {
+		summary := fmt.Sprintf("%s: %d copied, %d skipped, %d failed.", b.title, b.copied, b.skipped, b.failed)
+		if b.canceled {
+			summary = fmt.Sprintf("%s: canceled after %d of %d files (%d copied, %d skipped, %d failed).",
+				b.title, b.done, total, b.copied, b.skipped, b.failed)
+		}
+
+		return g.Layout{
+			g.Label(summary).Wrapped(true),
+			g.Button("Dismiss##MPQExplorerDismissBatch").OnClick(dismiss),
+			g.Separator(),
+		}
+	}
+
+	fraction := float32(1)
+	if total > 0 {
+		fraction = float32(b.done) / float32(total)
+	}
+
+	asking := b.asking
+
+	return g.Layout{
+		g.Label(b.title).Wrapped(true),
+		g.ProgressBar(fraction).Size(-1, 0).Overlay(fmt.Sprintf("%d / %d", b.done, total)),
+		g.Line(
+			g.Button("Cancel##MPQExplorerCancelBatch").OnClick(b.cancel),
+			g.Label(b.current),
+		),
+		g.PopupModal("Overwrite Files?##MPQExplorerOverwrite").IsOpen(&asking).Layout(g.Layout{
+			g.Label(fmt.Sprintf("%d of %d files already exist. Overwrite them?", b.existing, total)),
+			g.Line(
+				g.Button("Overwrite").OnClick(func() { b.resolve(overwriteAll) }),
+				g.Button("Skip").OnClick(func() { b.resolve(overwriteSkip) }),
+				g.Button("Cancel").OnClick(b.cancel),
+			),
+		}),
+		g.Separator(),
+	}
+}
@@ -2,54 +2,60 @@
 package hsmpqexplorer
 
 import (
-	"log"
-	"os"
-	"path"
-	"path/filepath"
-	"strings"
 	"sync"
 
 	g "github.com/ianling/giu"
 
-	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2mpq"
-
 	"github.com/OpenDiablo2/HellSpawner/hscommon"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsreference"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
-	"github.com/OpenDiablo2/HellSpawner/hscommon/hsutil"
 	"github.com/OpenDiablo2/HellSpawner/hsconfig"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
 )
 
 const (
 	mainWindowW, mainWindowH = 300, 400
+	policyComboW             = 170
 )
 
 // MPQExplorerFileSelectedCallback represents file selected callback
 type MPQExplorerFileSelectedCallback func(path *hscommon.PathEntry)
 
+// MPQExplorerReferenceCallback represents callback on "Find Usages" or "Go To Referenced File" clicked
+type MPQExplorerReferenceCallback func(path *hscommon.PathEntry)
+
+// MPQExplorerInspectCallback represents callback on "Inspect Archive" clicked
+type MPQExplorerInspectCallback func(mpqPath string)
+
 // MPQExplorer represents a mpq explorer
 type MPQExplorer struct {
 	*hstoolwindow.ToolWindow
-	config               *hsconfig.Config
-	project              *hsproject.Project
-	fileSelectedCallback MPQExplorerFileSelectedCallback
-	nodeCache            []g.Widget
-
-	filesToOverwrite []fileToOverwrite
-}
-
-type fileToOverwrite struct {
-	Path string
-	Data []byte
+	config                *hsconfig.Config
+	project               *hsproject.Project
+	fileSelectedCallback  MPQExplorerFileSelectedCallback
+	findUsagesCallback    MPQExplorerReferenceCallback
+	goToReferenceCallback MPQExplorerReferenceCallback
+	inspectCallback       MPQExplorerInspectCallback
+	nodeCache             []g.Widget
+
+	// batch is the running (or last) extraction, overwritePolicy is the policy used by the next one
+	batchMutex      sync.Mutex
+	batch           *batch
+	overwritePolicy int32
 }
 
 // Create creates a new explorer
-func Create(fileSelectedCallback MPQExplorerFileSelectedCallback, config *hsconfig.Config, x, y float32) (*MPQExplorer, error) {
+func Create(fileSelectedCallback MPQExplorerFileSelectedCallback,
+	findUsagesCallback, goToReferenceCallback MPQExplorerReferenceCallback,
+	inspectCallback MPQExplorerInspectCallback, config *hsconfig.Config, x, y float32) (*MPQExplorer, error) {
 	result := &MPQExplorer{
-		ToolWindow:           hstoolwindow.New("MPQ Explorer", hsstate.ToolWindowTypeMPQExplorer, x, y),
-		fileSelectedCallback: fileSelectedCallback,
-		config:               config,
+		ToolWindow:            hstoolwindow.New("MPQ Explorer", hsstate.ToolWindowTypeMPQExplorer, x, y),
+		fileSelectedCallback:  fileSelectedCallback,
+		findUsagesCallback:    findUsagesCallback,
+		goToReferenceCallback: goToReferenceCallback,
+		inspectCallback:       inspectCallback,
+		config:                config,
 	}
 
 	return result, nil
@@ -66,34 +72,38 @@ func (m *MPQExplorer) Build() {
 		return
 	}
 
-	needToShowOverwritePrompt := len(m.filesToOverwrite) > 0
-	if needToShowOverwritePrompt {
-		m.IsOpen(&needToShowOverwritePrompt).Layout(g.Layout{
-			g.PopupModal("Overwrite File?").IsOpen(&needToShowOverwritePrompt).Layout(g.Layout{
-				g.Label("File at " + m.filesToOverwrite[0].Path + " already exists. Overwrite?"),
-				g.Line(
-					g.Button("Overwrite").OnClick(func() {
-						success := hsutil.CreateFileAtPath(m.filesToOverwrite[0].Path, m.filesToOverwrite[0].Data)
-						if success {
-							m.project.InvalidateFileStructure()
-						}
-						m.filesToOverwrite = m.filesToOverwrite[1:]
-					}),
-					g.Button("Cancel").OnClick(func() {
-						m.filesToOverwrite = m.filesToOverwrite[1:]
-					}),
-				),
-			})})
-	} else {
-		m.IsOpen(&m.Visible).
-			Size(mainWindowW, mainWindowH).
-			Layout(g.Layout{
-				g.Child("MpqExplorerContent").
-					Border(false).
-					Flags(g.WindowFlagsHorizontalScrollbar).
-					Layout(m.getMpqTreeNodes()),
-			})
+	m.batchMutex.Lock()
+	current := m.batch
+	m.batchMutex.Unlock()
+
+	layout := g.Layout{}
+
+	if current != nil {
+		layout = append(layout, current.layout(func() {
+			m.batchMutex.Lock()
+			if m.batch == current {
+				m.batch = nil
+			}
+			m.batchMutex.Unlock()
+		}))
 	}
+
+	policies := overwritePolicyNames()
+
+	layout = append(layout,
+		g.Line(
+			g.Label("Existing files:"),
+			g.Combo("##MPQExplorerOverwritePolicy", policies[m.overwritePolicy], policies, &m.overwritePolicy).Size(policyComboW),
+		),
+		g.Child("MpqExplorerContent").
+			Border(false).
+			Flags(g.WindowFlagsHorizontalScrollbar).
+			Layout(m.getMpqTreeNodes()),
+	)
+
+	m.IsOpen(&m.Visible).
+		Size(mainWindowW, mainWindowH).
+		Layout(layout)
 }
 
 func (m *MPQExplorer) getMpqTreeNodes() []g.Widget {
@@ -101,18 +111,16 @@ func (m *MPQExplorer) getMpqTreeNodes() []g.Widget {
 		return m.nodeCache
 	}
 
+	// the archives are opened once by the project when its auxiliary MPQs are (re)loaded
+	archives := m.project.AuxiliaryArchives()
+
 	wg := sync.WaitGroup{}
-	result := make([]g.Widget, len(m.project.AuxiliaryMPQs))
-	wg.Add(len(m.project.AuxiliaryMPQs))
+	result := make([]g.Widget, len(archives))
+	wg.Add(len(archives))
 
-	for mpqIndex := range m.project.AuxiliaryMPQs {
+	for mpqIndex := range archives {
 		go func(idx int) {
-			mpq, err := d2mpq.FromFile(filepath.Join(m.config.AuxiliaryMpqPath, m.project.AuxiliaryMPQs[idx]))
-			if err != nil {
-				log.Fatal("failed to load mpq: ", err)
-			}
-
-			nodes := m.project.GetMPQFileNodes(mpq, m.config)
+			nodes := m.project.GetMPQFileNodes(archives[idx], m.config)
 			result[idx] = m.renderNodes(nodes)
 
 			wg.Done()
@@ -135,11 +143,8 @@ func (m *MPQExplorer) renderNodes(pathEntry *hscommon.PathEntry) g.Widget {
 				OnClick(func() {
 					go m.fileSelectedCallback(pathEntry)
 				}),
-			g.ContextMenu("Context" + id).Layout(g.Layout{
-				g.Selectable("Copy to Project").OnClick(func() {
-					m.copyToProject(pathEntry)
-				}),
-			})}
+			g.ContextMenu("Context" + id).Layout(m.makeFileContextMenu(pathEntry)),
+		}
 	}
 
 	widgets := make([]g.Widget, len(pathEntry.Children))
@@ -158,41 +163,61 @@ func (m *MPQExplorer) renderNodes(pathEntry *hscommon.PathEntry) g.Widget {
 
 	wg.Wait()
 
-	return g.TreeNode(pathEntry.Name).Layout(widgets)
-}
+	if pathEntry.FullPath == "" {
+		// the root node of an archive
+		id := "##MPQExplorerArchive_" + pathEntry.MPQFile
 
-func (m *MPQExplorer) copyToProject(pathEntry *hscommon.PathEntry) {
-	data, err := pathEntry.GetFileBytes()
-	if err != nil {
-		log.Printf("failed to read file %s when copying to project: %s", pathEntry.FullPath, err)
-		return
+		return g.Layout{
+			g.TreeNode(pathEntry.Name + id).Layout(widgets),
+			g.ContextMenu("Context" + id).Layout(g.Layout{
+				g.Selectable("Inspect Archive").OnClick(func() {
+					go m.inspectCallback(pathEntry.MPQFile)
+				}),
+				g.Selectable("Extract to Disk...").OnClick(func() {
+					go m.extractToDisk(pathEntry)
+				}),
+			}),
+		}
 	}
 
-	pathToFile := pathEntry.FullPath
-	if strings.HasPrefix(pathEntry.FullPath, "data") {
-		// strip "data" from the beginning of the path if it exists
-		pathToFile = pathToFile[4:]
+	id := "##MPQExplorerFolder_" + pathEntry.MPQFile + pathEntry.FullPath
+
+	return g.Layout{
+		g.TreeNode(pathEntry.Name + id).Layout(widgets),
+		g.ContextMenu("Context" + id).Layout(g.Layout{
+			g.Selectable("Copy Folder to Project").OnClick(func() {
+				go m.copyToProject(pathEntry)
+			}),
+			g.Selectable("Extract to Disk...").OnClick(func() {
+				go m.extractToDisk(pathEntry)
+			}),
+		}),
 	}
+}
 
-	pathToFile = path.Join(m.project.GetProjectFileContentPath(), pathToFile)
-	pathToFile = strings.ReplaceAll(pathToFile, "\\", "/")
-
-	if _, err := os.Stat(pathToFile); err == nil {
-		// file already exists
-		fileInfo := fileToOverwrite{
-			Path: pathToFile,
-			Data: data,
-		}
-
-		m.filesToOverwrite = append(m.filesToOverwrite, fileInfo)
+func (m *MPQExplorer) makeFileContextMenu(pathEntry *hscommon.PathEntry) g.Layout {
+	result := g.Layout{
+		g.Selectable("Copy to Project").OnClick(func() {
+			go m.copyToProject(pathEntry)
+		}),
+		g.Selectable("Extract to Disk...").OnClick(func() {
+			go m.extractToDisk(pathEntry)
+		}),
+	}
 
-		return
+	if hsreference.CanBeReferenced(pathEntry.Name) {
+		result = append(result, g.Selectable("Find Usages").OnClick(func() {
+			m.findUsagesCallback(pathEntry)
+		}))
 	}
 
-	success := hsutil.CreateFileAtPath(pathToFile, data)
-	if success {
-		m.project.InvalidateFileStructure()
+	if hsreference.CanReference(pathEntry.Name) {
+		result = append(result, g.Selectable("Go To Referenced File").OnClick(func() {
+			m.goToReferenceCallback(pathEntry)
+		}))
 	}
+
+	return result
 }
 
 func generatePathEntryID(pathEntry *hscommon.PathEntry) string {
@@ -0,0 +1,392 @@
+// Package hsmpqinspector contains the tool window showing the internal structure of an MPQ archive
+package hsmpqinspector
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsmpq"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
+)
+
+const (
+	mainWindowW, mainWindowH = 800, 450
+	resultsH                 = 120
+	percent                  = 100
+	defaultStatus            = "Right click an archive in the MPQ Explorer and select Inspect Archive."
+)
+
+// columns of the block table, the table can be sorted by each of them
+const (
+	columnIndex = iota
+	columnName
+	columnPosition
+	columnCompressed
+	columnUncompressed
+	columnRatio
+	columnFlags
+	numColumns
+)
+
+// MPQInspector is a tool window showing the header, the hash table and the block table of an archive
+type MPQInspector struct {
+	*hstoolwindow.ToolWindow
+	config  *hsconfig.Config
+	project *hsproject.Project
+
+	mutex      sync.Mutex
+	info       *hsmpq.ArchiveInfo
+	named      int
+	header     string
+	status     string
+	problems   []string
+	blocks     []hsmpq.Block
+	rows       g.Rows
+	sortColumn int
+	sortDesc   bool
+
+	checking    bool
+	cancelCheck bool
+	progress    string
+	report      *hsmpq.IntegrityReport
+	reportRows  g.Rows
+}
+
+// Create creates a new mpq inspector
+func Create(config *hsconfig.Config, x, y float32) (*MPQInspector, error) {
+	result := &MPQInspector{
+		ToolWindow: hstoolwindow.New("MPQ Inspector", hsstate.ToolWindowTypeMPQInspector, x, y),
+		config:     config,
+		status:     defaultStatus,
+	}
+
+	return result, nil
+}
+
+// SetProject sets the project whose archives are inspected
+func (m *MPQInspector) SetProject(project *hsproject.Project) {
+	m.project = project
+
+	m.mutex.Lock()
+	m.cancelCheck = true
+	m.info = nil
+	m.rows = nil
+	m.report = nil
+	m.reportRows = nil
+	m.problems = nil
+	m.status = defaultStatus
+	m.mutex.Unlock()
+}
+
+// Inspect reads the tables of an archive and shows the window
+func (m *MPQInspector) Inspect(mpqPath string) {
+	m.Show()
+
+	m.mutex.Lock()
+	m.cancelCheck = true
+	m.info = nil
+	m.rows = nil
+	m.report = nil
+	m.reportRows = nil
+	m.problems = nil
+	m.status = fmt.Sprintf("Reading %s...", filepath.Base(mpqPath))
+	m.mutex.Unlock()
+
+	info, err := hsmpq.Inspect(mpqPath)
+	if err != nil {
+		m.mutex.Lock()
+		m.status = fmt.Sprintf("Could not read %s: %s", filepath.Base(mpqPath), err)
+		m.mutex.Unlock()
+
+		return
+	}
+
+	named := 0
+
+	// the names are needed to show the files and to decrypt encrypted files
+	if m.project != nil {
+		if archive, openErr := m.project.Archives().Open(mpqPath); openErr == nil {
+			if names, listErr := hsproject.MPQFileList(m.project.Archives(), archive, m.config); listErr == nil {
+				named = info.AssignNames(names)
+			}
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.info = info
+	m.named = named
+	m.problems = info.Problems()
+	m.status = ""
+	m.blocks = make([]hsmpq.Block, 0, len(info.Blocks))
+
+	for idx := range info.Blocks {
+		if info.Blocks[idx].Flags.Has(hsmpq.FlagExists) {
+			m.blocks = append(m.blocks, info.Blocks[idx])
+		}
+	}
+
+	m.header = m.describeHeader(info)
+	m.sortBlocks()
+}
+
+// Build builds the inspector window
+func (m *MPQInspector) Build() {
+	m.mutex.Lock()
+	info := m.info
+	header := m.header
+	status := m.status
+	rows := m.rows
+	problems := m.problems
+	checking := m.checking
+	progress := m.progress
+	report := m.report
+	reportRows := m.reportRows
+	m.mutex.Unlock()
+
+	var layout g.Layout
+
+	if info == nil {
+		layout = g.Layout{g.Label(status).Wrapped(true)}
+	} else {
+		layout = g.Layout{
+			g.Label(header).Wrapped(true),
+			g.Separator(),
+		}
+
+		checkButton := g.Button("Check Integrity##MPQInspectorCheck").OnClick(func() {
+			go m.checkIntegrity()
+		})
+
+		if checking {
+			checkButton = g.Button("Cancel##MPQInspectorCancel").OnClick(func() {
+				m.mutex.Lock()
+				m.cancelCheck = true
+				m.mutex.Unlock()
+			})
+		}
+
+		layout = append(layout, g.Line(checkButton, g.Label(progress)))
+
+		if len(problems) > 0 || report != nil {
+			layout = append(layout, g.Child("MPQInspectorResults").
+				Border(true).
+				Size(-1, resultsH).
+				Layout(m.resultsLayout(problems, report, reportRows)))
+		}
+
+		layout = append(layout,
+			g.Child("MPQInspectorBlocks").
+				Border(false).
+				Flags(g.WindowFlagsHorizontalScrollbar).
+				Layout(g.Layout{
+					g.FastTable("").Border(true).Rows(rows),
+				}),
+		)
+	}
+
+	m.IsOpen(&m.Visible).
+		Size(mainWindowW, mainWindowH).
+		Layout(layout)
+}
+
+func (m *MPQInspector) describeHeader(info *hsmpq.ArchiveInfo) string {
+	used, empty, deleted := 0, 0, 0
+
+	for idx := range info.Hashes {
+		switch {
+		case info.Hashes[idx].IsEmpty():
+			empty++
+		case info.Hashes[idx].IsDeleted():
+			deleted++
+		default:
+			used++
+		}
+	}
+
+	return fmt.Sprintf("%s: format version %d, header size %d bytes, archive size %d bytes (file size %d bytes), "+
+		"sector size %d bytes.\nHash table: %d entries at offset %d (%d used, %d empty, %d deleted).\n"+
+		"Block table: %d entries at offset %d (%d files, %d with a known name).",
+		filepath.Base(info.Path), info.Header.FormatVersion, info.Header.HeaderSize, info.Header.ArchiveSize, info.FileSize,
+		info.Header.SectorSize(), info.Header.HashTableEntries, info.Header.HashTableOffset, used, empty, deleted,
+		info.Header.BlockTableEntries, info.Header.BlockTableOffset, len(m.blocks), m.named)
+}
+
+func (m *MPQInspector) resultsLayout(problems []string, report *hsmpq.IntegrityReport, reportRows g.Rows) g.Layout {
+	result := g.Layout{}
+
+	for _, problem := range problems {
+		result = append(result, g.Label(problem))
+	}
+
+	if report == nil {
+		return result
+	}
+
+	summary := fmt.Sprintf("Integrity check: %d files decompressed, %d failed, %d encrypted files skipped (unknown name).",
+		report.Checked, len(report.Failures), report.Skipped)
+	if report.Canceled {
+		summary = "Canceled. " + summary
+	}
+
+	result = append(result, g.Label(summary))
+
+	if len(report.Failures) > 0 {
+		result = append(result, g.FastTable("").Border(true).Rows(reportRows))
+	}
+
+	return result
+}
+
+func (m *MPQInspector) checkIntegrity() {
+	m.mutex.Lock()
+	if m.checking || m.info == nil {
+		m.mutex.Unlock()
+		return
+	}
+
+	info := m.info
+	m.checking = true
+	m.cancelCheck = false
+	m.report = nil
+	m.mutex.Unlock()
+
+	report, err := info.CheckIntegrity(func(done, total int) bool {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		m.progress = fmt.Sprintf("Checking %d / %d", done, total)
+
+		return !m.cancelCheck
+	})
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.checking = false
+
+	if m.info != info {
+		// another archive was opened in the meantime
+		m.progress = ""
+		return
+	}
+
+	if err != nil {
+		m.progress = fmt.Sprintf("The integrity check failed: %s", err)
+		return
+	}
+
+	m.progress = ""
+	m.report = report
+	m.reportRows = g.Rows{g.Row(g.Label("Block"), g.Label("Name"), g.Label("Error"))}
+
+	for idx := range report.Failures {
+		failure := &report.Failures[idx]
+		m.reportRows = append(m.reportRows, g.Row(
+			g.Label(fmt.Sprintf("%d", failure.Block.Index)),
+			g.Label(info.BlockName(&failure.Block)),
+			g.Label(failure.Err.Error()),
+		))
+	}
+}
+
+// sortBy sorts the block table by a column, selecting the sorted column again reverses the order
+func (m *MPQInspector) sortBy(column int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.sortColumn == column {
+		m.sortDesc = !m.sortDesc
+	} else {
+		m.sortColumn, m.sortDesc = column, false
+	}
+
+	m.sortBlocks()
+}
+
+func (m *MPQInspector) sortBlocks() {
+	blocks := m.blocks
+	info := m.info
+
+	less := func(a, b *hsmpq.Block) bool {
+		switch m.sortColumn {
+		case columnName:
+			return strings.ToLower(info.BlockName(a)) < strings.ToLower(info.BlockName(b))
+		case columnPosition:
+			return a.FilePosition < b.FilePosition
+		case columnCompressed:
+			return a.CompressedSize < b.CompressedSize
+		case columnUncompressed:
+			return a.UncompressedSize < b.UncompressedSize
+		case columnRatio:
+			return a.Ratio() < b.Ratio()
+		case columnFlags:
+			return a.Flags.String() < b.Flags.String()
+		default:
+			return a.Index < b.Index
+		}
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		if m.sortDesc {
+			return less(&blocks[j], &blocks[i])
+		}
+
+		return less(&blocks[i], &blocks[j])
+	})
+
+	m.updateRows()
+}
+
+func (m *MPQInspector) updateRows() {
+	titles := [numColumns]string{"Block", "Name", "Position", "Compressed", "Uncompressed", "Ratio", "Flags"}
+	header := make([]g.Widget, numColumns)
+
+	for column := range titles {
+		title := titles[column]
+		if column == m.sortColumn {
+			if m.sortDesc {
+				title += " v"
+			} else {
+				title += " ^"
+			}
+		}
+
+		sortColumn := column
+		header[column] = g.Selectable(title + "##MPQInspectorSort" + titles[column]).OnClick(func() {
+			m.sortBy(sortColumn)
+		})
+	}
+
+	rows := g.Rows{g.Row(header...)}
+
+	for idx := range m.blocks {
+		block := &m.blocks[idx]
+
+		flags := block.Flags.String()
+		if flags == "" {
+			flags = "-"
+		}
+
+		rows = append(rows, g.Row(
+			g.Label(fmt.Sprintf("%d", block.Index)),
+			g.Label(m.info.BlockName(block)),
+			g.Label(fmt.Sprintf("%d", block.FilePosition)),
+			g.Label(fmt.Sprintf("%d", block.CompressedSize)),
+			g.Label(fmt.Sprintf("%d", block.UncompressedSize)),
+			g.Label(fmt.Sprintf("%.1f%%", block.Ratio()*percent)),
+			g.Label(flags),
+		))
+	}
+
+	m.rows = rows
+}
@@ -0,0 +1,190 @@
+// Package hsoverridereport contains the override report tool window
+package hsoverridereport
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/OpenDiablo2/dialog"
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
+)
+
+const (
+	mainWindowW, mainWindowH = 600, 400
+)
+
+// OverrideReportFileSelectedCallback represents file selected callback
+type OverrideReportFileSelectedCallback func(path *hscommon.PathEntry)
+
+// OverrideReport is a tool window listing which copy of each file wins, and whether it differs
+// from the copy it overrides
+type OverrideReport struct {
+	*hstoolwindow.ToolWindow
+	config               *hsconfig.Config
+	project              *hsproject.Project
+	fileSelectedCallback OverrideReportFileSelectedCallback
+
+	mutex         sync.Mutex
+	report        *hsproject.OverrideReport
+	rows          g.Rows
+	generating    bool
+	showIdentical bool
+}
+
+// Create creates a new override report window
+func Create(fileSelectedCallback OverrideReportFileSelectedCallback,
+	config *hsconfig.Config, x, y float32) (*OverrideReport, error) {
+	result := &OverrideReport{
+		ToolWindow:           hstoolwindow.New("Override Report", hsstate.ToolWindowTypeOverrideReport, x, y),
+		fileSelectedCallback: fileSelectedCallback,
+		config:               config,
+		showIdentical:        true,
+	}
+
+	return result, nil
+}
+
+// SetProject sets the project the report is generated for
+func (r *OverrideReport) SetProject(project *hsproject.Project) {
+	r.project = project
+	r.Reset()
+}
+
+// Reset discards the current report
+func (r *OverrideReport) Reset() {
+	r.mutex.Lock()
+	r.report = nil
+	r.rows = nil
+	r.mutex.Unlock()
+}
+
+// Build builds the report window
+func (r *OverrideReport) Build() {
+	if r.project == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	generating := r.generating
+	report := r.report
+	rows := r.rows
+	r.mutex.Unlock()
+
+	var content g.Widget
+
+	switch {
+	case generating:
+		content = g.Label("Comparing files, please wait...")
+	case report == nil:
+		content = g.Label("Press Generate to compare the project with its auxiliary MPQs.")
+	case len(rows) < 2:
+		content = g.Label("The project does not override any files.")
+	default:
+		content = g.Child("OverrideReportContent").
+			Border(false).
+			Flags(g.WindowFlagsHorizontalScrollbar).
+			Layout(g.Layout{
+				g.FastTable("").Border(true).Rows(rows),
+			})
+	}
+
+	r.IsOpen(&r.Visible).
+		Size(mainWindowW, mainWindowH).
+		Layout(g.Layout{
+			g.Line(
+				g.Button("Generate##OverrideReportGenerate").OnClick(r.generate),
+				g.Button("Export JSON...##OverrideReportExport").OnClick(r.onExportClicked),
+				g.Checkbox("Show identical files##OverrideReportShowIdentical", &r.showIdentical).OnChange(r.updateRows),
+			),
+			g.Label("Load order: " + strings.Join(r.project.LoadOrderNames(), " > ")),
+			g.Separator(),
+			content,
+		})
+}
+
+func (r *OverrideReport) generate() {
+	r.mutex.Lock()
+	if r.generating {
+		r.mutex.Unlock()
+		return
+	}
+
+	r.generating = true
+	r.mutex.Unlock()
+
+	go func() {
+		report := r.project.GenerateOverrideReport(r.config)
+
+		r.mutex.Lock()
+		r.report = report
+		r.generating = false
+		r.mutex.Unlock()
+
+		r.updateRows()
+	}()
+}
+
+func (r *OverrideReport) updateRows() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.report == nil {
+		r.rows = nil
+		return
+	}
+
+	rows := g.Rows{
+		g.Row(g.Label("Path"), g.Label("Status"), g.Label("Winner"), g.Label("Sources")),
+	}
+
+	for idx := range r.report.Entries {
+		entry := r.report.Entries[idx]
+
+		if entry.Status == hsproject.OverrideStatusIdentical && !r.showIdentical {
+			continue
+		}
+
+		status := string(entry.Status)
+		if entry.Error != "" {
+			status = fmt.Sprintf("%s (%s)", status, entry.Error)
+		}
+
+		rows = append(rows, g.Row(
+			g.Selectable(entry.Path+"##OverrideReportRow_"+entry.Path).OnClick(func() {
+				go r.fileSelectedCallback(entry.WinnerEntry)
+			}),
+			g.Label(status),
+			g.Label(entry.Winner),
+			g.Label(strings.Join(entry.Sources, ", ")),
+		))
+	}
+
+	r.rows = rows
+}
+
+func (r *OverrideReport) onExportClicked() {
+	r.mutex.Lock()
+	report := r.report
+	r.mutex.Unlock()
+
+	if report == nil {
+		dialog.Message("Generate the report before exporting it.").Error()
+		return
+	}
+
+	file, err := dialog.File().Filter("JSON File", "json").Title("Export Override Report").Save()
+	if err != nil || file == "" {
+		return
+	}
+
+	if err := report.SaveToFile(file); err != nil {
+		dialog.Message("Could not export report:\n%s", err).Error()
+	}
+}
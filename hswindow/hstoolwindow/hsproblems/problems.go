@@ -0,0 +1,163 @@
+// Package hsproblems contains the problems tool window, which shows the results of the project validation
+package hsproblems
+
+import (
+	"fmt"
+	"sync"
+
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
+)
+
+const (
+	mainWindowW, mainWindowH = 600, 300
+)
+
+// ProblemsFileSelectedCallback represents file selected callback
+type ProblemsFileSelectedCallback func(path *hscommon.PathEntry)
+
+// Problems is a tool window listing the problems found by validating the project
+type Problems struct {
+	*hstoolwindow.ToolWindow
+	config               *hsconfig.Config
+	project              *hsproject.Project
+	fileSelectedCallback ProblemsFileSelectedCallback
+
+	mutex        sync.Mutex
+	report       *hsproject.ValidationReport
+	rows         g.Rows
+	validating   bool
+	showWarnings bool
+}
+
+// Create creates a new problems window
+func Create(fileSelectedCallback ProblemsFileSelectedCallback,
+	config *hsconfig.Config, x, y float32) (*Problems, error) {
+	result := &Problems{
+		ToolWindow:           hstoolwindow.New("Problems", hsstate.ToolWindowTypeProblems, x, y),
+		fileSelectedCallback: fileSelectedCallback,
+		config:               config,
+		showWarnings:         true,
+	}
+
+	return result, nil
+}
+
+// SetProject sets the project to validate
+func (p *Problems) SetProject(project *hsproject.Project) {
+	p.project = project
+	p.Reset()
+}
+
+// Reset discards the results of the last validation
+func (p *Problems) Reset() {
+	p.mutex.Lock()
+	p.report = nil
+	p.rows = nil
+	p.mutex.Unlock()
+}
+
+// Build builds the problems window
+func (p *Problems) Build() {
+	if p.project == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	validating := p.validating
+	report := p.report
+	rows := p.rows
+	p.mutex.Unlock()
+
+	var content g.Widget
+
+	switch {
+	case validating:
+		content = g.Label("Validating project, please wait...")
+	case report == nil:
+		content = g.Label("Press Validate to check every file of the project.")
+	default:
+		content = g.Layout{
+			g.Label(fmt.Sprintf("%d files checked, %d problems found.", report.FileCount, len(report.Problems))),
+			g.Child("ProblemsContent").
+				Border(false).
+				Flags(g.WindowFlagsHorizontalScrollbar).
+				Layout(g.Layout{
+					g.FastTable("").Border(true).Rows(rows),
+				}),
+		}
+	}
+
+	p.IsOpen(&p.Visible).
+		Size(mainWindowW, mainWindowH).
+		Layout(g.Layout{
+			g.Line(
+				g.Button("Validate##ProblemsValidate").OnClick(p.Validate),
+				g.Checkbox("Show warnings##ProblemsShowWarnings", &p.showWarnings).OnChange(p.updateRows),
+			),
+			g.Separator(),
+			content,
+		})
+}
+
+// Validate validates the project in the background and shows the window
+func (p *Problems) Validate() {
+	p.Show()
+
+	p.mutex.Lock()
+	if p.validating {
+		p.mutex.Unlock()
+		return
+	}
+
+	p.validating = true
+	p.mutex.Unlock()
+
+	go func() {
+		report := p.project.Validate(p.config)
+
+		p.mutex.Lock()
+		p.report = report
+		p.validating = false
+		p.mutex.Unlock()
+
+		p.updateRows()
+	}()
+}
+
+func (p *Problems) updateRows() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.report == nil {
+		p.rows = nil
+		return
+	}
+
+	rows := g.Rows{
+		g.Row(g.Label("Severity"), g.Label("File"), g.Label("Message")),
+	}
+
+	for idx := range p.report.Problems {
+		problem := p.report.Problems[idx]
+
+		if problem.Severity == hsproject.SeverityWarning && !p.showWarnings {
+			continue
+		}
+
+		rows = append(rows, g.Row(
+			g.Label(string(problem.Severity)),
+			g.Selectable(fmt.Sprintf("%s##ProblemsRow_%d", problem.Path, idx)).OnClick(func() {
+				go p.fileSelectedCallback(problem.Entry)
+			}),
+			g.Label(problem.Message),
+		))
+	}
+
+	p.rows = rows
+}
@@ -2,6 +2,7 @@
 package hsprojectexplorer
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -16,8 +17,10 @@ import (
 	"github.com/OpenDiablo2/HellSpawner/hscommon"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsfiletypes"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsreference"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
 	"github.com/OpenDiablo2/HellSpawner/hscommon/hsutil"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
 	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
 )
 
@@ -32,6 +35,13 @@ const (
 	blackHalfOpacity = 0xffffff20
 )
 
+const (
+	// dragDropPayloadType identifies the files and folders dragged in the project explorer
+	dragDropPayloadType = "HS_PROJECT_PATH"
+	// maxPreviewLines is the number of references listed when asking to confirm a move
+	maxPreviewLines = 15
+)
+
 const (
 	refreshItemButtonPath = "3rdparty/iconpack-obsidian/Obsidian/actions/16/reload.png"
 )
@@ -39,24 +49,53 @@ const (
 // ProjectExplorerFileSelectedCallback represents callback on project file selected
 type ProjectExplorerFileSelectedCallback func(path *hscommon.PathEntry)
 
+// ProjectExplorerReferenceCallback represents callback on "Find Usages" or "Go To Referenced File" clicked
+type ProjectExplorerReferenceCallback func(path *hscommon.PathEntry)
+
+// ProjectExplorerLocalHistoryCallback represents callback on "Local History" clicked
+type ProjectExplorerLocalHistoryCallback func(path *hscommon.PathEntry)
+
+// ProjectExplorerDeletedCallback represents callback on files or folders moved to the recycle bin
+type ProjectExplorerDeletedCallback func()
+
+// ProjectExplorerFilesMovedCallback represents callback on files moved or renamed, moves maps old paths to new paths
+type ProjectExplorerFilesMovedCallback func(moves map[string]string)
+
 // ProjectExplorer represents a project explorer
 type ProjectExplorer struct {
 	*hstoolwindow.ToolWindow
 
-	project              *hsproject.Project
-	fileSelectedCallback ProjectExplorerFileSelectedCallback
-	nodeCache            map[string][]g.Widget
-	refreshIconTexture   *g.Texture
+	project               *hsproject.Project
+	config                *hsconfig.Config
+	fileSelectedCallback  ProjectExplorerFileSelectedCallback
+	findUsagesCallback    ProjectExplorerReferenceCallback
+	goToReferenceCallback ProjectExplorerReferenceCallback
+	localHistoryCallback  ProjectExplorerLocalHistoryCallback
+	filesMovedCallback    ProjectExplorerFilesMovedCallback
+	deletedCallback       ProjectExplorerDeletedCallback
+	nodeCache             map[string][]g.Widget
+	refreshIconTexture    *g.Texture
 }
 
 // Create creates a new project explorer
 func Create(textureLoader *hscommon.TextureLoader,
 	fileSelectedCallback ProjectExplorerFileSelectedCallback,
+	findUsagesCallback, goToReferenceCallback ProjectExplorerReferenceCallback,
+	localHistoryCallback ProjectExplorerLocalHistoryCallback,
+	filesMovedCallback ProjectExplorerFilesMovedCallback,
+	deletedCallback ProjectExplorerDeletedCallback,
+	config *hsconfig.Config,
 	x, y float32) (*ProjectExplorer, error) {
 	result := &ProjectExplorer{
-		ToolWindow:           hstoolwindow.New("Project Explorer", hsstate.ToolWindowTypeProjectExplorer, x, y),
-		nodeCache:            make(map[string][]g.Widget),
-		fileSelectedCallback: fileSelectedCallback,
+		ToolWindow:            hstoolwindow.New("Project Explorer", hsstate.ToolWindowTypeProjectExplorer, x, y),
+		config:                config,
+		nodeCache:             make(map[string][]g.Widget),
+		fileSelectedCallback:  fileSelectedCallback,
+		findUsagesCallback:    findUsagesCallback,
+		goToReferenceCallback: goToReferenceCallback,
+		localHistoryCallback:  localHistoryCallback,
+		filesMovedCallback:    filesMovedCallback,
+		deletedCallback:       deletedCallback,
 	}
 	result.Visible = false
 
@@ -133,13 +172,16 @@ func (m *ProjectExplorer) getProjectTreeNodes() g.Layout {
 		return []g.Widget{g.Label("No project loaded...")}
 	}
 
-	fileStructure := m.project.GetFileStructure()
+	fileStructure, err := m.project.GetFileStructure()
+	if err != nil {
+		return []g.Widget{g.Label("Could not read the project files:"), g.Label(err.Error())}
+	}
 
 	if fileStructure == nil {
 		return []g.Widget{g.Label("No file structure detected...")}
 	}
 
-	return []g.Widget{m.renderNodes(m.project.GetFileStructure())}
+	return []g.Widget{m.renderNodes(fileStructure)}
 }
 
 func (m *ProjectExplorer) onRefreshProjectExplorerClicked() {
@@ -194,15 +236,26 @@ func (m *ProjectExplorer) createFileTreeItem(pathEntry *hscommon.PathEntry) g.Wi
 	} else {
 		layout = append(layout, g.Selectable(pathEntry.Name+id).OnClick(func() {
 			m.fileSelectedCallback(pathEntry)
-		}))
+		}), g.Custom(func() { m.dragDropSource(pathEntry) }))
 	}
 
-	layout = append(layout,
-		g.ContextMenu("Context"+id).Layout(g.Layout{
-			g.MenuItem("Rename").OnClick(func() { m.onRenameFileClicked(pathEntry) }),
-			g.MenuItem("Delete...").OnClick(func() { m.onDeleteFileClicked(pathEntry) }),
-		}),
-	)
+	contextMenuLayout := g.Layout{
+		g.MenuItem("Rename").OnClick(func() { m.onRenameFileClicked(pathEntry) }),
+		g.MenuItem("Delete...").OnClick(func() { m.onDeleteFileClicked(pathEntry) }),
+		g.MenuItem("Local History").OnClick(func() { m.localHistoryCallback(pathEntry) }),
+	}
+
+	if hsreference.CanBeReferenced(pathEntry.Name) {
+		contextMenuLayout = append(contextMenuLayout,
+			g.MenuItem("Find Usages").OnClick(func() { m.findUsagesCallback(pathEntry) }))
+	}
+
+	if hsreference.CanReference(pathEntry.Name) {
+		contextMenuLayout = append(contextMenuLayout,
+			g.MenuItem("Go To Referenced File").OnClick(func() { m.goToReferenceCallback(pathEntry) }))
+	}
+
+	layout = append(layout, g.ContextMenu("Context"+id).Layout(contextMenuLayout))
 
 	return layout
 }
@@ -244,39 +297,76 @@ func (m *ProjectExplorer) createDirectoryTreeItem(pathEntry *hscommon.PathEntry,
 		g.Custom(func() { imgui.PopID() }),
 	}
 
+	// the event handler runs right after the node's header, whether the node is open or not
+	dragDrop := func() {
+		if !pathEntry.IsRoot {
+			m.dragDropSource(pathEntry)
+		}
+
+		m.dragDropTarget(pathEntry)
+	}
+
 	if layout == nil {
-		return g.TreeNode(id).Layout(menuLayout)
+		return g.TreeNode(id).Event(dragDrop).Layout(menuLayout)
 	}
 
-	return g.TreeNode(id).Layout(append(menuLayout, layout...))
+	return g.TreeNode(id).Event(dragDrop).Layout(append(menuLayout, layout...))
 }
 
-func (m *ProjectExplorer) onDeleteFolderClicked(entry *hscommon.PathEntry) {
-	if !dialog.Message("Are you sure you want to delete:\n%s", entry.FullPath).YesNo() {
+// dragDropSource lets the last item, which shows the given file or folder, be dragged onto a folder
+func (m *ProjectExplorer) dragDropSource(pathEntry *hscommon.PathEntry) {
+	if !imgui.BeginDragDropSource(imgui.DragDropFlagsNone) {
 		return
 	}
 
-	if err := os.RemoveAll(entry.FullPath); err != nil {
-		dialog.Message("Could not delete:\n%s", entry.FullPath).Error()
+	imgui.SetDragDropPayload(dragDropPayloadType, []byte(pathEntry.FullPath), imgui.ConditionNone)
+	g.Label(pathEntry.Name).Build()
+	imgui.EndDragDropSource()
+}
 
+// dragDropTarget moves the files and folders dropped onto the last item into the given folder
+func (m *ProjectExplorer) dragDropTarget(pathEntry *hscommon.PathEntry) {
+	if !imgui.BeginDragDropTarget() {
 		return
 	}
 
-	m.project.InvalidateFileStructure()
+	payload := imgui.AcceptDragDropPayload(dragDropPayloadType, imgui.DragDropFlagsNone)
+	imgui.EndDragDropTarget()
+
+	if len(payload) == 0 {
+		return
+	}
+
+	oldPath := string(payload)
+	if filepath.Dir(oldPath) == pathEntry.FullPath || oldPath == pathEntry.FullPath {
+		return
+	}
+
+	m.moveFile(oldPath, filepath.Join(pathEntry.FullPath, filepath.Base(oldPath)))
+}
+
+func (m *ProjectExplorer) onDeleteFolderClicked(entry *hscommon.PathEntry) {
+	m.deleteEntry(entry)
 }
 
 func (m *ProjectExplorer) onDeleteFileClicked(entry *hscommon.PathEntry) {
-	if !dialog.Message("Are you sure you want to delete:\n%s", entry.FullPath).YesNo() {
+	m.deleteEntry(entry)
+}
+
+// deleteEntry moves a file or folder into the project's recycle bin, the last delete can be undone with Ctrl+Z
+func (m *ProjectExplorer) deleteEntry(entry *hscommon.PathEntry) {
+	if !dialog.Message("Are you sure you want to delete:\n%s\n\nIt is moved to the recycle bin.", entry.FullPath).YesNo() {
 		return
 	}
 
-	if err := os.Remove(entry.FullPath); err != nil {
-		dialog.Message("Could not delete:\n%s", entry.FullPath).Error()
+	if _, err := m.project.Trash().Delete(entry.FullPath); err != nil {
+		dialog.Message("Could not delete:\n%s\n%s", entry.FullPath, err).Error()
 
 		return
 	}
 
 	m.project.InvalidateFileStructure()
+	m.deletedCallback()
 }
 
 func (m *ProjectExplorer) onRenameFileClicked(entry *hscommon.PathEntry) {
@@ -301,45 +391,88 @@ func (m *ProjectExplorer) onFileRenamed(entry *hscommon.PathEntry) {
 		return
 	}
 
-	if filepath.Ext(entry.Name) == "" {
+	if !entry.IsDirectory && filepath.Ext(entry.Name) == "" {
 		entry.Name += filepath.Ext(entry.OldName)
 	}
 
-	if !strings.EqualFold(filepath.Ext(entry.OldName), filepath.Ext(entry.Name)) {
-		dialog.Message("Cannot rename file:\nFile extension cannot be changed.").Error()
+	basePath := filepath.Dir(entry.FullPath)
 
+	oldPath := filepath.Join(basePath, entry.OldName)
+
+	newPath := filepath.Join(basePath, entry.Name)
+
+	if !m.moveFile(oldPath, newPath) {
 		entry.Name = entry.OldName
+	}
 
-		entry.OldName = ""
+	entry.OldName = ""
+}
 
-		return
+// moveFile moves or renames a file or folder, after showing the references to it which will be updated.
+// It returns false if the file wasn't moved.
+func (m *ProjectExplorer) moveFile(oldPath, newPath string) bool {
+	plan, err := m.project.PlanMove(oldPath, newPath, m.config)
+	if err != nil {
+		dialog.Message("Cannot move %s:\n%s", filepath.Base(oldPath), err).Error()
+
+		return false
 	}
 
-	basePath := filepath.Dir(entry.FullPath)
+	if !plan.IsEmpty() && !dialog.Message("%s", m.movePreview(plan)).Title("Move").YesNo() {
+		return false
+	}
 
-	oldPath := filepath.Join(basePath, entry.OldName)
+	if err := m.project.ApplyMove(plan, m.config); err != nil {
+		dialog.Message("Could not move %s:\n%s", filepath.Base(oldPath), err).Error()
 
-	newPath := filepath.Join(basePath, entry.Name)
+		if _, statErr := os.Stat(oldPath); statErr == nil {
+			// nothing was moved
+			return false
+		}
+	}
 
-	if _, err := os.Stat(newPath); !os.IsNotExist(err) {
-		dialog.Message("Cannot rename file:\nAlready exists.").Error()
+	m.filesMovedCallback(plan.Files)
 
-		entry.Name = entry.OldName
-		entry.OldName = ""
+	return true
+}
 
-		return
+// movePreview describes what a move changes, to confirm it before the files are touched
+func (m *ProjectExplorer) movePreview(plan *hsproject.MovePlan) string {
+	relPath := func(path string) string {
+		if rel, err := filepath.Rel(m.project.GetProjectFileContentPath(), path); err == nil {
+			return rel
+		}
+
+		return path
 	}
 
-	if err := os.Rename(oldPath, newPath); err != nil {
-		dialog.Message("Could not rename file:\n" + err.Error()).Error()
+	lines := []string{fmt.Sprintf("Move %s to %s?", relPath(plan.OldPath), relPath(plan.NewPath))}
 
-		entry.Name = entry.OldName
-		entry.OldName = ""
+	if len(plan.References) > 0 {
+		lines = append(lines, "", "These references will be updated:")
 
-		return
+		for idx := range plan.References {
+			if idx == maxPreviewLines {
+				lines = append(lines, fmt.Sprintf("...and %d more", len(plan.References)-maxPreviewLines))
+				break
+			}
+
+			reference := plan.References[idx]
+			lines = append(lines, fmt.Sprintf("%s (%s): %s -> %s", relPath(reference.Path), reference.Kind,
+				relPath(reference.OldTarget), relPath(reference.NewTarget)))
+		}
 	}
 
-	m.project.InvalidateFileStructure()
+	if plan.EditorStates > 0 {
+		lines = append(lines, "", fmt.Sprintf("%d saved editor windows will be updated.", plan.EditorStates))
+	}
+
+	if len(plan.Warnings) > 0 {
+		lines = append(lines, "", "Warnings:")
+		lines = append(lines, plan.Warnings...)
+	}
+
+	return strings.Join(lines, "\n")
 }
 
 func (m *ProjectExplorer) onNewFolderClicked(pathEntry *hscommon.PathEntry) {
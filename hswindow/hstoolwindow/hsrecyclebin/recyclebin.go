@@ -0,0 +1,184 @@
+// Package hsrecyclebin contains the tool window listing the project's deleted files and folders
+package hsrecyclebin
+
+import (
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/OpenDiablo2/dialog"
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hstrash"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
+)
+
+const (
+	mainWindowW, mainWindowH = 650, 300
+	timeFormat               = "2006-01-02 15:04:05"
+)
+
+// RestoredCallback is called after an item was restored, so the views of the project can be refreshed
+type RestoredCallback func()
+
+// RecycleBin is a tool window listing the items of the project's trash, which can be restored or purged
+type RecycleBin struct {
+	*hstoolwindow.ToolWindow
+	project          *hsproject.Project
+	restoredCallback RestoredCallback
+
+	mutex  sync.Mutex
+	items  []hstrash.Item
+	status string
+	rows   g.Rows
+}
+
+// Create creates a new recycle bin window
+func Create(restoredCallback RestoredCallback, x, y float32) (*RecycleBin, error) {
+	result := &RecycleBin{
+		ToolWindow:       hstoolwindow.New("Recycle Bin", hsstate.ToolWindowTypeRecycleBin, x, y),
+		restoredCallback: restoredCallback,
+	}
+
+	return result, nil
+}
+
+// SetProject sets the project whose trash is shown
+func (r *RecycleBin) SetProject(project *hsproject.Project) {
+	r.project = project
+	r.Refresh()
+}
+
+// Refresh reads the items of the trash again, it has to be called after files were deleted
+func (r *RecycleBin) Refresh() {
+	if r.project == nil {
+		return
+	}
+
+	items, err := r.project.Trash().Items()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err != nil {
+		r.items = nil
+		r.status = fmt.Sprintf("Could not read the recycle bin: %s", err)
+		r.rows = nil
+
+		return
+	}
+
+	r.items = items
+	r.status = fmt.Sprintf("%d deleted items.", len(items))
+	r.updateRows()
+}
+
+// Build builds the recycle bin window
+func (r *RecycleBin) Build() {
+	if r.project == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	status := r.status
+	rows := r.rows
+	empty := len(r.items) == 0
+	r.mutex.Unlock()
+
+	layout := g.Layout{
+		g.Line(
+			g.Button("Refresh##RecycleBinRefresh").OnClick(r.Refresh),
+			g.Custom(func() {
+				if !empty {
+					g.Button("Empty Recycle Bin##RecycleBinEmpty").OnClick(r.onEmptyClicked).Build()
+				}
+			}),
+		),
+		g.Label(status),
+		g.Separator(),
+	}
+
+	if len(rows) > 1 {
+		layout = append(layout, g.Child("RecycleBinContent").
+			Border(false).
+			Flags(g.WindowFlagsHorizontalScrollbar).
+			Layout(g.Layout{
+				g.FastTable("").Border(true).Rows(rows),
+			}))
+	}
+
+	r.IsOpen(&r.Visible).
+		Size(mainWindowW, mainWindowH).
+		Layout(layout)
+}
+
+// updateRows has to be called with the mutex locked
+func (r *RecycleBin) updateRows() {
+	rows := g.Rows{
+		g.Row(g.Label("Name"), g.Label("Deleted From"), g.Label("Deleted"), g.Label("Size"), g.Label("")),
+	}
+
+	for idx := range r.items {
+		item := r.items[idx]
+
+		name := item.Name()
+		if item.IsDirectory {
+			name += "/"
+		}
+
+		dir := path.Dir(item.Path)
+		if dir == "." {
+			dir = ""
+		}
+
+		rows = append(rows, g.Row(
+			g.Label(name),
+			g.Label("/"+dir),
+			g.Label(item.Time.Local().Format(timeFormat)),
+			g.Label(fmt.Sprintf("%d bytes", item.Size)),
+			g.Line(
+				g.Button("Restore##RecycleBinRestore_"+item.ID).OnClick(func() { r.onRestoreClicked(item) }),
+				g.Button("Purge##RecycleBinPurge_"+item.ID).OnClick(func() { r.onPurgeClicked(item) }),
+			),
+		))
+	}
+
+	r.rows = rows
+}
+
+func (r *RecycleBin) onRestoreClicked(item hstrash.Item) {
+	if _, err := r.project.Trash().Restore(item.ID); err != nil {
+		dialog.Message("Could not restore %s:\n%s", item.Path, err).Error()
+		return
+	}
+
+	r.Refresh()
+	r.restoredCallback()
+}
+
+func (r *RecycleBin) onPurgeClicked(item hstrash.Item) {
+	if !dialog.Message("Permanently delete %s?\nIt can't be restored afterwards.", item.Path).Title("Purge").YesNo() {
+		return
+	}
+
+	if err := r.project.Trash().Purge(item.ID); err != nil {
+		dialog.Message("Could not purge %s:\n%s", item.Path, err).Error()
+	}
+
+	r.Refresh()
+}
+
+func (r *RecycleBin) onEmptyClicked() {
+	if !dialog.Message("Permanently delete every item of the recycle bin?\nThey can't be restored afterwards.").
+		Title("Empty Recycle Bin").YesNo() {
+		return
+	}
+
+	if err := r.project.Trash().Empty(); err != nil {
+		dialog.Message("Could not empty the recycle bin:\n%s", err).Error()
+	}
+
+	r.Refresh()
+}
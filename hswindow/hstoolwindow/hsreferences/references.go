@@ -0,0 +1,187 @@
+// Package hsreferences contains the tool window listing the references between files
+package hsreferences
+
+import (
+	"fmt"
+	"sync"
+
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsreference"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
+)
+
+const (
+	mainWindowW, mainWindowH = 600, 300
+)
+
+// ReferencesFileSelectedCallback represents file selected callback
+type ReferencesFileSelectedCallback func(path *hscommon.PathEntry)
+
+// References is a tool window which lists the usages of a file, or the files referenced by a file
+type References struct {
+	*hstoolwindow.ToolWindow
+	config               *hsconfig.Config
+	project              *hsproject.Project
+	fileSelectedCallback ReferencesFileSelectedCallback
+
+	// indexMutex is held while the index is built, so it is only built once
+	indexMutex sync.Mutex
+	index      *hsreference.Index
+	vfs        *hsproject.VirtualFileSystem
+
+	mutex  sync.Mutex
+	status string
+	rows   g.Rows
+}
+
+// Create creates a new references window
+func Create(fileSelectedCallback ReferencesFileSelectedCallback,
+	config *hsconfig.Config, x, y float32) (*References, error) {
+	result := &References{
+		ToolWindow:           hstoolwindow.New("References", hsstate.ToolWindowTypeReferences, x, y),
+		fileSelectedCallback: fileSelectedCallback,
+		config:               config,
+	}
+
+	return result, nil
+}
+
+// SetProject sets the project whose references are shown
+func (r *References) SetProject(project *hsproject.Project) {
+	r.project = project
+	r.Reset()
+
+	r.mutex.Lock()
+	r.status = ""
+	r.rows = nil
+	r.mutex.Unlock()
+}
+
+// Reset discards the reference index, it is rebuilt the next time it is needed
+func (r *References) Reset() {
+	r.indexMutex.Lock()
+	r.index = nil
+	r.vfs = nil
+	r.indexMutex.Unlock()
+}
+
+// Build builds the references window
+func (r *References) Build() {
+	if r.project == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	status := r.status
+	rows := r.rows
+	r.mutex.Unlock()
+
+	var content g.Widget = g.Label("")
+
+	if len(rows) > 1 {
+		content = g.Child("ReferencesContent").
+			Border(false).
+			Flags(g.WindowFlagsHorizontalScrollbar).
+			Layout(g.Layout{
+				g.FastTable("").Border(true).Rows(rows),
+			})
+	}
+
+	r.IsOpen(&r.Visible).
+		Size(mainWindowW, mainWindowH).
+		Layout(g.Layout{
+			g.Label(status),
+			g.Separator(),
+			content,
+		})
+}
+
+// ShowUsages lists every file referencing the given file
+func (r *References) ShowUsages(entry *hscommon.PathEntry) {
+	r.Show()
+
+	go func() {
+		path := r.project.VirtualPath(entry)
+		index, vfs := r.getIndex()
+		usages := index.Usages(path)
+
+		rows := g.Rows{g.Row(g.Label("Used by"), g.Label("Kind"), g.Label("Where"))}
+
+		for idx := range usages {
+			usage := usages[idx]
+
+			rows = append(rows, g.Row(
+				r.makeLink(vfs, usage.Source, idx),
+				g.Label(string(usage.Kind)),
+				g.Label(usage.Detail),
+			))
+		}
+
+		r.setContent(fmt.Sprintf("Usages of %s: %d", path, len(usages)), rows)
+	}()
+}
+
+// GoToReferencedFile opens the file referenced by the given file. When it references more
+// than one file, they are listed in the window instead.
+func (r *References) GoToReferencedFile(entry *hscommon.PathEntry) {
+	go func() {
+		path := r.project.VirtualPath(entry)
+		index, vfs := r.getIndex()
+		references := index.References(path)
+
+		if len(references) == 1 && references[0].Exists {
+			if file := vfs.Lookup(references[0].Target); file != nil {
+				r.fileSelectedCallback(file.Winner())
+				return
+			}
+		}
+
+		rows := g.Rows{g.Row(g.Label("References"), g.Label("Kind"), g.Label("Where"))}
+
+		for idx := range references {
+			reference := references[idx]
+
+			var target g.Widget = g.Label(reference.Target + " (missing)")
+			if reference.Exists {
+				target = r.makeLink(vfs, reference.Target, idx)
+			}
+
+			rows = append(rows, g.Row(target, g.Label(string(reference.Kind)), g.Label(reference.Detail)))
+		}
+
+		r.setContent(fmt.Sprintf("Files referenced by %s: %d", path, len(references)), rows)
+		r.Show()
+	}()
+}
+
+func (r *References) getIndex() (*hsreference.Index, *hsproject.VirtualFileSystem) {
+	r.indexMutex.Lock()
+	defer r.indexMutex.Unlock()
+
+	if r.index == nil {
+		r.setContent("Indexing references, please wait...", nil)
+		r.index, r.vfs = r.project.BuildReferenceIndex(r.config)
+	}
+
+	return r.index, r.vfs
+}
+
+func (r *References) setContent(status string, rows g.Rows) {
+	r.mutex.Lock()
+	r.status = status
+	r.rows = rows
+	r.mutex.Unlock()
+}
+
+func (r *References) makeLink(vfs *hsproject.VirtualFileSystem, path string, idx int) g.Widget {
+	return g.Selectable(fmt.Sprintf("%s##ReferencesRow_%d", path, idx)).OnClick(func() {
+		if file := vfs.Lookup(path); file != nil {
+			go r.fileSelectedCallback(file.Winner())
+		}
+	})
+}
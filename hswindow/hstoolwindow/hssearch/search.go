@@ -0,0 +1,194 @@
+// Package hssearch contains the project-wide search tool window
+package hssearch
+
+import (
+	"fmt"
+	"sync"
+
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
+)
+
+const (
+	mainWindowW, mainWindowH = 500, 400
+	inputTextSize            = 250
+	modeComboSize            = 100
+	maxResults               = 1000
+)
+
+// SearchFileSelectedCallback represents file selected callback
+type SearchFileSelectedCallback func(path *hscommon.PathEntry)
+
+// Search is a tool window which searches for files in the project and in all auxiliary MPQs
+type Search struct {
+	*hstoolwindow.ToolWindow
+	config               *hsconfig.Config
+	project              *hsproject.Project
+	fileSelectedCallback SearchFileSelectedCallback
+
+	pattern string
+	content string
+	mode    int32
+
+	mutex     sync.Mutex
+	searching bool
+	status    string
+	rows      g.Rows
+}
+
+// Create creates a new search window
+func Create(fileSelectedCallback SearchFileSelectedCallback, config *hsconfig.Config, x, y float32) (*Search, error) {
+	result := &Search{
+		ToolWindow:           hstoolwindow.New("Search", hsstate.ToolWindowTypeSearch, x, y),
+		fileSelectedCallback: fileSelectedCallback,
+		config:               config,
+		mode:                 int32(hsproject.SearchModeSubstring),
+	}
+
+	return result, nil
+}
+
+// SetProject sets the project to search in
+func (s *Search) SetProject(project *hsproject.Project) {
+	s.project = project
+	s.Reset()
+}
+
+// Reset clears the search results
+func (s *Search) Reset() {
+	s.mutex.Lock()
+	s.rows = nil
+	s.status = ""
+	s.mutex.Unlock()
+}
+
+// Build builds the search window
+func (s *Search) Build() {
+	if s.project == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	searching := s.searching
+	status := s.status
+	rows := s.rows
+	s.mutex.Unlock()
+
+	var content g.Widget = g.Label(status)
+
+	switch {
+	case searching:
+		content = g.Label("Searching, please wait...")
+	case len(rows) > 1:
+		content = g.Layout{
+			g.Label(status),
+			g.Child("SearchResults").
+				Border(false).
+				Flags(g.WindowFlagsHorizontalScrollbar).
+				Layout(g.Layout{
+					g.FastTable("").Border(true).Rows(rows),
+				}),
+		}
+	}
+
+	modes := hsproject.SearchModeNames()
+
+	s.IsOpen(&s.Visible).
+		Size(mainWindowW, mainWindowH).
+		Layout(g.Layout{
+			g.Line(
+				g.Label("Path:   "),
+				g.InputText("##SearchPattern", &s.pattern).
+					Size(inputTextSize).
+					Flags(g.InputTextFlagsEnterReturnsTrue).
+					OnChange(s.search),
+				g.Combo("##SearchMode", modes[s.mode], modes, &s.mode).Size(modeComboSize),
+			),
+			g.Line(
+				g.Label("Content:"),
+				g.InputText("##SearchContent", &s.content).
+					Size(inputTextSize).
+					Flags(g.InputTextFlagsEnterReturnsTrue).
+					OnChange(s.search),
+				g.Button("Search##SearchStart").OnClick(s.search),
+			),
+			g.Label("Content is only searched in text (.txt) and table (.tbl) files."),
+			g.Separator(),
+			content,
+		})
+}
+
+func (s *Search) search() {
+	s.mutex.Lock()
+	if s.searching {
+		s.mutex.Unlock()
+		return
+	}
+
+	s.searching = true
+	s.mutex.Unlock()
+
+	options := hsproject.SearchOptions{
+		Pattern:    s.pattern,
+		Mode:       hsproject.SearchMode(s.mode),
+		Content:    s.content,
+		MaxResults: maxResults,
+	}
+
+	go func() {
+		results, truncated, err := s.project.Search(s.config, options)
+
+		var status string
+
+		switch {
+		case err != nil:
+			status = err.Error()
+		case truncated:
+			status = fmt.Sprintf("Showing the first %d results.", len(results))
+		default:
+			status = fmt.Sprintf("%d results.", len(results))
+		}
+
+		rows := s.makeRows(results, options.Content != "")
+
+		s.mutex.Lock()
+		s.rows = rows
+		s.status = status
+		s.searching = false
+		s.mutex.Unlock()
+	}()
+}
+
+func (s *Search) makeRows(results []hsproject.SearchResult, showMatches bool) g.Rows {
+	header := g.Row(g.Label("Path"), g.Label("Source"))
+	if showMatches {
+		header = g.Row(g.Label("Path"), g.Label("Source"), g.Label("Match"))
+	}
+
+	rows := g.Rows{header}
+
+	for idx := range results {
+		result := results[idx]
+		id := fmt.Sprintf("##SearchResult_%d", idx)
+
+		widgets := []g.Widget{
+			g.Selectable(result.Path + id).OnClick(func() {
+				go s.fileSelectedCallback(result.Entry)
+			}),
+			g.Label(result.Source),
+		}
+
+		if showMatches {
+			widgets = append(widgets, g.Label(result.Match))
+		}
+
+		rows = append(rows, g.Row(widgets...))
+	}
+
+	return rows
+}
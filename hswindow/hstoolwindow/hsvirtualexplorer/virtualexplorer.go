@@ -0,0 +1,148 @@
+// Package hsvirtualexplorer contains the virtual file system explorer's data
+package hsvirtualexplorer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	g "github.com/ianling/giu"
+
+	"github.com/OpenDiablo2/HellSpawner/hscommon"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsproject"
+	"github.com/OpenDiablo2/HellSpawner/hscommon/hsstate"
+	"github.com/OpenDiablo2/HellSpawner/hsconfig"
+	"github.com/OpenDiablo2/HellSpawner/hswindow/hstoolwindow"
+)
+
+const (
+	mainWindowW, mainWindowH = 400, 400
+)
+
+// VirtualExplorerFileSelectedCallback represents file selected callback
+type VirtualExplorerFileSelectedCallback func(path *hscommon.PathEntry)
+
+// VirtualExplorer shows the files the game actually sees: the project's content directory
+// overlaid on the auxiliary MPQs, along with the layer each file comes from
+type VirtualExplorer struct {
+	*hstoolwindow.ToolWindow
+	config               *hsconfig.Config
+	project              *hsproject.Project
+	fileSelectedCallback VirtualExplorerFileSelectedCallback
+
+	mutex     sync.Mutex
+	nodeCache []g.Widget
+	loading   bool
+	// generation is increased by Reset, so that a file tree built before it is discarded
+	generation int
+}
+
+// Create creates a new virtual file system explorer
+func Create(fileSelectedCallback VirtualExplorerFileSelectedCallback,
+	config *hsconfig.Config, x, y float32) (*VirtualExplorer, error) {
+	result := &VirtualExplorer{
+		ToolWindow:           hstoolwindow.New("Virtual File System", hsstate.ToolWindowTypeVirtualExplorer, x, y),
+		fileSelectedCallback: fileSelectedCallback,
+		config:               config,
+	}
+
+	return result, nil
+}
+
+// SetProject sets explorer's project
+func (m *VirtualExplorer) SetProject(project *hsproject.Project) {
+	m.project = project
+	m.Reset()
+}
+
+// Build builds an explorer
+func (m *VirtualExplorer) Build() {
+	if m.project == nil {
+		return
+	}
+
+	m.IsOpen(&m.Visible).
+		Size(mainWindowW, mainWindowH).
+		Layout(g.Layout{
+			g.Line(
+				g.Button("Refresh##VirtualExplorerRefresh").OnClick(m.Reset),
+				g.Label("Load order: "+strings.Join(m.project.LoadOrderNames(), " > ")),
+			),
+			g.Separator(),
+			g.Child("VirtualExplorerContent").
+				Border(false).
+				Flags(g.WindowFlagsHorizontalScrollbar).
+				Layout(m.getTreeNodes()),
+		})
+}
+
+// getTreeNodes returns the file tree, it is built in the background because listing the MPQs
+// may have to harvest the names of the MPQs without a listfile
+func (m *VirtualExplorer) getTreeNodes() []g.Widget {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.nodeCache != nil {
+		return m.nodeCache
+	}
+
+	if !m.loading {
+		m.loading = true
+
+		go m.buildTreeNodes(m.project, m.generation)
+	}
+
+	return []g.Widget{g.Label("Loading the virtual file system, please wait...")}
+}
+
+func (m *VirtualExplorer) buildTreeNodes(project *hsproject.Project, generation int) {
+	vfs := project.BuildVirtualFileSystem(m.config)
+	nodes := []g.Widget{m.renderNodes(vfs.Root())}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.loading = false
+
+	// the tree was reset while it was built, the next frame builds it again
+	if generation != m.generation {
+		return
+	}
+
+	m.nodeCache = nodes
+}
+
+func (m *VirtualExplorer) renderNodes(pathEntry *hscommon.PathEntry) g.Widget {
+	if !pathEntry.IsDirectory {
+		id := "##VirtualExplorerNode_" + pathEntry.FullPath
+
+		// the file is opened from the source it resolves to, so project files are saved and share their editor
+		// with the project explorer
+		file := pathEntry.Resolved
+		if file == nil {
+			file = pathEntry
+		}
+
+		return g.Selectable(fmt.Sprintf("%s  [%s]%s", pathEntry.Name, pathEntry.GetSourceName(), id)).
+			OnClick(func() {
+				go m.fileSelectedCallback(file)
+			})
+	}
+
+	widgets := make([]g.Widget, len(pathEntry.Children))
+
+	for idx := range pathEntry.Children {
+		widgets[idx] = m.renderNodes(pathEntry.Children[idx])
+	}
+
+	return g.TreeNode(pathEntry.Name + "##VirtualExplorerNode_" + pathEntry.FullPath).Layout(widgets)
+}
+
+// Reset clears the cached file tree so it gets rebuilt on the next frame
+func (m *VirtualExplorer) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.nodeCache = nil
+	m.generation++
+}
@@ -2,11 +2,17 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/OpenDiablo2/HellSpawner/hsapp"
+	"github.com/OpenDiablo2/HellSpawner/hscli"
 )
 
 func main() {
+	if handled, exitCode := hscli.Run(os.Args[1:]); handled {
+		os.Exit(exitCode)
+	}
+
 	app, err := hsapp.Create()
 
 	if err != nil {